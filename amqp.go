@@ -0,0 +1,140 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// defaultAMQPRoutingKey is the default template of the routing key, e.g. "Players.INSERT".
+const defaultAMQPRoutingKey = "{{.TableName}}.{{.ModType}}"
+
+// AMQPSink publishes the data change records to an AMQP 0.9.1 broker such as RabbitMQ.
+//
+// The exchange and the routing key are text/template templates executed with each data change record,
+// so that the records can be routed by table name or mod type. Each Read waits for the publisher confirms.
+type AMQPSink struct {
+	conn       *amqp.Connection
+	ch         *amqp.Channel
+	exchange   *template.Template
+	routingKey *template.Template
+	mu         sync.Mutex
+}
+
+// NewAMQPSink connects to the broker and creates a new AMQPSink.
+func NewAMQPSink(url, exchange, routingKey string) (*AMQPSink, error) {
+	exchangeTemplate, err := newAMQPTemplate("exchange", exchange)
+	if err != nil {
+		return nil, err
+	}
+	routingKeyTemplate, err := newAMQPTemplate("routing key", routingKey)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := amqp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := ch.Confirm(false); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &AMQPSink{
+		conn:       conn,
+		ch:         ch,
+		exchange:   exchangeTemplate,
+		routingKey: routingKeyTemplate,
+	}, nil
+}
+
+func (s *AMQPSink) Read(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var confirms []*amqp.DeferredConfirmation
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			exchange, err := executeAMQPTemplate(s.exchange, r)
+			if err != nil {
+				return err
+			}
+			routingKey, err := executeAMQPTemplate(s.routingKey, r)
+			if err != nil {
+				return err
+			}
+			body, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			confirm, err := s.ch.PublishWithDeferredConfirmWithContext(context.Background(), exchange, routingKey, false, false, amqp.Publishing{
+				ContentType:  "application/json",
+				DeliveryMode: amqp.Persistent,
+				MessageId:    r.ServerTransactionID + "/" + r.RecordSequence,
+				Timestamp:    r.CommitTimestamp,
+				Body:         body,
+			})
+			if err != nil {
+				return err
+			}
+			confirms = append(confirms, confirm)
+		}
+	}
+
+	for _, confirm := range confirms {
+		if !confirm.Wait() {
+			return fmt.Errorf("message was not confirmed by the broker")
+		}
+	}
+	return nil
+}
+
+// Close closes the channel and the connection.
+func (s *AMQPSink) Close() error {
+	s.ch.Close()
+	return s.conn.Close()
+}
+
+func newAMQPTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return t, nil
+}
+
+func executeAMQPTemplate(t *template.Template, r *changestreams.DataChangeRecord) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, r); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,61 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+)
+
+func TestExecuteAMQPTemplate(t *testing.T) {
+	r := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+
+	for _, test := range []struct {
+		desc     string
+		text     string
+		expected string
+	}{
+		{desc: "default", text: defaultAMQPRoutingKey, expected: "Players.INSERT"},
+		{desc: "constant", text: "changes", expected: "changes"},
+		{desc: "empty", text: "", expected: ""},
+		{desc: "prefix", text: "spanner.{{.TableName}}", expected: "spanner.Players"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			tmpl, err := newAMQPTemplate("routing key", test.text)
+			if err != nil {
+				t.Fatalf("newAMQPTemplate error: %v", err)
+			}
+			got, err := executeAMQPTemplate(tmpl, r)
+			if err != nil {
+				t.Fatalf("executeAMQPTemplate error: %v", err)
+			}
+			if got != test.expected {
+				t.Errorf("executeAMQPTemplate = %q, want %q", got, test.expected)
+			}
+		})
+	}
+
+	if _, err := newAMQPTemplate("routing key", "{{.TableName"); err == nil {
+		t.Errorf("newAMQPTemplate must fail for an invalid template")
+	}
+	tmpl, err := newAMQPTemplate("routing key", "{{.Unknown}}")
+	if err != nil {
+		t.Fatalf("newAMQPTemplate error: %v", err)
+	}
+	if _, err := executeAMQPTemplate(tmpl, r); err == nil {
+		t.Errorf("executeAMQPTemplate must fail for an unknown field")
+	}
+}
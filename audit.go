@@ -0,0 +1,74 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// auditLog records the retries of the partition queries and the resumptions of the streams as JSON lines, as the
+// evidence to investigate the duplicates or the gaps of the records downstream.
+type auditLog struct {
+	logger *slog.Logger
+	close  func() error
+}
+
+// openAuditLog opens the audit log appending to the file.
+func openAuditLog(path string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	a := newAuditLog(f)
+	a.close = f.Close
+	return a, nil
+}
+
+func newAuditLog(w io.Writer) *auditLog {
+	return &auditLog{logger: slog.New(slog.NewJSONHandler(w, nil))}
+}
+
+// retry records the retry of a partition query. It's called by changestreams.RetryPolicy.
+func (a *auditLog) retry(event *changestreams.RetryEvent) {
+	a.logger.Info("retry",
+		"stream", event.StreamID,
+		"partition_token", event.PartitionToken,
+		"attempt", event.Attempt,
+		"reason", event.Err.Error(),
+		"backoff", event.Backoff.String(),
+		"resume_timestamp", event.ResumeTimestamp)
+}
+
+// resume records the resumption of a stream, e.g. from the checkpoint.
+func (a *auditLog) resume(streamID string, timestamp time.Time, reason string) {
+	a.logger.Info("resume",
+		"stream", streamID,
+		"reason", reason,
+		"resume_timestamp", timestamp)
+}
+
+func (a *auditLog) Close() error {
+	if a.close == nil {
+		return nil
+	}
+	return a.close()
+}
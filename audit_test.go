@@ -0,0 +1,51 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAuditLog(t *testing.T) {
+	var out bytes.Buffer
+	a := newAuditLog(&out)
+	a.logger = slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+
+	a.resume("s", mustParseTime(t, "2022-12-04T18:00:00Z"), "checkpoint")
+	a.retry(&changestreams.RetryEvent{
+		StreamID:        "s",
+		PartitionToken:  "a",
+		Attempt:         2,
+		Err:             errors.New("rpc error: code = Unavailable"),
+		Backoff:         2 * time.Second,
+		ResumeTimestamp: mustParseTime(t, "2022-12-04T18:00:10Z"),
+	})
+
+	expected := `{"level":"INFO","msg":"resume","stream":"s","reason":"checkpoint","resume_timestamp":"2022-12-04T18:00:00Z"}
+{"level":"INFO","msg":"retry","stream":"s","partition_token":"a","attempt":2,"reason":"rpc error: code = Unavailable","backoff":"2s","resume_timestamp":"2022-12-04T18:00:10Z"}
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("audit log has diff = %v", diff)
+	}
+}
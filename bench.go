@@ -0,0 +1,231 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"golang.org/x/sync/errgroup"
+)
+
+const commandBench = "bench"
+
+// benchDrainTimeout is the maximum time to wait for the written records to be read after the writes finish.
+const benchDrainTimeout = time.Minute
+
+// benchConfig is the configuration for the bench command.
+type benchConfig struct {
+	// table is the table to write the synthetic transactions into, with Id STRING and Payload STRING columns.
+	table string
+	// rate is the number of the transactions written per second.
+	rate        float64
+	duration    time.Duration
+	payloadSize int
+	format      string
+}
+
+// benchStats collects the latencies from the commit timestamps to the time the records are read.
+type benchStats struct {
+	written   int64
+	latencies []time.Duration
+	mu        sync.Mutex
+}
+
+func (s *benchStats) addWritten() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.written++
+}
+
+func (s *benchStats) addLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latencies = append(s.latencies, latency)
+}
+
+// caughtUp reports whether all the written records are read.
+func (s *benchStats) caughtUp() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.latencies)) >= s.written
+}
+
+// benchSummary is the summary of the bench command.
+type benchSummary struct {
+	Transactions int64   `json:"transactions"`
+	Records      int64   `json:"records"`
+	Throughput   float64 `json:"throughput"`
+	LatencyP50   float64 `json:"latency_p50_seconds"`
+	LatencyP95   float64 `json:"latency_p95_seconds"`
+	LatencyP99   float64 `json:"latency_p99_seconds"`
+	LatencyMax   float64 `json:"latency_max_seconds"`
+}
+
+// summary returns the summary of the records read in the elapsed time.
+func (s *benchStats) summary(elapsed time.Duration) *benchSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := &benchSummary{Transactions: s.written, Records: int64(len(s.latencies))}
+	if elapsed > 0 {
+		summary.Throughput = float64(len(s.latencies)) / elapsed.Seconds()
+	}
+	if len(s.latencies) == 0 {
+		return summary
+	}
+	latencies := make([]time.Duration, len(s.latencies))
+	copy(latencies, s.latencies)
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p float64) float64 {
+		return latencies[int(p*float64(len(latencies)-1))].Seconds()
+	}
+	summary.LatencyP50 = percentile(0.5)
+	summary.LatencyP95 = percentile(0.95)
+	summary.LatencyP99 = percentile(0.99)
+	summary.LatencyMax = latencies[len(latencies)-1].Seconds()
+	return summary
+}
+
+func writeBenchSummary(w io.Writer, summary *benchSummary, format string) error {
+	if format == formatJSON {
+		return json.NewEncoder(w).Encode(summary)
+	}
+	_, err := fmt.Fprintf(w, "Transactions: %d | Records: %d | Throughput: %.1f records/s | Latency p50: %.3fs p95: %.3fs p99: %.3fs max: %.3fs\n",
+		summary.Transactions, summary.Records, summary.Throughput, summary.LatencyP50, summary.LatencyP95, summary.LatencyP99, summary.LatencyMax)
+	return err
+}
+
+// runBench writes the synthetic transactions into the table at the rate for the duration while reading the streams
+// watching it, and writes the throughput and the latencies of the records to w.
+func runBench(ctx context.Context, w io.Writer, client *spanner.Client, streamIDs []string, config changestreams.Config, bench benchConfig) error {
+	readCtx, cancelRead := context.WithCancel(ctx)
+	defer cancelRead()
+
+	start := time.Now()
+	config.StartTimestamp = start
+	config.EndTimestamp = time.Time{}
+	readers, err := newStreamReaders(readCtx, client, streamIDs, config, nil)
+	if err != nil {
+		return err
+	}
+	defer readers.Close()
+
+	var stats benchStats
+	// The writes stop if the reader fails.
+	writeCtx, cancelWrite := context.WithCancel(ctx)
+	defer cancelWrite()
+	readErr := make(chan error, 1)
+	go func() {
+		defer cancelWrite()
+		readErr <- readers.Read(readCtx, func(result *changestreams.ReadResult) error {
+			now := time.Now()
+			for _, changeRecord := range result.ChangeRecords {
+				for _, r := range changeRecord.DataChangeRecords {
+					if r.TableName != bench.table {
+						continue
+					}
+					for range r.Mods {
+						stats.addLatency(now.Sub(r.CommitTimestamp))
+					}
+				}
+			}
+			return nil
+		})
+	}()
+
+	writeErr := writeBenchTransactions(writeCtx, client, bench, &stats)
+	if writeErr != nil && ctx.Err() == nil {
+		select {
+		case err := <-readErr:
+			return fmt.Errorf("failed to read: %w", err)
+		default:
+			return fmt.Errorf("failed to write: %w", writeErr)
+		}
+	}
+
+	// Waits for the reader to catch up with the writes.
+	drain := time.NewTimer(benchDrainTimeout)
+	defer drain.Stop()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for !stats.caughtUp() {
+		select {
+		case err := <-readErr:
+			// The reader is stopped by the interrupt, or failed.
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("failed to read: %w", err)
+			}
+			return writeBenchSummary(w, stats.summary(time.Since(start)), bench.format)
+		case <-drain.C:
+			logger.Warn("Gave up waiting for the written records to be read", "timeout", benchDrainTimeout)
+			return writeBenchSummary(w, stats.summary(time.Since(start)), bench.format)
+		case <-ticker.C:
+		}
+	}
+	elapsed := time.Since(start)
+	cancelRead()
+	<-readErr
+	return writeBenchSummary(w, stats.summary(elapsed), bench.format)
+}
+
+// writeBenchTransactions writes a row per transaction at the rate for the duration.
+func writeBenchTransactions(ctx context.Context, client *spanner.Client, bench benchConfig, stats *benchStats) error {
+	group, ctx := errgroup.WithContext(ctx)
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / bench.rate))
+	defer ticker.Stop()
+	deadline := time.After(bench.duration)
+	for {
+		select {
+		case <-ctx.Done():
+			return group.Wait()
+		case <-deadline:
+			return group.Wait()
+		case <-ticker.C:
+		}
+		id, payload, err := newBenchRow(bench.payloadSize)
+		if err != nil {
+			return err
+		}
+		group.Go(func() error {
+			m := spanner.Insert(bench.table, []string{"Id", "Payload"}, []interface{}{id, payload})
+			if _, err := client.Apply(ctx, []*spanner.Mutation{m}); err != nil {
+				return err
+			}
+			stats.addWritten()
+			return nil
+		})
+	}
+}
+
+// newBenchRow returns a random ID and a random payload of the size.
+func newBenchRow(payloadSize int) (id, payload string, err error) {
+	b := make([]byte, 16+(payloadSize+1)/2)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(b[:16]), hex.EncodeToString(b[16:])[:payloadSize], nil
+}
@@ -0,0 +1,78 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBenchSummary(t *testing.T) {
+	var stats benchStats
+	for i := 1; i <= 100; i++ {
+		stats.addWritten()
+		stats.addLatency(time.Duration(101-i) * 10 * time.Millisecond)
+	}
+	if !stats.caughtUp() {
+		t.Errorf("all the written records are read")
+	}
+
+	summary := stats.summary(10 * time.Second)
+	expected := &benchSummary{
+		Transactions: 100,
+		Records:      100,
+		Throughput:   10,
+		LatencyP50:   0.5,
+		LatencyP95:   0.95,
+		LatencyP99:   0.99,
+		LatencyMax:   1,
+	}
+	if diff := cmp.Diff(summary, expected); diff != "" {
+		t.Errorf("summary has diff = %v", diff)
+	}
+
+	for _, test := range []struct {
+		format   string
+		expected string
+	}{
+		{formatText, "Transactions: 100 | Records: 100 | Throughput: 10.0 records/s | Latency p50: 0.500s p95: 0.950s p99: 0.990s max: 1.000s\n"},
+		{formatJSON, `{"transactions":100,"records":100,"throughput":10,"latency_p50_seconds":0.5,"latency_p95_seconds":0.95,"latency_p99_seconds":0.99,"latency_max_seconds":1}` + "\n"},
+	} {
+		var buf bytes.Buffer
+		if err := writeBenchSummary(&buf, summary, test.format); err != nil {
+			t.Fatalf("writeBenchSummary error: %v", err)
+		}
+		if diff := cmp.Diff(buf.String(), test.expected); diff != "" {
+			t.Errorf("%s output has diff = %v", test.format, diff)
+		}
+	}
+}
+
+func TestNewBenchRow(t *testing.T) {
+	for _, size := range []int{0, 1, 100} {
+		id, payload, err := newBenchRow(size)
+		if err != nil {
+			t.Fatalf("newBenchRow error: %v", err)
+		}
+		if len(id) != 32 || len(payload) != size {
+			t.Errorf("newBenchRow(%d) = %q, %q", size, id, payload)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// bigQuerySchema is the schema of the table that BigQuerySink writes to. Each row represents a single mod.
+var bigQuerySchema = bigquery.Schema{
+	{Name: "commit_timestamp", Type: bigquery.TimestampFieldType, Required: true},
+	{Name: "partition_token", Type: bigquery.StringFieldType},
+	{Name: "record_sequence", Type: bigquery.StringFieldType},
+	{Name: "server_transaction_id", Type: bigquery.StringFieldType},
+	{Name: "is_last_record_in_transaction_in_partition", Type: bigquery.BooleanFieldType},
+	{Name: "table_name", Type: bigquery.StringFieldType},
+	{Name: "mod_type", Type: bigquery.StringFieldType},
+	{Name: "value_capture_type", Type: bigquery.StringFieldType},
+	{Name: "number_of_records_in_transaction", Type: bigquery.IntegerFieldType},
+	{Name: "number_of_partitions_in_transaction", Type: bigquery.IntegerFieldType},
+	{Name: "transaction_tag", Type: bigquery.StringFieldType},
+	{Name: "is_system_transaction", Type: bigquery.BooleanFieldType},
+	{Name: "mod_index", Type: bigquery.IntegerFieldType},
+	{Name: "keys", Type: bigquery.JSONFieldType},
+	{Name: "new_values", Type: bigquery.JSONFieldType},
+	{Name: "old_values", Type: bigquery.JSONFieldType},
+}
+
+// BigQuerySink streams the data change records into a BigQuery table.
+type BigQuerySink struct {
+	ctx      context.Context
+	client   *bigquery.Client
+	inserter *bigquery.Inserter
+	mu       sync.Mutex
+}
+
+// NewBigQuerySink creates a new BigQuerySink writing to the table specified in the form of
+// "dataset.table" or "project.dataset.table". If the table doesn't exist, it is created.
+func NewBigQuerySink(ctx context.Context, defaultProjectID, tablePath string, opts ...option.ClientOption) (*BigQuerySink, error) {
+	projectID, datasetID, tableID, err := parseBigQueryTablePath(defaultProjectID, tablePath)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := bigquery.NewClient(ctx, projectID, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	table := client.Dataset(datasetID).Table(tableID)
+	if _, err := table.Metadata(ctx); err != nil {
+		var apiErr *googleapi.Error
+		if !errors.As(err, &apiErr) || apiErr.Code != http.StatusNotFound {
+			client.Close()
+			return nil, err
+		}
+		if err := table.Create(ctx, &bigquery.TableMetadata{
+			Schema: bigQuerySchema,
+			TimePartitioning: &bigquery.TimePartitioning{
+				Type:  bigquery.DayPartitioningType,
+				Field: "commit_timestamp",
+			},
+		}); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	return &BigQuerySink{
+		ctx:      ctx,
+		client:   client,
+		inserter: table.Inserter(),
+	}, nil
+}
+
+func (s *BigQuerySink) Read(result *changestreams.ReadResult) error {
+	var rows []*bigQueryRow
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			for i, mod := range r.Mods {
+				rows = append(rows, &bigQueryRow{
+					partitionToken: result.PartitionToken,
+					record:         r,
+					modIndex:       i,
+					mod:            mod,
+				})
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inserter.Put(s.ctx, rows)
+}
+
+// Close closes the underlying client.
+func (s *BigQuerySink) Close() error {
+	return s.client.Close()
+}
+
+type bigQueryRow struct {
+	partitionToken string
+	record         *changestreams.DataChangeRecord
+	modIndex       int
+	mod            *changestreams.Mod
+}
+
+// Save implements bigquery.ValueSaver.
+func (r *bigQueryRow) Save() (map[string]bigquery.Value, string, error) {
+	keys, err := bigQueryJSON(r.mod.Keys)
+	if err != nil {
+		return nil, "", err
+	}
+	newValues, err := bigQueryJSON(r.mod.NewValues)
+	if err != nil {
+		return nil, "", err
+	}
+	oldValues, err := bigQueryJSON(r.mod.OldValues)
+	if err != nil {
+		return nil, "", err
+	}
+
+	row := map[string]bigquery.Value{
+		"commit_timestamp":                           r.record.CommitTimestamp.Format(time.RFC3339Nano),
+		"partition_token":                            r.partitionToken,
+		"record_sequence":                            r.record.RecordSequence,
+		"server_transaction_id":                      r.record.ServerTransactionID,
+		"is_last_record_in_transaction_in_partition": r.record.IsLastRecordInTransactionInPartition,
+		"table_name":                                 r.record.TableName,
+		"mod_type":                                   r.record.ModType,
+		"value_capture_type":                         r.record.ValueCaptureType,
+		"number_of_records_in_transaction":           r.record.NumberOfRecordsInTransaction,
+		"number_of_partitions_in_transaction":        r.record.NumberOfPartitionsInTransaction,
+		"transaction_tag":                            r.record.TransactionTag,
+		"is_system_transaction":                      r.record.IsSystemTransaction,
+		"mod_index":                                  r.modIndex,
+		"keys":                                       keys,
+		"new_values":                                 newValues,
+		"old_values":                                 oldValues,
+	}
+	// The insert ID is used by BigQuery for best effort de-duplication of retried inserts.
+	// Partition tokens are too long to be used as is, so the ID is hashed.
+	id := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s/%d", r.partitionToken, r.record.ServerTransactionID, r.record.RecordSequence, r.modIndex)))
+	return row, hex.EncodeToString(id[:]), nil
+}
+
+func bigQueryJSON(v spanner.NullJSON) (bigquery.Value, error) {
+	if !v.Valid {
+		return nil, nil
+	}
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func parseBigQueryTablePath(defaultProjectID, tablePath string) (projectID, datasetID, tableID string, err error) {
+	parts := strings.Split(tablePath, ".")
+	switch len(parts) {
+	case 2:
+		projectID, datasetID, tableID = defaultProjectID, parts[0], parts[1]
+	case 3:
+		projectID, datasetID, tableID = parts[0], parts[1], parts[2]
+	default:
+		return "", "", "", fmt.Errorf("invalid BigQuery table %q, must be in the form of [project.]dataset.table", tablePath)
+	}
+	if projectID == "" || datasetID == "" || tableID == "" {
+		return "", "", "", fmt.Errorf("invalid BigQuery table %q, must be in the form of [project.]dataset.table", tablePath)
+	}
+	return projectID, datasetID, tableID, nil
+}
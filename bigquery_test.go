@@ -0,0 +1,92 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseBigQueryTablePath(t *testing.T) {
+	for _, test := range []struct {
+		tablePath string
+		expected  []string
+		wantErr   bool
+	}{
+		{tablePath: "dataset.table", expected: []string{"default", "dataset", "table"}},
+		{tablePath: "project.dataset.table", expected: []string{"project", "dataset", "table"}},
+		{tablePath: "table", wantErr: true},
+		{tablePath: "dataset.", wantErr: true},
+		{tablePath: "a.b.c.d", wantErr: true},
+	} {
+		projectID, datasetID, tableID, err := parseBigQueryTablePath("default", test.tablePath)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseBigQueryTablePath(%q) should return an error", test.tablePath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBigQueryTablePath(%q) error: %v", test.tablePath, err)
+			continue
+		}
+		if diff := cmp.Diff([]string{projectID, datasetID, tableID}, test.expected); diff != "" {
+			t.Errorf("parseBigQueryTablePath(%q) has diff = %v", test.tablePath, diff)
+		}
+	}
+}
+
+func TestBigQueryRowSave(t *testing.T) {
+	record := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId", "Name")
+	row := &bigQueryRow{
+		partitionToken: "a",
+		record:         record,
+		modIndex:       0,
+		mod:            record.Mods[0],
+	}
+	values, insertID, err := row.Save()
+	if err != nil {
+		t.Fatalf("Save error: %v", err)
+	}
+	if insertID == "" {
+		t.Errorf("insert ID must not be empty")
+	}
+
+	expected := map[string]bigquery.Value{
+		"commit_timestamp":                           "2022-12-04T18:00:00Z",
+		"partition_token":                            "a",
+		"record_sequence":                            "",
+		"server_transaction_id":                      "",
+		"is_last_record_in_transaction_in_partition": false,
+		"table_name":                                 "Players",
+		"mod_type":                                   "INSERT",
+		"value_capture_type":                         "",
+		"number_of_records_in_transaction":           int64(0),
+		"number_of_partitions_in_transaction":        int64(0),
+		"transaction_tag":                            "",
+		"is_system_transaction":                      false,
+		"mod_index":                                  0,
+		"keys":                                       `{"PlayerId":"1"}`,
+		"new_values":                                 `{"Name":"foo"}`,
+		"old_values":                                 `{}`,
+	}
+	if diff := cmp.Diff(values, expected); diff != "" {
+		t.Errorf("Save has diff = %v", diff)
+	}
+}
@@ -0,0 +1,163 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// subscriptionBufferSize is the number of records buffered per subscriber.
+// Subscribers that fall behind more than this are disconnected, so that they never block the reader.
+const subscriptionBufferSize = 1024
+
+// recordFilter selects the data change records by table and mod type. Empty filters match everything.
+type recordFilter struct {
+	tables   map[string]bool
+	modTypes map[string]bool
+}
+
+func newRecordFilter(tables, modTypes []string) *recordFilter {
+	f := &recordFilter{
+		tables:   make(map[string]bool),
+		modTypes: make(map[string]bool),
+	}
+	for _, t := range tables {
+		f.tables[t] = true
+	}
+	for _, m := range modTypes {
+		f.modTypes[m] = true
+	}
+	return f
+}
+
+func (f *recordFilter) match(r *changestreams.DataChangeRecord) bool {
+	if len(f.tables) > 0 && !f.tables[r.TableName] {
+		return false
+	}
+	if len(f.modTypes) > 0 && !f.modTypes[r.ModType] {
+		return false
+	}
+	return true
+}
+
+// broadcastRecord is a data change record with the stream and the partition it was read from.
+type broadcastRecord struct {
+	streamID       string
+	partitionToken string
+	record         *changestreams.DataChangeRecord
+}
+
+// subscription receives the records from Broadcaster.
+// records is closed when the broadcaster is closed or the subscriber has fallen behind (overflowed is true).
+type subscription struct {
+	records    chan *broadcastRecord
+	filter     *recordFilter
+	overflowed bool
+}
+
+// Broadcaster fans out the data change records read by a single reader to multiple subscribers.
+type Broadcaster struct {
+	subscriptions map[*subscription]struct{}
+	closed        bool
+	mu            sync.Mutex
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		subscriptions: make(map[*subscription]struct{}),
+	}
+}
+
+func (b *Broadcaster) Read(result *changestreams.ReadResult) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			for s := range b.subscriptions {
+				if !s.filter.match(r) {
+					continue
+				}
+				// The mods are decoded once a subscriber matches the record, before it's shared by the subscribers.
+				if err := r.DecodeMods(); err != nil {
+					return err
+				}
+				select {
+				case s.records <- &broadcastRecord{streamID: result.StreamID, partitionToken: result.PartitionToken, record: r}:
+				default:
+					s.overflowed = true
+					b.remove(s)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Subscribe starts receiving the records matching the filter.
+func (b *Broadcaster) Subscribe(filter *recordFilter) *subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := &subscription{
+		records: make(chan *broadcastRecord, subscriptionBufferSize),
+		filter:  filter,
+	}
+	if b.closed {
+		close(s.records)
+		return s
+	}
+	b.subscriptions[s] = struct{}{}
+	return s
+}
+
+// Unsubscribe stops receiving the records.
+func (b *Broadcaster) Unsubscribe(s *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.remove(s)
+}
+
+// Overflowed reports whether the subscription was closed because the subscriber had fallen behind.
+func (b *Broadcaster) Overflowed(s *subscription) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return s.overflowed
+}
+
+// Close closes all the subscriptions.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for s := range b.subscriptions {
+		b.remove(s)
+	}
+}
+
+func (b *Broadcaster) remove(s *subscription) {
+	if _, ok := b.subscriptions[s]; !ok {
+		return
+	}
+	delete(b.subscriptions, s)
+	close(s.records)
+}
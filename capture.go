@@ -0,0 +1,136 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+const commandRecord = "record"
+
+// CaptureWriter writes the complete ReadResults, including the heartbeat and child partitions records,
+// as JSON lines to be read by the replay command. If the path ends with ".gz", the file is compressed with gzip.
+type CaptureWriter struct {
+	file *os.File
+	gz   *gzip.Writer
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	mu   sync.Mutex
+}
+
+// NewCaptureWriter creates the capture file.
+func NewCaptureWriter(path string) (*CaptureWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &CaptureWriter{file: file}
+	var out io.Writer = file
+	if strings.HasSuffix(path, ".gz") {
+		w.gz = gzip.NewWriter(file)
+		out = w.gz
+	}
+	w.buf = bufio.NewWriter(out)
+	w.enc = json.NewEncoder(w.buf)
+	return w, nil
+}
+
+func (w *CaptureWriter) Read(result *changestreams.ReadResult) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enc.Encode(result)
+}
+
+// Flush writes the buffered results to the file.
+func (w *CaptureWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+func (w *CaptureWriter) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes the results and closes the file.
+func (w *CaptureWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.buf.Flush()
+	if w.gz != nil {
+		if closeErr := w.gz.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openCapture opens the capture file, decompressing it if it's compressed with gzip.
+func openCapture(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := bufio.NewReader(file)
+	magic, err := r.Peek(2)
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, err
+	}
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &captureReader{Reader: gz, closers: []io.Closer{gz, file}}, nil
+	}
+	return &captureReader{Reader: r, closers: []io.Closer{file}}, nil
+}
+
+type captureReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *captureReader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if closeErr := c.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
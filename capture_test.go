@@ -0,0 +1,83 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCaptureWriter(t *testing.T) {
+	heartbeat := &changestreams.ReadResult{
+		StreamID:       "mystream",
+		PartitionToken: "a",
+		ChangeRecords: []*changestreams.ChangeRecord{
+			{HeartbeatRecords: []*changestreams.HeartbeatRecord{{Timestamp: mustParseTime(t, "2022-12-04T18:00:01Z")}}},
+		},
+	}
+	childPartitions := &changestreams.ReadResult{
+		StreamID: "mystream",
+		ChangeRecords: []*changestreams.ChangeRecord{
+			{ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
+				{
+					StartTimestamp:  mustParseTime(t, "2022-12-04T18:00:00Z"),
+					RecordSequence:  "00000001",
+					ChildPartitions: []*changestreams.ChildPartition{{Token: "a"}},
+				},
+			}},
+		},
+	}
+	dataChange := newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:02Z", "PlayerId"))
+	dataChange.StreamID = "mystream"
+	expected := []*changestreams.ReadResult{childPartitions, heartbeat, dataChange}
+
+	for _, name := range []string{"capture.jsonl", "capture.jsonl.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			w, err := NewCaptureWriter(path)
+			if err != nil {
+				t.Fatalf("NewCaptureWriter error: %v", err)
+			}
+			for _, result := range expected {
+				if err := w.Read(result); err != nil {
+					t.Fatalf("Read error: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close error: %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			var got []*changestreams.ReadResult
+			if err := newReplayReader(path, nil, nil).Read(ctx, func(result *changestreams.ReadResult) error {
+				got = append(got, result)
+				return nil
+			}); err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+			if diff := cmp.Diff(got, expected); diff != "" {
+				t.Errorf("results have diff = %v", diff)
+			}
+		})
+	}
+}
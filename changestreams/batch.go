@@ -0,0 +1,173 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchMaxSize  = 100
+	defaultBatchMaxDelay = time.Second
+)
+
+// BatchConfig is the configuration of ReadBatches.
+type BatchConfig struct {
+	// MaxSize is the maximum number of the results in a batch. Defaults to 100.
+	MaxSize int
+	// MaxDelay is the maximum time a result waits in the batch before the batch is passed. Defaults to 1 second.
+	MaxDelay time.Duration
+}
+
+// ReadBatches reads the change stream like Read, but passes the results to function f in batches flushed by count or
+// time, to reduce the overhead per result of the high-throughput consumers, e.g. BigQuery and Kafka.
+//
+// The results of a batch are in the order they are read, and f is never called concurrently. The partitions are read
+// ahead while a batch is waiting, so the results passed to Read are not yet consumed by f. The remaining results are
+// passed when reading finishes, unless f returned an error.
+func (r *Reader) ReadBatches(ctx context.Context, config BatchConfig, f func(results []*ReadResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	b := newBatcher(config, f, cancel)
+	defer b.stop()
+	err := r.Read(ctx, b.add)
+	if batchErr := b.error(); batchErr != nil {
+		if errors.Is(batchErr, ErrStop) {
+			return nil
+		}
+		return batchErr
+	}
+	if flushErr := b.flush(); err == nil && flushErr != nil && !errors.Is(flushErr, ErrStop) {
+		err = flushErr
+	}
+	return err
+}
+
+// DataChangeRecordsOf returns the data change records of the results.
+func DataChangeRecordsOf(results []*ReadResult) []*DataChangeRecord {
+	var records []*DataChangeRecord
+	for _, result := range results {
+		for _, changeRecord := range result.ChangeRecords {
+			records = append(records, changeRecord.DataChangeRecords...)
+		}
+	}
+	return records
+}
+
+// batcher accumulates the results, and passes them to f when the batch is full or the oldest result waits for MaxDelay.
+type batcher struct {
+	config BatchConfig
+	f      func(results []*ReadResult) error
+	// cancel stops reading when f fails in the timer.
+	cancel context.CancelFunc
+
+	results []*ReadResult
+	timer   *time.Timer
+	// generation is incremented by each batch, to ignore the stale timers.
+	generation int
+	err        error
+	mu         sync.Mutex
+	// flushMu serializes the calls of f, in the order of the batches.
+	flushMu sync.Mutex
+}
+
+func newBatcher(config BatchConfig, f func(results []*ReadResult) error, cancel context.CancelFunc) *batcher {
+	if config.MaxSize <= 0 {
+		config.MaxSize = defaultBatchMaxSize
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = defaultBatchMaxDelay
+	}
+	return &batcher{config: config, f: f, cancel: cancel}
+}
+
+func (b *batcher) add(result *ReadResult) error {
+	b.mu.Lock()
+	if b.err != nil {
+		b.mu.Unlock()
+		return b.err
+	}
+	b.results = append(b.results, result)
+	if len(b.results) == 1 {
+		generation := b.generation
+		b.timer = time.AfterFunc(b.config.MaxDelay, func() {
+			if err := b.flushGeneration(generation); err != nil {
+				b.cancel()
+			}
+		})
+	}
+	full := len(b.results) >= b.config.MaxSize
+	b.mu.Unlock()
+
+	if !full {
+		return nil
+	}
+	return b.flush()
+}
+
+// flush passes the accumulated results to f.
+func (b *batcher) flush() error {
+	return b.flushGeneration(-1)
+}
+
+// flushGeneration passes the accumulated results to f if the batch is of the generation, or any batch if it's negative.
+func (b *batcher) flushGeneration(generation int) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	if b.err != nil {
+		defer b.mu.Unlock()
+		return b.err
+	}
+	if len(b.results) == 0 || (generation >= 0 && generation != b.generation) {
+		b.mu.Unlock()
+		return nil
+	}
+	results := b.results
+	b.results = nil
+	b.generation++
+	b.timer.Stop()
+	b.mu.Unlock()
+
+	if err := b.f(results); err != nil {
+		b.mu.Lock()
+		b.err = err
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (b *batcher) error() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.err
+}
+
+// stop stops the timer of the pending batch.
+func (b *batcher) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+}
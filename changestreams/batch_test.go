@@ -0,0 +1,121 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// batchRecorder records the partition tokens of the batches.
+type batchRecorder struct {
+	batches [][]string
+	mu      sync.Mutex
+}
+
+func (r *batchRecorder) consume(results []*ReadResult) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var tokens []string
+	for _, result := range results {
+		tokens = append(tokens, result.PartitionToken)
+	}
+	r.batches = append(r.batches, tokens)
+	return nil
+}
+
+func (r *batchRecorder) get() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batches
+}
+
+func TestBatcherMaxSize(t *testing.T) {
+	recorder := &batchRecorder{}
+	b := newBatcher(BatchConfig{MaxSize: 2, MaxDelay: time.Hour}, recorder.consume, func() {})
+	defer b.stop()
+	for _, token := range []string{"a", "b", "c"} {
+		if err := b.add(&ReadResult{PartitionToken: token}); err != nil {
+			t.Fatalf("add error: %v", err)
+		}
+	}
+	if diff := cmp.Diff(recorder.get(), [][]string{{"a", "b"}}); diff != "" {
+		t.Errorf("batches have diff = %v", diff)
+	}
+
+	if err := b.flush(); err != nil {
+		t.Fatalf("flush error: %v", err)
+	}
+	if diff := cmp.Diff(recorder.get(), [][]string{{"a", "b"}, {"c"}}); diff != "" {
+		t.Errorf("batches have diff = %v", diff)
+	}
+}
+
+func TestBatcherMaxDelay(t *testing.T) {
+	recorder := &batchRecorder{}
+	b := newBatcher(BatchConfig{MaxSize: 100, MaxDelay: 10 * time.Millisecond}, recorder.consume, func() {})
+	defer b.stop()
+	if err := b.add(&ReadResult{PartitionToken: "a"}); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(recorder.get()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if diff := cmp.Diff(recorder.get(), [][]string{{"a"}}); diff != "" {
+		t.Errorf("batches have diff = %v", diff)
+	}
+}
+
+func TestBatcherError(t *testing.T) {
+	errConsume := errors.New("consume error")
+	canceled := make(chan struct{})
+	b := newBatcher(BatchConfig{MaxSize: 100, MaxDelay: 10 * time.Millisecond}, func(results []*ReadResult) error {
+		return errConsume
+	}, func() { close(canceled) })
+	defer b.stop()
+	if err := b.add(&ReadResult{PartitionToken: "a"}); err != nil {
+		t.Fatalf("add error: %v", err)
+	}
+
+	// The error in the timer cancels reading, and is returned from the next add.
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("reading must be canceled")
+	}
+	if err := b.add(&ReadResult{PartitionToken: "b"}); !errors.Is(err, errConsume) {
+		t.Errorf("add must return the error, but got %v", err)
+	}
+}
+
+func TestDataChangeRecordsOf(t *testing.T) {
+	a := &DataChangeRecord{TableName: "A"}
+	b := &DataChangeRecord{TableName: "B"}
+	results := []*ReadResult{
+		{ChangeRecords: []*ChangeRecord{{DataChangeRecords: []*DataChangeRecord{a}}}},
+		{ChangeRecords: []*ChangeRecord{{HeartbeatRecords: []*HeartbeatRecord{{}}}, {DataChangeRecords: []*DataChangeRecord{b}}}},
+	}
+	if diff := cmp.Diff(DataChangeRecordsOf(results), []*DataChangeRecord{a, b}); diff != "" {
+		t.Errorf("records have diff = %v", diff)
+	}
+}
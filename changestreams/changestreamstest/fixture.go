@@ -0,0 +1,95 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreamstest
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// ReadFixture reads the results of a fixture from r in order. A fixture is the ReadResults in JSON lines, the same as
+// captured by the record command or printed by the verbose output of spanner-change-streams-tail, so that the results
+// of a real database can be kept, e.g. in testdata, and replayed deterministically. It can be compressed with gzip.
+func ReadFixture(r io.Reader) ([]*changestreams.ReadResult, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err != nil && err != io.EOF {
+		return nil, err
+	} else if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var results []*changestreams.ReadResult
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var result changestreams.ReadResult
+		if err := dec.Decode(&result); err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode result %d: %w", line, err)
+		}
+		results = append(results, &result)
+	}
+}
+
+// LoadFixture reads the results of the fixture file.
+func LoadFixture(path string) ([]*changestreams.ReadResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	results, err := ReadFixture(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fixture %s: %w", path, err)
+	}
+	return results, nil
+}
+
+// NewReaderFromFixture returns a reader passing the results of the fixture file.
+func NewReaderFromFixture(path string) (*Reader, error) {
+	results, err := LoadFixture(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(results...), nil
+}
+
+// WriteFixture writes the results to w as a fixture, e.g. to keep the scripted results or the results of a real
+// reader in a file.
+func WriteFixture(w io.Writer, results []*changestreams.ReadResult) error {
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
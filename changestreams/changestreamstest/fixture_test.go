@@ -0,0 +1,108 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreamstest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func readAll(t *testing.T, reader *Reader) []*changestreams.ReadResult {
+	t.Helper()
+	var results []*changestreams.ReadResult
+	if err := reader.Read(context.Background(), func(result *changestreams.ReadResult) error {
+		results = append(results, result)
+		return nil
+	}); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	return results
+}
+
+func TestFixtureRoundTrip(t *testing.T) {
+	want := readAll(t, newTestReader())
+	for _, test := range []struct {
+		desc string
+		gzip bool
+	}{
+		{desc: "plain"},
+		{desc: "gzip", gzip: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var buf bytes.Buffer
+			if test.gzip {
+				gz := gzip.NewWriter(&buf)
+				if err := WriteFixture(gz, want); err != nil {
+					t.Fatalf("WriteFixture error: %v", err)
+				}
+				if err := gz.Close(); err != nil {
+					t.Fatalf("Close error: %v", err)
+				}
+			} else if err := WriteFixture(&buf, want); err != nil {
+				t.Fatalf("WriteFixture error: %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "fixture.jsonl")
+			if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			reader, err := NewReaderFromFixture(path)
+			if err != nil {
+				t.Fatalf("NewReaderFromFixture error: %v", err)
+			}
+			if diff := cmp.Diff(readAll(t, reader), want); diff != "" {
+				t.Errorf("results have diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestReadFixture(t *testing.T) {
+	in := `{"stream_id":"mystream","partition_token":"a","change_record":[{"heartbeat_record":[{"timestamp":"2022-12-04T18:00:00Z"}]}]}
+{"stream_id":"mystream","partition_token":"a","change_record":[{"data_change_record":[{"table_name":"Players","mod_type":"UPDATE","mods":[{"keys":{"PlayerId":"1"},"new_values":{"Score":2},"old_values":{"Score":1}}]}]}]}
+`
+	results, err := ReadFixture(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("ReadFixture error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	if got := results[0].ChangeRecords[0].HeartbeatRecords[0].Timestamp; !got.Equal(time.Date(2022, 12, 4, 18, 0, 0, 0, time.UTC)) {
+		t.Errorf("heartbeat timestamp = %v", got)
+	}
+	mod := changestreams.DataChangeRecordsOf(results)[0].Mods[0]
+	if diff := cmp.Diff(mod.OldValues.Value, map[string]interface{}{"Score": float64(1)}); diff != "" {
+		t.Errorf("old values have diff = %v", diff)
+	}
+}
+
+func TestReadFixtureInvalid(t *testing.T) {
+	_, err := ReadFixture(strings.NewReader("{\"partition_token\":\"a\"}\n{\n"))
+	if err == nil || !strings.Contains(err.Error(), "result 2") {
+		t.Errorf("ReadFixture error = %v, want the error of result 2", err)
+	}
+}
@@ -0,0 +1,116 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreamstest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+// UpdateGoldenEnv is the environment variable which, if set to true, makes CompareGolden write the golden files
+// instead of comparing with them, e.g. `UPDATE_GOLDEN=true go test ./...` after changing the output intentionally.
+const UpdateGoldenEnv = "UPDATE_GOLDEN"
+
+// CompareGolden compares the output with the golden file, and reports the difference by lines as a test error.
+func CompareGolden(t testing.TB, path string, got []byte) {
+	t.Helper()
+
+	if update, _ := strconv.ParseBool(os.Getenv(UpdateGoldenEnv)); update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create the directory of golden file: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		t.Logf("updated golden file %s", path)
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v (run the test with %s=true to create it)", err, UpdateGoldenEnv)
+		return
+	}
+	if diff := cmp.Diff(strings.Split(string(want), "\n"), strings.Split(string(got), "\n")); diff != "" {
+		t.Errorf("output differs from golden file %s (-want +got):\n%s(run the test with %s=true to update it)", path, diff, UpdateGoldenEnv)
+	}
+}
+
+// RunGolden runs the consumer over the results of the fixture file, and compares what it writes to w with the golden
+// file by CompareGolden.
+func RunGolden(t testing.TB, fixturePath, goldenPath string, consume func(ctx context.Context, reader *Reader, w io.Writer) error) {
+	t.Helper()
+
+	reader, err := NewReaderFromFixture(fixturePath)
+	if err != nil {
+		t.Fatalf("failed to create a reader: %v", err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := consume(context.Background(), reader, &buf); err != nil {
+		t.Fatalf("consumer error: %v", err)
+		return
+	}
+	CompareGolden(t, goldenPath, buf.Bytes())
+}
+
+// RecordingSink is a sink recording the results written to it, e.g. to compare the results passed by a sink wrapping
+// it with a golden file, written as a fixture by WriteFixture.
+type RecordingSink struct {
+	results []*changestreams.ReadResult
+	mu      sync.Mutex
+}
+
+// Open does nothing.
+func (s *RecordingSink) Open(ctx context.Context) error {
+	return nil
+}
+
+// Write records the result.
+func (s *RecordingSink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results = append(s.results, result)
+	return nil
+}
+
+// Flush does nothing.
+func (s *RecordingSink) Flush() error {
+	return nil
+}
+
+// Close does nothing.
+func (s *RecordingSink) Close() error {
+	return nil
+}
+
+// Results returns the results written so far.
+func (s *RecordingSink) Results() []*changestreams.ReadResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*changestreams.ReadResult(nil), s.results...)
+}
@@ -0,0 +1,126 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreamstest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// errorRecorder records the test errors instead of failing the test.
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Helper() {}
+
+func (r *errorRecorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *errorRecorder) Fatalf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *errorRecorder) Logf(format string, args ...interface{}) {}
+
+// printTables prints the table names and the mod types of the data change records.
+func printTables(ctx context.Context, reader *Reader, w io.Writer) error {
+	return reader.Read(ctx, func(result *changestreams.ReadResult) error {
+		for _, r := range changestreams.DataChangeRecordsOf([]*changestreams.ReadResult{result}) {
+			fmt.Fprintf(w, "%s %s\n", r.ModType, r.TableName)
+		}
+		return nil
+	})
+}
+
+func writeTestFixture(t *testing.T, dir string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteFixture(&buf, readAll(t, newTestReader())); err != nil {
+		t.Fatalf("WriteFixture error: %v", err)
+	}
+	path := filepath.Join(dir, "fixture.jsonl")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunGolden(t *testing.T) {
+	dir := t.TempDir()
+	fixture := writeTestFixture(t, dir)
+	golden := filepath.Join(dir, "golden", "tables.txt")
+
+	t.Setenv(UpdateGoldenEnv, "true")
+	RunGolden(t, fixture, golden, printTables)
+	if b, err := os.ReadFile(golden); err != nil || string(b) != "INSERT Players\n" {
+		t.Fatalf("golden file = %q, %v, want %q", b, err, "INSERT Players\n")
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	RunGolden(t, fixture, golden, printTables)
+}
+
+func TestCompareGoldenDiff(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "tables.txt")
+	if err := os.WriteFile(golden, []byte("INSERT Players\nDELETE Players\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := &errorRecorder{TB: t}
+	CompareGolden(recorder, golden, []byte("INSERT Players\nUPDATE Players\n"))
+	if len(recorder.errors) != 1 {
+		t.Fatalf("errors = %q, want 1 error", recorder.errors)
+	}
+	for _, want := range []string{"DELETE Players", "UPDATE Players", UpdateGoldenEnv} {
+		if !strings.Contains(recorder.errors[0], want) {
+			t.Errorf("error %q doesn't contain %q", recorder.errors[0], want)
+		}
+	}
+}
+
+func TestCompareGoldenNotFound(t *testing.T) {
+	recorder := &errorRecorder{TB: t}
+	CompareGolden(recorder, filepath.Join(t.TempDir(), "missing.txt"), []byte("INSERT Players\n"))
+	if len(recorder.errors) != 1 || !strings.Contains(recorder.errors[0], UpdateGoldenEnv) {
+		t.Errorf("errors = %q, want an error suggesting %s", recorder.errors, UpdateGoldenEnv)
+	}
+}
+
+func TestRecordingSink(t *testing.T) {
+	sink := &RecordingSink{}
+	if err := newTestReader().ReadToSink(context.Background(), sink); err != nil {
+		t.Fatalf("ReadToSink error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteFixture(&buf, sink.Results()); err != nil {
+		t.Fatalf("WriteFixture error: %v", err)
+	}
+	if n := strings.Count(buf.String(), "\n"); n != 3 {
+		t.Errorf("lines = %d, want 3", n)
+	}
+}
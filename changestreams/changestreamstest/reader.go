@@ -0,0 +1,139 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package changestreamstest provides a fake of changestreams.Reader, which passes the scripted results without Cloud
+// Spanner, so that the applications reading the change streams can unit test their consumers.
+//
+// The consumer should depend on the methods of the reader it calls, rather than *changestreams.Reader, e.g.
+//
+//	type changeReader interface {
+//		Read(ctx context.Context, f func(result *changestreams.ReadResult) error) error
+//	}
+//
+// so that both *changestreams.Reader and *changestreamstest.Reader satisfy it.
+package changestreamstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+const defaultBatchMaxSize = 100
+
+// Reader is a fake of changestreams.Reader, which passes the scripted results to the consumer in order. The results
+// are passed from a single goroutine, unlike the partitions read concurrently by changestreams.Reader.
+type Reader struct {
+	// Err is returned by Read after all the results are passed, e.g. to test the handling of the errors of reading.
+	Err error
+
+	results []*changestreams.ReadResult
+	closed  bool
+	mu      sync.Mutex
+}
+
+// NewReader returns a reader passing the results.
+func NewReader(results ...*changestreams.ReadResult) *Reader {
+	return &Reader{results: results}
+}
+
+// Add appends the results to be passed by the next Read.
+func (r *Reader) Add(results ...*changestreams.ReadResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, results...)
+}
+
+// Read passes the results to function f in order, and then returns Err. If f returns changestreams.ErrStop, Read
+// returns nil, the same as changestreams.Reader.
+func (r *Reader) Read(ctx context.Context, f func(result *changestreams.ReadResult) error) error {
+	r.mu.Lock()
+	results := r.results
+	err := r.Err
+	r.mu.Unlock()
+
+	for _, result := range results {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := f(result); errors.Is(err, changestreams.ErrStop) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// ReadToSink is the same as changestreams.Reader.ReadToSink, for the scripted results.
+func (r *Reader) ReadToSink(ctx context.Context, sink changestreams.Sink) error {
+	if err := sink.Open(ctx); err != nil {
+		return fmt.Errorf("failed to open sink: %w", err)
+	}
+
+	err := r.Read(ctx, sink.Write)
+	if flushErr := sink.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to flush sink: %w", flushErr)
+	}
+	if closeErr := sink.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close sink: %w", closeErr)
+	}
+	return err
+}
+
+// ReadBatches is the same as changestreams.Reader.ReadBatches, for the scripted results. The batches are flushed only
+// by MaxSize, as the results never wait to be read, and the remaining results are passed when reading finishes.
+func (r *Reader) ReadBatches(ctx context.Context, config changestreams.BatchConfig, f func(results []*changestreams.ReadResult) error) error {
+	maxSize := config.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultBatchMaxSize
+	}
+
+	var batch []*changestreams.ReadResult
+	err := r.Read(ctx, func(result *changestreams.ReadResult) error {
+		batch = append(batch, result)
+		if len(batch) < maxSize {
+			return nil
+		}
+		results := batch
+		batch = nil
+		return f(results)
+	})
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	if err := f(batch); err != nil && !errors.Is(err, changestreams.ErrStop) {
+		return err
+	}
+	return nil
+}
+
+// Close marks the reader closed, which Closed reports.
+func (r *Reader) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+}
+
+// Closed reports whether Close has been called, e.g. to test that the consumer releases the reader.
+func (r *Reader) Closed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.closed
+}
@@ -0,0 +1,179 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreamstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+// changeReader is the interface of the readers that the consumers depend on.
+type changeReader interface {
+	Read(ctx context.Context, f func(result *changestreams.ReadResult) error) error
+	ReadToSink(ctx context.Context, sink changestreams.Sink) error
+	ReadBatches(ctx context.Context, config changestreams.BatchConfig, f func(results []*changestreams.ReadResult) error) error
+	Close()
+}
+
+var (
+	_ changeReader = (*changestreams.Reader)(nil)
+	_ changeReader = (*Reader)(nil)
+)
+
+// tokenSink records the partition tokens of the results written.
+type tokenSink struct {
+	tokens []string
+	opened bool
+	closed bool
+}
+
+func (s *tokenSink) Open(ctx context.Context) error {
+	s.opened = true
+	return nil
+}
+
+func (s *tokenSink) Write(result *changestreams.ReadResult) error {
+	s.tokens = append(s.tokens, result.PartitionToken)
+	return nil
+}
+
+func (s *tokenSink) Flush() error { return nil }
+
+func (s *tokenSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func newTestReader() *Reader {
+	ts := time.Date(2022, 12, 4, 18, 0, 0, 0, time.UTC)
+	record := &changestreams.DataChangeRecord{
+		CommitTimestamp: ts,
+		TableName:       "Players",
+		Mods:            []*changestreams.Mod{Mod(map[string]interface{}{"PlayerId": "1"}, map[string]interface{}{"Name": "foo"}, nil)},
+		ModType:         "INSERT",
+	}
+	return NewReader(
+		DataChange("a", record),
+		Heartbeat("a", ts.Add(time.Second)),
+		ChildPartitions("a", ts.Add(2*time.Second), "b", "c"),
+	)
+}
+
+func TestReaderRead(t *testing.T) {
+	reader := newTestReader()
+	var results []*changestreams.ReadResult
+	if err := reader.Read(context.Background(), func(result *changestreams.ReadResult) error {
+		results = append(results, result)
+		return nil
+	}); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+
+	records := changestreams.DataChangeRecordsOf(results)
+	if len(records) != 1 {
+		t.Fatalf("len(records) = %d, want 1", len(records))
+	}
+	if got := records[0].Mods[0].OldValues; !got.Valid || len(got.Value.(map[string]interface{})) != 0 {
+		t.Errorf("OldValues = %v, want an empty object", got)
+	}
+	if got := results[1].ChangeRecords[0].HeartbeatRecords[0].Timestamp; !got.Equal(time.Date(2022, 12, 4, 18, 0, 1, 0, time.UTC)) {
+		t.Errorf("heartbeat timestamp = %v", got)
+	}
+	children := results[2].ChangeRecords[0].ChildPartitionsRecords[0].ChildPartitions
+	want := []*changestreams.ChildPartition{
+		{Token: "b", ParentPartitionTokens: []string{"a"}},
+		{Token: "c", ParentPartitionTokens: []string{"a"}},
+	}
+	if diff := cmp.Diff(children, want); diff != "" {
+		t.Errorf("child partitions have diff = %v", diff)
+	}
+}
+
+func TestReaderReadStop(t *testing.T) {
+	reader := newTestReader()
+	var n int
+	if err := reader.Read(context.Background(), func(result *changestreams.ReadResult) error {
+		n++
+		return changestreams.ErrStop
+	}); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("results passed = %d, want 1", n)
+	}
+}
+
+func TestReaderReadError(t *testing.T) {
+	reader := newTestReader()
+	reader.Err = errors.New("unavailable")
+	var n int
+	err := reader.Read(context.Background(), func(result *changestreams.ReadResult) error {
+		n++
+		return nil
+	})
+	if !errors.Is(err, reader.Err) {
+		t.Errorf("Read error = %v, want %v", err, reader.Err)
+	}
+	if n != 3 {
+		t.Errorf("results passed = %d, want 3", n)
+	}
+}
+
+func TestReaderReadToSink(t *testing.T) {
+	reader := newTestReader()
+	reader.Add(DataChange("b"))
+	sink := &tokenSink{}
+	if err := reader.ReadToSink(context.Background(), sink); err != nil {
+		t.Fatalf("ReadToSink error: %v", err)
+	}
+	if !sink.opened || !sink.closed {
+		t.Errorf("sink opened = %v, closed = %v, want both true", sink.opened, sink.closed)
+	}
+	if diff := cmp.Diff(sink.tokens, []string{"a", "a", "a", "b"}); diff != "" {
+		t.Errorf("tokens have diff = %v", diff)
+	}
+}
+
+func TestReaderReadBatches(t *testing.T) {
+	reader := newTestReader()
+	var sizes []int
+	if err := reader.ReadBatches(context.Background(), changestreams.BatchConfig{MaxSize: 2}, func(results []*changestreams.ReadResult) error {
+		sizes = append(sizes, len(results))
+		return nil
+	}); err != nil {
+		t.Fatalf("ReadBatches error: %v", err)
+	}
+	if diff := cmp.Diff(sizes, []int{2, 1}); diff != "" {
+		t.Errorf("batch sizes have diff = %v", diff)
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	reader := NewReader()
+	if reader.Closed() {
+		t.Fatal("Closed() = true before Close")
+	}
+	reader.Close()
+	if !reader.Closed() {
+		t.Error("Closed() = false after Close")
+	}
+}
@@ -0,0 +1,81 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreamstest
+
+import (
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// DataChange returns a result of the partition with the data change records.
+func DataChange(partitionToken string, records ...*changestreams.DataChangeRecord) *changestreams.ReadResult {
+	return &changestreams.ReadResult{
+		PartitionToken: partitionToken,
+		ChangeRecords:  []*changestreams.ChangeRecord{{DataChangeRecords: records}},
+	}
+}
+
+// Heartbeat returns a result of the partition with a heartbeat record at the timestamp.
+func Heartbeat(partitionToken string, timestamp time.Time) *changestreams.ReadResult {
+	return &changestreams.ReadResult{
+		PartitionToken: partitionToken,
+		ChangeRecords: []*changestreams.ChangeRecord{{
+			HeartbeatRecords: []*changestreams.HeartbeatRecord{{Timestamp: timestamp}},
+		}},
+	}
+}
+
+// ChildPartitions returns a result of the partition with a child partitions record, whose children start at the
+// timestamp with the partition as their parent.
+func ChildPartitions(partitionToken string, startTimestamp time.Time, childTokens ...string) *changestreams.ReadResult {
+	children := make([]*changestreams.ChildPartition, 0, len(childTokens))
+	for _, token := range childTokens {
+		children = append(children, &changestreams.ChildPartition{
+			Token:                 token,
+			ParentPartitionTokens: []string{partitionToken},
+		})
+	}
+	return &changestreams.ReadResult{
+		PartitionToken: partitionToken,
+		ChangeRecords: []*changestreams.ChangeRecord{{
+			ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{{
+				StartTimestamp:  startTimestamp,
+				RecordSequence:  "00000000",
+				ChildPartitions: children,
+			}},
+		}},
+	}
+}
+
+// Mod returns a mod of the keys and the values. A nil map of the values is an empty object, the same as the values
+// not captured by Cloud Spanner, e.g. the old values of INSERT.
+func Mod(keys, newValues, oldValues map[string]interface{}) *changestreams.Mod {
+	return &changestreams.Mod{
+		Keys:      jsonObject(keys),
+		NewValues: jsonObject(newValues),
+		OldValues: jsonObject(oldValues),
+	}
+}
+
+func jsonObject(m map[string]interface{}) spanner.NullJSON {
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	return spanner.NullJSON{Value: m, Valid: true}
+}
@@ -0,0 +1,111 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"fmt"
+	"sync"
+)
+
+// deadLetterMinRecords is the number of the records before the error rate of DeadLetterSink is checked, not to fail
+// by the first few errors.
+const deadLetterMinRecords = 100
+
+// DeadLetter is a data change record that the sink failed to write.
+type DeadLetter struct {
+	StreamID       string
+	PartitionToken string
+	Record         *DataChangeRecord
+	Err            error
+}
+
+// DeadLetterSink passes the data change records that the sink fails to write to a handler, e.g. to save them to a
+// file or to publish them to a Pub/Sub topic, and continues reading instead of failing.
+//
+// When the sink fails to write a result, its data change records are written again one by one to find the failed
+// ones, so the others may be written twice. The raw change record and the row are of the whole result, so they are not
+// passed with the records written one by one.
+type DeadLetterSink struct {
+	Sink
+	handler      func(deadLetter *DeadLetter) error
+	maxErrorRate float64
+
+	records     int64
+	deadLetters int64
+	mu          sync.Mutex
+}
+
+// NewDeadLetterSink creates a new DeadLetterSink. If the ratio of the dead letters to the data change records exceeds
+// maxErrorRate, or the handler fails, Write fails. If maxErrorRate is zero, the ratio is not limited.
+func NewDeadLetterSink(sink Sink, handler func(deadLetter *DeadLetter) error, maxErrorRate float64) *DeadLetterSink {
+	return &DeadLetterSink{
+		Sink:         sink,
+		handler:      handler,
+		maxErrorRate: maxErrorRate,
+	}
+}
+
+func (s *DeadLetterSink) Write(result *ReadResult) error {
+	var records []*DataChangeRecord
+	var others []*ChangeRecord
+	for _, changeRecord := range result.ChangeRecords {
+		records = append(records, changeRecord.DataChangeRecords...)
+		if len(changeRecord.HeartbeatRecords) > 0 || len(changeRecord.ChildPartitionsRecords) > 0 {
+			others = append(others, &ChangeRecord{
+				HeartbeatRecords:       changeRecord.HeartbeatRecords,
+				ChildPartitionsRecords: changeRecord.ChildPartitionsRecords,
+			})
+		}
+	}
+
+	err := s.Sink.Write(result)
+	if err != nil && len(records) == 0 {
+		// Only the data change records can be dead letters.
+		return err
+	}
+
+	var deadLetters int64
+	if err != nil {
+		for _, r := range records {
+			single := &ReadResult{
+				StreamID:       result.StreamID,
+				PartitionToken: result.PartitionToken,
+				ChangeRecords:  []*ChangeRecord{{DataChangeRecords: []*DataChangeRecord{r}}},
+			}
+			if err := s.Sink.Write(single); err != nil {
+				if err := s.handler(&DeadLetter{StreamID: result.StreamID, PartitionToken: result.PartitionToken, Record: r, Err: err}); err != nil {
+					return fmt.Errorf("failed to handle dead letter: %w", err)
+				}
+				deadLetters++
+			}
+		}
+		if len(others) > 0 {
+			if err := s.Sink.Write(&ReadResult{StreamID: result.StreamID, PartitionToken: result.PartitionToken, ChangeRecords: others}); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records += int64(len(records))
+	s.deadLetters += deadLetters
+	if s.maxErrorRate > 0 && s.records >= deadLetterMinRecords && float64(s.deadLetters)/float64(s.records) > s.maxErrorRate {
+		return fmt.Errorf("too many dead letters: %d of %d records", s.deadLetters, s.records)
+	}
+	return nil
+}
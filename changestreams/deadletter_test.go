@@ -0,0 +1,146 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// failingSink fails to write the results containing the records of the table "Bad".
+type failingSink struct {
+	testSink
+	written []string
+}
+
+func (s *failingSink) Write(result *ReadResult) error {
+	var tables []string
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			if r.TableName == "Bad" {
+				return errors.New("bad record")
+			}
+			tables = append(tables, r.TableName)
+		}
+		if len(changeRecord.HeartbeatRecords) > 0 {
+			tables = append(tables, "heartbeat")
+		}
+	}
+	s.written = append(s.written, tables...)
+	return nil
+}
+
+func TestDeadLetterSink(t *testing.T) {
+	sink := &failingSink{}
+	var deadLetters []string
+	s := NewDeadLetterSink(sink, func(d *DeadLetter) error {
+		deadLetters = append(deadLetters, d.StreamID+"/"+d.PartitionToken+"/"+d.Record.TableName+": "+d.Err.Error())
+		return nil
+	}, 0.1)
+
+	result := &ReadResult{
+		StreamID:       "s",
+		PartitionToken: "a",
+		ChangeRecords: []*ChangeRecord{
+			{
+				DataChangeRecords: []*DataChangeRecord{{TableName: "A"}, {TableName: "Bad"}, {TableName: "B"}},
+				HeartbeatRecords:  []*HeartbeatRecord{{}},
+			},
+		},
+	}
+	if err := s.Write(result); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if diff := cmp.Diff(sink.written, []string{"A", "B", "heartbeat"}); diff != "" {
+		t.Errorf("written records have diff = %v", diff)
+	}
+	if diff := cmp.Diff(deadLetters, []string{"s/a/Bad: bad record"}); diff != "" {
+		t.Errorf("dead letters have diff = %v", diff)
+	}
+
+	// 10 dead letters of 100 records don't exceed the rate, but 11 do.
+	write := func(table string, n int) error {
+		for i := 0; i < n; i++ {
+			if err := s.Write(&ReadResult{ChangeRecords: []*ChangeRecord{{DataChangeRecords: []*DataChangeRecord{{TableName: table}}}}}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := write("A", 88); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := write("Bad", 9); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := write("Bad", 1); err == nil {
+		t.Errorf("Write must fail when the dead letters exceed the rate")
+	}
+}
+
+func TestDeadLetterSinkHandlerError(t *testing.T) {
+	s := NewDeadLetterSink(&failingSink{}, func(d *DeadLetter) error {
+		return errors.New("handler error")
+	}, 0)
+	err := s.Write(&ReadResult{ChangeRecords: []*ChangeRecord{{DataChangeRecords: []*DataChangeRecord{{TableName: "Bad"}}}}})
+	if err == nil {
+		t.Errorf("Write must fail when the handler fails")
+	}
+}
+
+// splitSink fails to write more than one data change record at once, and records whether the raw change records are
+// passed.
+type splitSink struct {
+	testSink
+	raws []json.RawMessage
+}
+
+func (s *splitSink) Write(result *ReadResult) error {
+	if n := len(DataChangeRecordsOf([]*ReadResult{result})); n > 1 {
+		return errors.New("too many records")
+	}
+	s.raws = append(s.raws, result.RawChangeRecord)
+	return nil
+}
+
+func TestDeadLetterSinkRawChangeRecord(t *testing.T) {
+	sink := &splitSink{}
+	var deadLetters int
+	s := NewDeadLetterSink(sink, func(d *DeadLetter) error {
+		deadLetters++
+		return nil
+	}, 0)
+	err := s.Write(&ReadResult{
+		ChangeRecords:   []*ChangeRecord{{DataChangeRecords: []*DataChangeRecord{{TableName: "A"}, {TableName: "B"}}}},
+		RawChangeRecord: []byte(`[{"data_change_record":[{},{}]}]`),
+	})
+	if err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if len(sink.raws) != 2 || deadLetters != 0 {
+		t.Fatalf("written = %d, dead letters = %d, want 2 and 0", len(sink.raws), deadLetters)
+	}
+	// The raw change record of the whole result must not be written again with each record.
+	for i, raw := range sink.raws {
+		if raw != nil {
+			t.Errorf("raw change record of the record %d = %s, want nil", i, raw)
+		}
+	}
+}
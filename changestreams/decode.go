@@ -0,0 +1,390 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// errDecodeFallback is returned by the decoder for the values it doesn't handle, e.g. NULL in a non-nullable field,
+// so that the row is decoded by reflection instead, with the same result or error as before.
+var errDecodeFallback = errors.New("changestreams: decode by reflection")
+
+// LazyMods is the undecoded mods of a data change record read with Config.LazyMods.
+type LazyMods struct {
+	t *sppb.Type
+	v *structpb.Value
+}
+
+// DecodeMods decodes the mods left in LazyMods into Mods, if any. It must not be called concurrently for the record.
+func (r *DataChangeRecord) DecodeMods() error {
+	if r.LazyMods == nil {
+		return nil
+	}
+	mods, err := decodeStructArray(r.LazyMods.t, r.LazyMods.v, decodeMod)
+	if errors.Is(err, errDecodeFallback) {
+		mods = nil
+		err = spanner.GenericColumnValue{Type: r.LazyMods.t, Value: r.LazyMods.v}.Decode(&mods)
+	}
+	if err != nil {
+		return err
+	}
+	r.Mods = mods
+	r.LazyMods = nil
+	return nil
+}
+
+// modsDecoding is how the mods and the column types of the data change records are decoded.
+type modsDecoding int
+
+const (
+	modsDecoded modsDecoding = iota
+	// modsSkipped skips the mods and the column types, e.g. for raw JSON.
+	modsSkipped
+	// modsLazy leaves the mods undecoded in LazyMods until DecodeMods is called.
+	modsLazy
+)
+
+// structDecoder decodes a struct from the fields and the values of the struct.
+type structDecoder[T any] func(fields []*sppb.StructType_Field, values []*structpb.Value) (*T, error)
+
+// decodeChangeRecords decodes the ChangeRecord column of the row of GoogleSQL without reflection, as ToStructLenient
+// is a hot spot on busy streams. The unknown fields are ignored as ToStructLenient does.
+func decodeChangeRecords(row *spanner.Row, mods modsDecoding) ([]*ChangeRecord, error) {
+	col, err := changeRecordColumn(row)
+	if err != nil {
+		return nil, err
+	}
+	return decodeStructArray(col.Type, col.Value, changeRecordDecoder(mods))
+}
+
+// changeRecordColumn returns the ChangeRecord column of the row of GoogleSQL.
+func changeRecordColumn(row *spanner.Row) (*spanner.GenericColumnValue, error) {
+	if row.Size() != 1 || row.ColumnName(0) != "ChangeRecord" {
+		return nil, errDecodeFallback
+	}
+	var col spanner.GenericColumnValue
+	if err := row.Column(0, &col); err != nil {
+		return nil, err
+	}
+	return &col, nil
+}
+
+// changeRecordDecoder returns the decoder of ChangeRecord decoding the mods of the data change records as specified.
+func changeRecordDecoder(mods modsDecoding) structDecoder[ChangeRecord] {
+	decodeDataChangeRecord := dataChangeRecordDecoder(mods)
+	return func(fields []*sppb.StructType_Field, values []*structpb.Value) (*ChangeRecord, error) {
+		var r ChangeRecord
+		for i, f := range fields {
+			var err error
+			switch f.Name {
+			case "data_change_record":
+				r.DataChangeRecords, err = decodeStructArray(f.Type, values[i], decodeDataChangeRecord)
+			case "heartbeat_record":
+				r.HeartbeatRecords, err = decodeStructArray(f.Type, values[i], decodeHeartbeatRecord)
+			case "child_partitions_record":
+				r.ChildPartitionsRecords, err = decodeStructArray(f.Type, values[i], decodeChildPartitionsRecord)
+			default:
+				err = checkUnknownField(f)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return &r, nil
+	}
+}
+
+func dataChangeRecordDecoder(mods modsDecoding) structDecoder[DataChangeRecord] {
+	return func(fields []*sppb.StructType_Field, values []*structpb.Value) (*DataChangeRecord, error) {
+		return decodeDataChangeRecord(fields, values, mods)
+	}
+}
+
+func decodeDataChangeRecord(fields []*sppb.StructType_Field, values []*structpb.Value, mods modsDecoding) (*DataChangeRecord, error) {
+	var r DataChangeRecord
+	for i, f := range fields {
+		var err error
+		switch f.Name {
+		case "commit_timestamp":
+			r.CommitTimestamp, err = decodeTimestamp(f.Type, values[i])
+		case "record_sequence":
+			r.RecordSequence, err = decodeString(f.Type, values[i])
+		case "server_transaction_id":
+			r.ServerTransactionID, err = decodeString(f.Type, values[i])
+		case "is_last_record_in_transaction_in_partition":
+			r.IsLastRecordInTransactionInPartition, err = decodeBool(f.Type, values[i])
+		case "table_name":
+			r.TableName, err = decodeString(f.Type, values[i])
+		case "column_types":
+			if mods != modsSkipped {
+				r.ColumnTypes, err = decodeStructArray(f.Type, values[i], decodeColumnType)
+			}
+		case "mods":
+			switch mods {
+			case modsDecoded:
+				r.Mods, err = decodeStructArray(f.Type, values[i], decodeMod)
+			case modsLazy:
+				r.LazyMods = &LazyMods{t: f.Type, v: values[i]}
+			}
+		case "mod_type":
+			r.ModType, err = decodeString(f.Type, values[i])
+		case "value_capture_type":
+			r.ValueCaptureType, err = decodeString(f.Type, values[i])
+		case "number_of_records_in_transaction":
+			r.NumberOfRecordsInTransaction, err = decodeInt64(f.Type, values[i])
+		case "number_of_partitions_in_transaction":
+			r.NumberOfPartitionsInTransaction, err = decodeInt64(f.Type, values[i])
+		case "transaction_tag":
+			r.TransactionTag, err = decodeString(f.Type, values[i])
+		case "is_system_transaction":
+			r.IsSystemTransaction, err = decodeBool(f.Type, values[i])
+		default:
+			err = checkUnknownField(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+func decodeColumnType(fields []*sppb.StructType_Field, values []*structpb.Value) (*ColumnType, error) {
+	var c ColumnType
+	for i, f := range fields {
+		var err error
+		switch f.Name {
+		case "name":
+			c.Name, err = decodeString(f.Type, values[i])
+		case "type":
+			c.Type, err = decodeJSON(f.Type, values[i])
+		case "is_primary_key":
+			c.IsPrimaryKey, err = decodeBool(f.Type, values[i])
+		case "ordinal_position":
+			c.OrdinalPosition, err = decodeInt64(f.Type, values[i])
+		default:
+			err = checkUnknownField(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+func decodeMod(fields []*sppb.StructType_Field, values []*structpb.Value) (*Mod, error) {
+	var m Mod
+	for i, f := range fields {
+		var err error
+		switch f.Name {
+		case "keys":
+			m.Keys, err = decodeJSON(f.Type, values[i])
+		case "new_values":
+			m.NewValues, err = decodeJSON(f.Type, values[i])
+		case "old_values":
+			m.OldValues, err = decodeJSON(f.Type, values[i])
+		default:
+			err = checkUnknownField(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &m, nil
+}
+
+func decodeHeartbeatRecord(fields []*sppb.StructType_Field, values []*structpb.Value) (*HeartbeatRecord, error) {
+	var r HeartbeatRecord
+	for i, f := range fields {
+		var err error
+		switch f.Name {
+		case "timestamp":
+			r.Timestamp, err = decodeTimestamp(f.Type, values[i])
+		default:
+			err = checkUnknownField(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+func decodeChildPartitionsRecord(fields []*sppb.StructType_Field, values []*structpb.Value) (*ChildPartitionsRecord, error) {
+	var r ChildPartitionsRecord
+	for i, f := range fields {
+		var err error
+		switch f.Name {
+		case "start_timestamp":
+			r.StartTimestamp, err = decodeTimestamp(f.Type, values[i])
+		case "record_sequence":
+			r.RecordSequence, err = decodeString(f.Type, values[i])
+		case "child_partitions":
+			r.ChildPartitions, err = decodeStructArray(f.Type, values[i], decodeChildPartition)
+		default:
+			err = checkUnknownField(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &r, nil
+}
+
+func decodeChildPartition(fields []*sppb.StructType_Field, values []*structpb.Value) (*ChildPartition, error) {
+	var p ChildPartition
+	for i, f := range fields {
+		var err error
+		switch f.Name {
+		case "token":
+			p.Token, err = decodeString(f.Type, values[i])
+		case "parent_partition_tokens":
+			p.ParentPartitionTokens, err = decodeStringArray(f.Type, values[i])
+		default:
+			err = checkUnknownField(f)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+// checkUnknownField falls back to reflection for the field names matching case-insensitively, which it may decode.
+func checkUnknownField(f *sppb.StructType_Field) error {
+	for i := 0; i < len(f.Name); i++ {
+		if c := f.Name[i]; 'A' <= c && c <= 'Z' {
+			return errDecodeFallback
+		}
+	}
+	return nil
+}
+
+// decodeStructArray decodes an array of structs. A NULL array is decoded into a nil slice.
+func decodeStructArray[T any](t *sppb.Type, v *structpb.Value, decode structDecoder[T]) ([]*T, error) {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return nil, nil
+	}
+	if t.GetCode() != sppb.TypeCode_ARRAY || t.GetArrayElementType().GetCode() != sppb.TypeCode_STRUCT {
+		return nil, errDecodeFallback
+	}
+	list := v.GetListValue()
+	if list == nil {
+		return nil, errDecodeFallback
+	}
+	fields := t.GetArrayElementType().GetStructType().GetFields()
+	structs := make([]*T, 0, len(list.Values))
+	for _, element := range list.Values {
+		values := element.GetListValue()
+		if values == nil || len(values.Values) != len(fields) {
+			return nil, errDecodeFallback
+		}
+		s, err := decode(fields, values.Values)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, s)
+	}
+	return structs, nil
+}
+
+func decodeStringArray(t *sppb.Type, v *structpb.Value) ([]string, error) {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return nil, nil
+	}
+	if t.GetCode() != sppb.TypeCode_ARRAY || t.GetArrayElementType().GetCode() != sppb.TypeCode_STRING {
+		return nil, errDecodeFallback
+	}
+	list := v.GetListValue()
+	if list == nil {
+		return nil, errDecodeFallback
+	}
+	array := make([]string, 0, len(list.Values))
+	for _, element := range list.Values {
+		s, ok := element.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return nil, errDecodeFallback
+		}
+		array = append(array, s.StringValue)
+	}
+	return array, nil
+}
+
+func decodeString(t *sppb.Type, v *structpb.Value) (string, error) {
+	s, ok := v.GetKind().(*structpb.Value_StringValue)
+	if t.GetCode() != sppb.TypeCode_STRING || !ok {
+		return "", errDecodeFallback
+	}
+	return s.StringValue, nil
+}
+
+func decodeBool(t *sppb.Type, v *structpb.Value) (bool, error) {
+	b, ok := v.GetKind().(*structpb.Value_BoolValue)
+	if t.GetCode() != sppb.TypeCode_BOOL || !ok {
+		return false, errDecodeFallback
+	}
+	return b.BoolValue, nil
+}
+
+func decodeInt64(t *sppb.Type, v *structpb.Value) (int64, error) {
+	s, ok := v.GetKind().(*structpb.Value_StringValue)
+	if t.GetCode() != sppb.TypeCode_INT64 || !ok {
+		return 0, errDecodeFallback
+	}
+	n, err := strconv.ParseInt(s.StringValue, 10, 64)
+	if err != nil {
+		return 0, errDecodeFallback
+	}
+	return n, nil
+}
+
+func decodeTimestamp(t *sppb.Type, v *structpb.Value) (time.Time, error) {
+	s, ok := v.GetKind().(*structpb.Value_StringValue)
+	if t.GetCode() != sppb.TypeCode_TIMESTAMP || !ok {
+		return time.Time{}, errDecodeFallback
+	}
+	ts, err := time.Parse(time.RFC3339Nano, s.StringValue)
+	if err != nil {
+		return time.Time{}, errDecodeFallback
+	}
+	return ts, nil
+}
+
+func decodeJSON(t *sppb.Type, v *structpb.Value) (spanner.NullJSON, error) {
+	if t.GetCode() != sppb.TypeCode_JSON {
+		return spanner.NullJSON{}, errDecodeFallback
+	}
+	switch x := v.GetKind().(type) {
+	case *structpb.Value_NullValue:
+		return spanner.NullJSON{}, nil
+	case *structpb.Value_StringValue:
+		var value interface{}
+		if err := json.Unmarshal([]byte(x.StringValue), &value); err != nil {
+			return spanner.NullJSON{}, errDecodeFallback
+		}
+		return spanner.NullJSON{Value: value, Valid: true}, nil
+	default:
+		return spanner.NullJSON{}, errDecodeFallback
+	}
+}
@@ -0,0 +1,152 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestChangeRecordRow(t testing.TB) *spanner.Row {
+	changeRecords := []*ChangeRecord{
+		{
+			DataChangeRecords: []*DataChangeRecord{
+				{
+					CommitTimestamp:                      mustParseTime("2022-12-04T18:00:00.123456Z"),
+					RecordSequence:                       "00000001",
+					ServerTransactionID:                  "tx",
+					IsLastRecordInTransactionInPartition: true,
+					TableName:                            "Players",
+					ColumnTypes: []*ColumnType{
+						{Name: "PlayerId", Type: spanner.NullJSON{Value: map[string]interface{}{"code": "INT64"}, Valid: true}, IsPrimaryKey: true, OrdinalPosition: 1},
+						{Name: "Name", Type: spanner.NullJSON{Value: map[string]interface{}{"code": "STRING"}, Valid: true}, OrdinalPosition: 2},
+					},
+					Mods: []*Mod{
+						{
+							Keys:      spanner.NullJSON{Value: map[string]interface{}{"PlayerId": "1"}, Valid: true},
+							NewValues: spanner.NullJSON{Value: map[string]interface{}{"Name": "foo", "Score": 1.5}, Valid: true},
+						},
+					},
+					ModType:                         "INSERT",
+					ValueCaptureType:                "OLD_AND_NEW_VALUES",
+					NumberOfRecordsInTransaction:    1,
+					NumberOfPartitionsInTransaction: 2,
+				},
+			},
+			HeartbeatRecords:       []*HeartbeatRecord{},
+			ChildPartitionsRecords: []*ChildPartitionsRecord{},
+		},
+		{
+			DataChangeRecords: []*DataChangeRecord{},
+			HeartbeatRecords:  []*HeartbeatRecord{{Timestamp: mustParseTime("2022-12-04T18:00:10Z")}},
+			ChildPartitionsRecords: []*ChildPartitionsRecord{
+				{
+					StartTimestamp:  mustParseTime("2022-12-04T18:00:20Z"),
+					RecordSequence:  "00000002",
+					ChildPartitions: []*ChildPartition{{Token: "b", ParentPartitionTokens: []string{"a"}}},
+				},
+			},
+		},
+	}
+	row, err := spanner.NewRow([]string{"ChangeRecord"}, []interface{}{changeRecords})
+	if err != nil {
+		t.Fatalf("NewRow error: %v", err)
+	}
+	return row
+}
+
+func TestDecodeChangeRecords(t *testing.T) {
+	row := newTestChangeRecordRow(t)
+
+	got, err := decodeChangeRecords(row, modsDecoded)
+	if err != nil {
+		t.Fatalf("decodeChangeRecords error: %v", err)
+	}
+	var expected ReadResult
+	if err := row.ToStructLenient(&expected); err != nil {
+		t.Fatalf("ToStructLenient error: %v", err)
+	}
+	if diff := cmp.Diff(got, expected.ChangeRecords); diff != "" {
+		t.Errorf("decodeChangeRecords has diff = %v", diff)
+	}
+}
+
+func TestDecodeChangeRecordsLazy(t *testing.T) {
+	row := newTestChangeRecordRow(t)
+
+	got, err := decodeChangeRecords(row, modsLazy)
+	if err != nil {
+		t.Fatalf("decodeChangeRecords error: %v", err)
+	}
+	r := got[0].DataChangeRecords[0]
+	if r.Mods != nil || r.LazyMods == nil {
+		t.Fatalf("mods must be left undecoded")
+	}
+	if err := r.DecodeMods(); err != nil {
+		t.Fatalf("DecodeMods error: %v", err)
+	}
+	if r.LazyMods != nil {
+		t.Errorf("LazyMods must be cleared once decoded")
+	}
+
+	var expected ReadResult
+	if err := row.ToStructLenient(&expected); err != nil {
+		t.Fatalf("ToStructLenient error: %v", err)
+	}
+	if diff := cmp.Diff(got, expected.ChangeRecords); diff != "" {
+		t.Errorf("decodeChangeRecords has diff = %v", diff)
+	}
+}
+
+func TestDecodeChangeRecordsFallback(t *testing.T) {
+	// NULL can't be decoded into time.Time, which is left to reflection.
+	type heartbeatRecord struct {
+		Timestamp spanner.NullTime `spanner:"timestamp"`
+	}
+	row, err := spanner.NewRow([]string{"ChangeRecord"}, []interface{}{[]*struct {
+		HeartbeatRecords []*heartbeatRecord `spanner:"heartbeat_record"`
+	}{{HeartbeatRecords: []*heartbeatRecord{{}}}}})
+	if err != nil {
+		t.Fatalf("NewRow error: %v", err)
+	}
+	if _, err := decodeChangeRecords(row, modsDecoded); err != errDecodeFallback {
+		t.Errorf("decodeChangeRecords must fall back, but got %v", err)
+	}
+}
+
+func BenchmarkDecodeChangeRecords(b *testing.B) {
+	row := newTestChangeRecordRow(b)
+	b.Run("reflection", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var result ReadResult
+			if err := row.ToStructLenient(&result); err != nil {
+				b.Fatalf("ToStructLenient error: %v", err)
+			}
+		}
+	})
+	b.Run("decoder", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := decodeChangeRecords(row, modsDecoded); err != nil {
+				b.Fatalf("decodeChangeRecords error: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,212 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+// Defaults of the change stream options.
+const (
+	DefaultValueCaptureType = "OLD_AND_NEW_VALUES"
+	DefaultRetentionPeriod  = "1d"
+)
+
+// StreamDescription is the configuration and the current state of a change stream.
+type StreamDescription struct {
+	Name string `json:"name"`
+	// All is true if the stream watches all the tables.
+	All              bool           `json:"all"`
+	Tables           []WatchedTable `json:"tables"`
+	ValueCaptureType string         `json:"value_capture_type"`
+	RetentionPeriod  string         `json:"retention_period"`
+	// Partitions is the number of the partitions at the time of the description.
+	Partitions int `json:"partitions"`
+}
+
+// WatchedTable is a table watched by a change stream.
+type WatchedTable struct {
+	Name string `json:"name"`
+	// AllColumns is true if the stream watches all the columns of the table.
+	// Otherwise, it watches only Columns in addition to the primary key columns.
+	AllColumns bool     `json:"all_columns"`
+	Columns    []string `json:"columns"`
+}
+
+type describeQueries struct {
+	stream, options, tables, columns string
+	param                            string
+}
+
+var describeQueriesByDialect = map[dialect]describeQueries{
+	dialectGoogleSQL: {
+		stream:  "SELECT cs.ALL FROM INFORMATION_SCHEMA.CHANGE_STREAMS AS cs WHERE cs.CHANGE_STREAM_NAME = @name",
+		options: "SELECT OPTION_NAME, OPTION_VALUE FROM INFORMATION_SCHEMA.CHANGE_STREAM_OPTIONS WHERE CHANGE_STREAM_NAME = @name",
+		tables:  "SELECT TABLE_NAME, ALL_COLUMNS FROM INFORMATION_SCHEMA.CHANGE_STREAM_TABLES WHERE CHANGE_STREAM_NAME = @name ORDER BY TABLE_NAME",
+		columns: "SELECT TABLE_NAME, COLUMN_NAME FROM INFORMATION_SCHEMA.CHANGE_STREAM_COLUMNS WHERE CHANGE_STREAM_NAME = @name ORDER BY TABLE_NAME, COLUMN_NAME",
+		param:   "name",
+	},
+	dialectPostgreSQL: {
+		stream:  `SELECT cs."all" FROM information_schema.change_streams AS cs WHERE cs.change_stream_name = $1`,
+		options: "SELECT option_name, option_value FROM information_schema.change_stream_options WHERE change_stream_name = $1",
+		tables:  "SELECT table_name, all_columns FROM information_schema.change_stream_tables WHERE change_stream_name = $1 ORDER BY table_name",
+		columns: "SELECT table_name, column_name FROM information_schema.change_stream_columns WHERE change_stream_name = $1 ORDER BY table_name, column_name",
+		param:   "p1",
+	},
+}
+
+// DescribeStream returns the description of the change stream.
+// To count the current partitions, it reads the change stream for a moment.
+func DescribeStream(ctx context.Context, client *spanner.Client, streamID string) (*StreamDescription, error) {
+	d, err := DescribeStreamCoverage(ctx, client, streamID)
+	if err != nil {
+		return nil, err
+	}
+	partitions, err := countPartitions(ctx, client, streamID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count partitions: %w", err)
+	}
+	d.Partitions = partitions
+	return d, nil
+}
+
+// DescribeStreamCoverage returns the description of the change stream without the partitions, e.g. to see the tables
+// and the columns watched by the stream before reading it.
+func DescribeStreamCoverage(ctx context.Context, client *spanner.Client, streamID string) (*StreamDescription, error) {
+	dialect, err := detectDialect(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect dialect: %w", err)
+	}
+	queries, ok := describeQueriesByDialect[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unexpected dialect: %s", dialect)
+	}
+	query := func(sql string, f func(row *spanner.Row) error) error {
+		stmt := spanner.Statement{SQL: sql, Params: map[string]interface{}{queries.param: streamID}}
+		return client.Single().Query(ctx, stmt).Do(f)
+	}
+
+	d := &StreamDescription{
+		Name:             streamID,
+		ValueCaptureType: DefaultValueCaptureType,
+		RetentionPeriod:  DefaultRetentionPeriod,
+	}
+	found := false
+	if err := query(queries.stream, func(row *spanner.Row) error {
+		found = true
+		return columnBool(row, 0, dialect, &d.All)
+	}); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrStreamNotFound, streamID)
+	}
+
+	if err := query(queries.options, func(row *spanner.Row) error {
+		var name, value string
+		if err := row.Columns(&name, &value); err != nil {
+			return err
+		}
+		switch name {
+		case "value_capture_type":
+			d.ValueCaptureType = value
+		case "retention_period":
+			d.RetentionPeriod = value
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := query(queries.tables, func(row *spanner.Row) error {
+		var t WatchedTable
+		if err := row.Column(0, &t.Name); err != nil {
+			return err
+		}
+		if err := columnBool(row, 1, dialect, &t.AllColumns); err != nil {
+			return err
+		}
+		d.Tables = append(d.Tables, t)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	tables := make(map[string]*WatchedTable)
+	for i := range d.Tables {
+		tables[d.Tables[i].Name] = &d.Tables[i]
+	}
+	if err := query(queries.columns, func(row *spanner.Row) error {
+		var table, column string
+		if err := row.Columns(&table, &column); err != nil {
+			return err
+		}
+		if t, ok := tables[table]; ok && !t.AllColumns {
+			t.Columns = append(t.Columns, column)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// columnBool decodes the boolean column, which is 'YES' or 'NO' in PostgreSQL dialect.
+func columnBool(row *spanner.Row, i int, d dialect, b *bool) error {
+	if d == dialectPostgreSQL {
+		var s string
+		if err := row.Column(i, &s); err != nil {
+			return err
+		}
+		*b = s == "YES"
+		return nil
+	}
+	return row.Column(i, b)
+}
+
+// countPartitions counts the child partitions returned by the initial query at the current timestamp.
+func countPartitions(ctx context.Context, client *spanner.Client, streamID string) (int, error) {
+	now := time.Now()
+	r, err := NewReaderWithClient(ctx, client, streamID, Config{
+		StartTimestamp: now,
+		EndTimestamp:   now.Add(time.Second),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var count int
+	if err := r.Read(ctx, func(result *ReadResult) error {
+		if result.PartitionToken != "" {
+			// The initial query has finished.
+			return ErrStop
+		}
+		for _, changeRecord := range result.ChangeRecords {
+			for _, childPartitionsRecord := range changeRecord.ChildPartitionsRecords {
+				count += len(childPartitionsRecord.ChildPartitions)
+			}
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
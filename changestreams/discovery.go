@@ -0,0 +1,155 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+)
+
+// ErrStreamNotFound is returned when the change stream does not exist in the database.
+var ErrStreamNotFound = errors.New("change stream is not found")
+
+// StreamsForTable returns the names of the change streams watching the table, including the ones FOR ALL tables.
+func StreamsForTable(ctx context.Context, client *spanner.Client, tableName string) ([]string, error) {
+	dialect, err := detectDialect(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect dialect: %w", err)
+	}
+
+	var stmt spanner.Statement
+	switch dialect {
+	case dialectGoogleSQL:
+		stmt = spanner.Statement{
+			SQL: `SELECT cs.CHANGE_STREAM_NAME FROM INFORMATION_SCHEMA.CHANGE_STREAMS AS cs
+WHERE cs.ALL OR EXISTS (
+  SELECT 1 FROM INFORMATION_SCHEMA.CHANGE_STREAM_TABLES AS t
+  WHERE t.CHANGE_STREAM_NAME = cs.CHANGE_STREAM_NAME AND t.TABLE_NAME = @table_name
+)
+ORDER BY cs.CHANGE_STREAM_NAME`,
+			Params: map[string]interface{}{"table_name": tableName},
+		}
+	case dialectPostgreSQL:
+		stmt = spanner.Statement{
+			SQL: `SELECT cs.change_stream_name FROM information_schema.change_streams AS cs
+WHERE cs."all" = 'YES' OR EXISTS (
+  SELECT 1 FROM information_schema.change_stream_tables AS t
+  WHERE t.change_stream_name = cs.change_stream_name AND t.table_name = $1
+)
+ORDER BY cs.change_stream_name`,
+			Params: map[string]interface{}{"p1": tableName},
+		}
+	default:
+		return nil, fmt.Errorf("unexpected dialect: %s", dialect)
+	}
+
+	return queryNames(ctx, client, stmt)
+}
+
+// Streams returns the names of all the change streams in the database.
+func Streams(ctx context.Context, client *spanner.Client) ([]string, error) {
+	dialect, err := detectDialect(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect dialect: %w", err)
+	}
+	return streams(ctx, client, dialect)
+}
+
+func streams(ctx context.Context, client *spanner.Client, dialect dialect) ([]string, error) {
+	var stmt spanner.Statement
+	switch dialect {
+	case dialectGoogleSQL:
+		stmt = spanner.NewStatement("SELECT CHANGE_STREAM_NAME FROM INFORMATION_SCHEMA.CHANGE_STREAMS ORDER BY CHANGE_STREAM_NAME")
+	case dialectPostgreSQL:
+		stmt = spanner.NewStatement("SELECT change_stream_name FROM information_schema.change_streams ORDER BY change_stream_name")
+	default:
+		return nil, fmt.Errorf("unexpected dialect: %s", dialect)
+	}
+	return queryNames(ctx, client, stmt)
+}
+
+// Tables returns the names of the tables in the database, e.g. to find the tables created after reading a stream
+// FOR ALL tables.
+func Tables(ctx context.Context, client *spanner.Client) ([]string, error) {
+	dialect, err := detectDialect(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect dialect: %w", err)
+	}
+
+	var stmt spanner.Statement
+	switch dialect {
+	case dialectGoogleSQL:
+		stmt = spanner.NewStatement("SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = '' AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME")
+	case dialectPostgreSQL:
+		stmt = spanner.NewStatement("SELECT table_name FROM information_schema.tables WHERE table_schema = 'public' AND table_type = 'BASE TABLE' ORDER BY table_name")
+	default:
+		return nil, fmt.Errorf("unexpected dialect: %s", dialect)
+	}
+	return queryNames(ctx, client, stmt)
+}
+
+// streamExists reports whether the change stream exists. The names are compared case-insensitively.
+func streamExists(ctx context.Context, client *spanner.Client, streamID string) (bool, error) {
+	streams, err := Streams(ctx, client)
+	if err != nil {
+		return false, err
+	}
+	for _, stream := range streams {
+		if strings.EqualFold(stream, streamID) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// checkStreamExists returns ErrStreamNotFound with the names of the streams in the database if the change stream
+// doesn't exist, so that a typo of the name is found before reading.
+func checkStreamExists(ctx context.Context, client *spanner.Client, dialect dialect, streamID string) error {
+	names, err := streams(ctx, client, dialect)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if strings.EqualFold(name, streamID) {
+			return nil
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%w: %s (the database has no change streams)", ErrStreamNotFound, streamID)
+	}
+	return fmt.Errorf("%w: %s (the change streams of the database: %s)", ErrStreamNotFound, streamID, strings.Join(names, ", "))
+}
+
+// queryNames returns the values of the first column.
+func queryNames(ctx context.Context, client *spanner.Client, stmt spanner.Statement) ([]string, error) {
+	var names []string
+	if err := client.Single().Query(ctx, stmt).Do(func(row *spanner.Row) error {
+		var name string
+		if err := row.Column(0, &name); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
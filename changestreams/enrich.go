@@ -0,0 +1,133 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// enrichRecord sets the full rows of the mods of the record, read by their keys at the commit timestamp. The deleted
+// rows don't exist at the commit timestamp, so the mods of DELETE are left as they are.
+func (r *Reader) enrichRecord(ctx context.Context, record *DataChangeRecord) error {
+	if record.ModType == "DELETE" {
+		return nil
+	}
+	keyTypes, err := primaryKeyTypes(record.ColumnTypes)
+	if err != nil {
+		return fmt.Errorf("failed to read the full row of %s: %w", record.TableName, err)
+	}
+
+	tx := r.client.Single().WithTimestampBound(spanner.ReadTimestamp(record.CommitTimestamp))
+	defer tx.Close()
+	for _, mod := range record.Mods {
+		keys, ok := mod.Keys.Value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("failed to read the full row of %s: unexpected keys: %v", record.TableName, mod.Keys.Value)
+		}
+		stmt, err := fullRowStatement(r.dialect, record.TableName, keys, keyTypes)
+		if err != nil {
+			return fmt.Errorf("failed to read the full row of %s: %w", record.TableName, err)
+		}
+		if err := tx.Query(ctx, stmt).Do(func(row *spanner.Row) error {
+			mod.FullRow, err = rowValues(row)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to read the full row of %s: %w", record.TableName, err)
+		}
+	}
+	return nil
+}
+
+// primaryKeyTypes returns the types of the primary key columns by their names.
+func primaryKeyTypes(columnTypes []*ColumnType) (map[string]*sppb.Type, error) {
+	types := make(map[string]*sppb.Type)
+	for _, c := range columnTypes {
+		if !c.IsPrimaryKey {
+			continue
+		}
+		b, err := json.Marshal(c.Type.Value)
+		if err != nil {
+			return nil, err
+		}
+		var t sppb.Type
+		if err := protojson.Unmarshal(b, &t); err != nil {
+			return nil, fmt.Errorf("unexpected type of column %s: %s", c.Name, b)
+		}
+		types[c.Name] = &t
+	}
+	return types, nil
+}
+
+// fullRowStatement returns the query of the row by the keys, whose values are in JSON as the mods of the change
+// streams, e.g. the INT64 values in strings, so they are passed with the types of the columns as they are.
+func fullRowStatement(d dialect, tableName string, keys map[string]interface{}, keyTypes map[string]*sppb.Type) (spanner.Statement, error) {
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	quote := func(name string) string { return "`" + name + "`" }
+	param := func(i int) string { return fmt.Sprintf("@p%d", i) }
+	if d == dialectPostgreSQL {
+		quote = func(name string) string { return `"` + strings.ReplaceAll(name, `"`, `""`) + `"` }
+		param = func(i int) string { return fmt.Sprintf("$%d", i) }
+	}
+
+	params := make(map[string]interface{}, len(names))
+	conditions := make([]string, 0, len(names))
+	for i, name := range names {
+		t, ok := keyTypes[name]
+		if !ok {
+			return spanner.Statement{}, fmt.Errorf("unknown type of key column %s", name)
+		}
+		v, err := structpb.NewValue(keys[name])
+		if err != nil {
+			return spanner.Statement{}, fmt.Errorf("unexpected value of key column %s: %w", name, err)
+		}
+		params[fmt.Sprintf("p%d", i+1)] = spanner.GenericColumnValue{Type: t, Value: v}
+		conditions = append(conditions, fmt.Sprintf("%s = %s", quote(name), param(i+1)))
+	}
+	sql := fmt.Sprintf("SELECT * FROM %s", quote(tableName))
+	if len(conditions) > 0 {
+		sql += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	return spanner.Statement{SQL: sql, Params: params}, nil
+}
+
+// rowValues returns the values of the columns of the row in a JSON object, in the same representation as the mods,
+// e.g. the INT64 values in strings.
+func rowValues(row *spanner.Row) (json.RawMessage, error) {
+	values := make(map[string]interface{}, row.Size())
+	for i, name := range row.ColumnNames() {
+		var col spanner.GenericColumnValue
+		if err := row.Column(i, &col); err != nil {
+			return nil, err
+		}
+		values[name] = col.Value.AsInterface()
+	}
+	return json.Marshal(values)
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestFullRowStatement(t *testing.T) {
+	keyTypes, err := primaryKeyTypes([]*ColumnType{
+		{Name: "TeamId", Type: spanner.NullJSON{Value: map[string]interface{}{"code": "STRING"}, Valid: true}, IsPrimaryKey: true, OrdinalPosition: 1},
+		{Name: "PlayerId", Type: spanner.NullJSON{Value: map[string]interface{}{"code": "INT64"}, Valid: true}, IsPrimaryKey: true, OrdinalPosition: 2},
+		{Name: "Name", Type: spanner.NullJSON{Value: map[string]interface{}{"code": "STRING"}, Valid: true}, OrdinalPosition: 3},
+	})
+	if err != nil {
+		t.Fatalf("primaryKeyTypes error: %v", err)
+	}
+	keys := map[string]interface{}{"TeamId": "a", "PlayerId": "1"}
+	wantParams := map[string]interface{}{
+		"p1": spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_INT64}, Value: structpb.NewStringValue("1")},
+		"p2": spanner.GenericColumnValue{Type: &sppb.Type{Code: sppb.TypeCode_STRING}, Value: structpb.NewStringValue("a")},
+	}
+
+	for _, test := range []struct {
+		desc    string
+		dialect dialect
+		want    string
+	}{
+		{
+			desc:    "GoogleSQL",
+			dialect: dialectGoogleSQL,
+			want:    "SELECT * FROM `Players` WHERE `PlayerId` = @p1 AND `TeamId` = @p2",
+		},
+		{
+			desc:    "PostgreSQL",
+			dialect: dialectPostgreSQL,
+			want:    `SELECT * FROM "Players" WHERE "PlayerId" = $1 AND "TeamId" = $2`,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			stmt, err := fullRowStatement(test.dialect, "Players", keys, keyTypes)
+			if err != nil {
+				t.Fatalf("fullRowStatement error: %v", err)
+			}
+			if stmt.SQL != test.want {
+				t.Errorf("SQL = %q, want %q", stmt.SQL, test.want)
+			}
+			if diff := cmp.Diff(stmt.Params, wantParams, protocmp.Transform()); diff != "" {
+				t.Errorf("params have diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestFullRowStatementUnknownKey(t *testing.T) {
+	if _, err := fullRowStatement(dialectGoogleSQL, "Players", map[string]interface{}{"PlayerId": "1"}, nil); err == nil {
+		t.Error("fullRowStatement succeeded with the key of unknown type")
+	}
+}
+
+func TestRowValues(t *testing.T) {
+	row, err := spanner.NewRow([]string{"PlayerId", "Name", "Score", "Active"}, []interface{}{int64(1), "foo", spanner.NullFloat64{}, true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rowValues(row)
+	if err != nil {
+		t.Fatalf("rowValues error: %v", err)
+	}
+	want := `{"Active":true,"Name":"foo","PlayerId":"1","Score":null}`
+	if string(got) != want {
+		t.Errorf("rowValues = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,58 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PartitionError is the error of reading a partition, returned by Read with the partition failed to be read.
+// The error of the query can be unwrapped, e.g. by errors.As to *spanner.Error.
+type PartitionError struct {
+	StreamID string
+	// PartitionToken is empty for the initial query, which is not a partition.
+	PartitionToken string
+	StartTimestamp time.Time
+	Err            error
+}
+
+func (e *PartitionError) Error() string {
+	start := e.StartTimestamp.Format(time.RFC3339Nano)
+	if e.PartitionToken == "" {
+		return fmt.Sprintf("stream %s, initial query from %s: %v", e.StreamID, start, e.Err)
+	}
+	return fmt.Sprintf("stream %s, partition %s from %s: %v", e.StreamID, e.PartitionToken, start, e.Err)
+}
+
+func (e *PartitionError) Unwrap() error {
+	return e.Err
+}
+
+// GRPCStatus returns the gRPC status of the wrapped error, as status.FromError and spanner.ErrCode don't unwrap
+// the errors.
+func (e *PartitionError) GRPCStatus() *status.Status {
+	var se interface{ GRPCStatus() *status.Status }
+	if errors.As(e.Err, &se) {
+		return se.GRPCStatus()
+	}
+	return status.New(codes.Unknown, e.Error())
+}
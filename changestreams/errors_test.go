@@ -0,0 +1,53 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"errors"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestPartitionError(t *testing.T) {
+	start := mustParseTime("2022-12-04T18:00:00Z")
+	queryErr := status.Error(codes.Unavailable, "unavailable")
+
+	err := error(&PartitionError{StreamID: "s", PartitionToken: "a", StartTimestamp: start, Err: queryErr})
+	if expected := "stream s, partition a from 2022-12-04T18:00:00Z: rpc error: code = Unavailable desc = unavailable"; err.Error() != expected {
+		t.Errorf("Error() = %q, but want %q", err.Error(), expected)
+	}
+	if !errors.Is(err, queryErr) {
+		t.Errorf("PartitionError must unwrap the error of the query")
+	}
+	if code := spanner.ErrCode(err); code != codes.Unavailable {
+		t.Errorf("spanner.ErrCode = %v, but want Unavailable", code)
+	}
+
+	err = &PartitionError{StreamID: "s", StartTimestamp: start, Err: ErrStreamNotFound}
+	if expected := "stream s, initial query from 2022-12-04T18:00:00Z: change stream is not found"; err.Error() != expected {
+		t.Errorf("Error() = %q, but want %q", err.Error(), expected)
+	}
+	if !errors.Is(err, ErrStreamNotFound) {
+		t.Errorf("PartitionError must unwrap ErrStreamNotFound")
+	}
+	if code := spanner.ErrCode(err); code != codes.Unknown {
+		t.Errorf("spanner.ErrCode of a non-gRPC error = %v, but want Unknown", code)
+	}
+}
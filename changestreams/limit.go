@@ -0,0 +1,64 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// startLimiter spaces the starts of the partition queries evenly by the interval, so that a stream with many partitions
+// doesn't start all of them at once.
+type startLimiter struct {
+	interval time.Duration
+	next     time.Time
+	mu       sync.Mutex
+}
+
+// newStartLimiter creates a new startLimiter starting the number of partitions per second, or nil for no limit.
+func newStartLimiter(perSecond float64) *startLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &startLimiter{interval: time.Duration(float64(time.Second) / perSecond)}
+}
+
+// wait waits for the turn to start a partition.
+func (l *startLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
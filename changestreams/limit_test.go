@@ -0,0 +1,51 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStartLimiter(t *testing.T) {
+	if l := newStartLimiter(0); l != nil {
+		t.Errorf("newStartLimiter(0) must be nil for no limit")
+	}
+
+	l := newStartLimiter(100)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := l.wait(context.Background()); err != nil {
+			t.Fatalf("wait error: %v", err)
+		}
+	}
+	// The first start doesn't wait, and the others are spaced by 10ms.
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("5 starts at 100 per second must take at least 40ms, but took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l = newStartLimiter(0.001)
+	if err := l.wait(ctx); err != nil {
+		t.Fatalf("first wait must not wait, but got %v", err)
+	}
+	if err := l.wait(ctx); err != context.Canceled {
+		t.Errorf("wait must fail with the canceled context, but got %v", err)
+	}
+}
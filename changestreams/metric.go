@@ -0,0 +1,96 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// readerMetrics are the OpenTelemetry instruments of the reader.
+type readerMetrics struct {
+	records          metric.Int64Counter
+	activePartitions metric.Int64UpDownCounter
+	queuedPartitions metric.Int64UpDownCounter
+	consumerLatency  metric.Float64Histogram
+}
+
+// newReaderMetrics creates the instruments of the provider, or of the global provider if it's nil.
+func newReaderMetrics(provider metric.MeterProvider) (*readerMetrics, error) {
+	if provider == nil {
+		provider = otel.GetMeterProvider()
+	}
+	meter := provider.Meter(instrumentationName)
+
+	records, err := meter.Int64Counter("changestreams.records",
+		metric.WithDescription("Number of the data change records read."),
+		metric.WithUnit("1"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the records counter: %w", err)
+	}
+	activePartitions, err := meter.Int64UpDownCounter("changestreams.partitions.active",
+		metric.WithDescription("Number of the partitions being read."),
+		metric.WithUnit("1"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the partitions counter: %w", err)
+	}
+	queuedPartitions, err := meter.Int64UpDownCounter("changestreams.partitions.queued",
+		metric.WithDescription("Number of the partitions ready to be read, waiting for a worker."),
+		metric.WithUnit("1"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the queued partitions counter: %w", err)
+	}
+	consumerLatency, err := meter.Float64Histogram("changestreams.consumer.latency",
+		metric.WithDescription("Time taken by the consumer, e.g. the sink, to process a result."),
+		metric.WithUnit("ms"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the consumer latency histogram: %w", err)
+	}
+	return &readerMetrics{
+		records:          records,
+		activePartitions: activePartitions,
+		queuedPartitions: queuedPartitions,
+		consumerLatency:  consumerLatency,
+	}, nil
+}
+
+// recordResult records the data change records of the result, and the latency of the consumer.
+func (m *readerMetrics) recordResult(ctx context.Context, streamID string, result *ReadResult, latency time.Duration) {
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			m.records.Add(ctx, 1, metric.WithAttributes(
+				attributeStreamID.String(streamID),
+				attributeTableName.String(r.TableName),
+				attributeModType.String(r.ModType)))
+		}
+	}
+	m.consumerLatency.Record(ctx, float64(latency)/float64(time.Millisecond), metric.WithAttributes(attributeStreamID.String(streamID)))
+}
+
+// addActivePartitions adds the delta to the active partitions of the stream.
+func (m *readerMetrics) addActivePartitions(ctx context.Context, streamID string, delta int64) {
+	m.activePartitions.Add(ctx, delta, metric.WithAttributes(attributeStreamID.String(streamID)))
+}
+
+// addQueuedPartitions adds the delta to the queued partitions of the stream.
+func (m *readerMetrics) addQueuedPartitions(ctx context.Context, streamID string, delta int64) {
+	m.queuedPartitions.Add(ctx, delta, metric.WithAttributes(attributeStreamID.String(streamID)))
+}
@@ -0,0 +1,116 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// fakeMeterProvider records the values of the synchronous instruments by the name and the attributes.
+type fakeMeterProvider struct {
+	noop.MeterProvider
+	values map[string]float64
+}
+
+func newFakeMeterProvider() *fakeMeterProvider {
+	return &fakeMeterProvider{values: make(map[string]float64)}
+}
+
+func (p *fakeMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return &fakeMeter{provider: p}
+}
+
+type fakeMeter struct {
+	noop.Meter
+	provider *fakeMeterProvider
+}
+
+func (m *fakeMeter) Int64Counter(name string, opts ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return &fakeInstrument{provider: m.provider, name: name}, nil
+}
+
+func (m *fakeMeter) Int64UpDownCounter(name string, opts ...metric.Int64UpDownCounterOption) (metric.Int64UpDownCounter, error) {
+	return &fakeInstrument{provider: m.provider, name: name}, nil
+}
+
+func (m *fakeMeter) Float64Histogram(name string, opts ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return &fakeInstrument{provider: m.provider, name: name}, nil
+}
+
+func (p *fakeMeterProvider) add(name string, v float64, attrs attribute.Set) {
+	key := name
+	for _, attr := range attrs.ToSlice() {
+		key += " " + string(attr.Key) + "=" + attr.Value.Emit()
+	}
+	p.values[key] += v
+}
+
+type fakeInstrument struct {
+	noop.Int64Counter
+	noop.Int64UpDownCounter
+	noop.Float64Histogram
+	provider *fakeMeterProvider
+	name     string
+}
+
+func (i *fakeInstrument) Add(ctx context.Context, incr int64, opts ...metric.AddOption) {
+	i.provider.add(i.name, float64(incr), metric.NewAddConfig(opts).Attributes())
+}
+
+// Record counts the records of the histogram.
+func (i *fakeInstrument) Record(ctx context.Context, v float64, opts ...metric.RecordOption) {
+	i.provider.add(i.name, 1, metric.NewRecordConfig(opts).Attributes())
+}
+
+func TestReaderMetrics(t *testing.T) {
+	provider := newFakeMeterProvider()
+	metrics, err := newReaderMetrics(provider)
+	if err != nil {
+		t.Fatalf("newReaderMetrics error: %v", err)
+	}
+
+	ctx := context.Background()
+	metrics.addActivePartitions(ctx, "s", 1)
+	metrics.addActivePartitions(ctx, "s", 1)
+	metrics.addActivePartitions(ctx, "s", -1)
+	metrics.recordResult(ctx, "s", &ReadResult{
+		ChangeRecords: []*ChangeRecord{
+			{DataChangeRecords: []*DataChangeRecord{
+				{TableName: "Players", ModType: "INSERT"},
+				{TableName: "Players", ModType: "INSERT"},
+				{TableName: "Players", ModType: "DELETE"},
+			}},
+		},
+	}, time.Millisecond)
+
+	expected := map[string]float64{
+		"changestreams.records changestreams.mod_type=INSERT changestreams.stream_id=s changestreams.table_name=Players": 2,
+		"changestreams.records changestreams.mod_type=DELETE changestreams.stream_id=s changestreams.table_name=Players": 1,
+		"changestreams.partitions.active changestreams.stream_id=s":                                                      1,
+		"changestreams.consumer.latency changestreams.stream_id=s":                                                       1,
+	}
+	if diff := cmp.Diff(provider.values, expected); diff != "" {
+		t.Errorf("metrics have diff = %v", diff)
+	}
+}
@@ -0,0 +1,95 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+
+	"cloud.google.com/go/spanner"
+)
+
+// rowIterator is the iterator of the rows of a query, i.e. *spanner.RowIterator.
+type rowIterator interface {
+	Do(f func(row *spanner.Row) error) error
+}
+
+// decodeJob is a row decoded by the decode workers.
+type decodeJob struct {
+	ctx            context.Context
+	row            *spanner.Row
+	partitionToken string
+	result         *ReadResult
+	err            error
+	// done is closed when the row is decoded.
+	done chan struct{}
+}
+
+func (r *Reader) decodeWorker() {
+	for job := range r.decodeJobs {
+		job.result, job.err = r.decode(job.ctx, job.row, job.partitionToken)
+		close(job.done)
+	}
+}
+
+// doPipelined fetches the rows of the iterator, and passes them to the decode workers. The decoded results are passed
+// to f in the order of the rows by another goroutine. If decoding or f fails, the query is canceled by cancel.
+func (r *Reader) doPipelined(ctx context.Context, cancel context.CancelFunc, iter rowIterator, partitionToken string, f func(result *ReadResult) error) error {
+	// The rows being decoded, in order.
+	pending := make(chan *decodeJob, r.decodeWorkers)
+	var consumeErr error
+	consumed := make(chan struct{})
+	go func() {
+		defer close(consumed)
+		for job := range pending {
+			<-job.done
+			if consumeErr != nil {
+				// Drains the rest.
+				continue
+			}
+			if job.err != nil {
+				consumeErr = job.err
+			} else {
+				consumeErr = r.consume(ctx, job.result, f)
+			}
+			if consumeErr != nil {
+				cancel()
+			}
+		}
+	}()
+
+	err := iter.Do(func(row *spanner.Row) error {
+		job := &decodeJob{ctx: ctx, row: row, partitionToken: partitionToken, done: make(chan struct{})}
+		// Once submitted, the job is always decoded, so the consumer never waits for it forever.
+		select {
+		case r.decodeJobs <- job:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		select {
+		case pending <- job:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		return nil
+	})
+	close(pending)
+	<-consumed
+	if consumeErr != nil {
+		return consumeErr
+	}
+	return err
+}
@@ -0,0 +1,132 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// fakeRowIterator iterates the rows until f fails or the context is canceled.
+type fakeRowIterator struct {
+	ctx  context.Context
+	rows []*spanner.Row
+}
+
+func (it *fakeRowIterator) Do(f func(row *spanner.Row) error) error {
+	for _, row := range it.rows {
+		if err := it.ctx.Err(); err != nil {
+			return err
+		}
+		if err := f(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newTestHeartbeatRows returns the rows of the heartbeat records at the seconds from 0 to n-1.
+func newTestHeartbeatRows(t *testing.T, n int) []*spanner.Row {
+	var rows []*spanner.Row
+	for i := 0; i < n; i++ {
+		row, err := spanner.NewRow([]string{"ChangeRecord"}, []interface{}{[]*ChangeRecord{
+			{HeartbeatRecords: []*HeartbeatRecord{{Timestamp: time.Unix(int64(i), 0).UTC()}}},
+		}})
+		if err != nil {
+			t.Fatalf("NewRow error: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func newTestPipelineReader(t *testing.T, workers int) *Reader {
+	metrics, err := newReaderMetrics(noop.NewMeterProvider())
+	if err != nil {
+		t.Fatalf("newReaderMetrics error: %v", err)
+	}
+	r := &Reader{
+		streamID:      "s",
+		dialect:       dialectGoogleSQL,
+		decodeWorkers: workers,
+		decodeJobs:    make(chan *decodeJob),
+		tracer:        newTracer(nil),
+		metrics:       metrics,
+	}
+	for i := 0; i < workers; i++ {
+		go r.decodeWorker()
+	}
+	t.Cleanup(func() { close(r.decodeJobs) })
+	return r
+}
+
+func TestDoPipelined(t *testing.T) {
+	r := newTestPipelineReader(t, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var got []int64
+	err := r.doPipelined(ctx, cancel, &fakeRowIterator{ctx: ctx, rows: newTestHeartbeatRows(t, 100)}, "a", func(result *ReadResult) error {
+		if result.PartitionToken != "a" {
+			t.Errorf("partition token = %q, want a", result.PartitionToken)
+		}
+		got = append(got, result.ChangeRecords[0].HeartbeatRecords[0].Timestamp.Unix())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("doPipelined error: %v", err)
+	}
+
+	var expected []int64
+	for i := 0; i < 100; i++ {
+		expected = append(expected, int64(i))
+	}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("results have diff = %v", diff)
+	}
+}
+
+func TestDoPipelinedError(t *testing.T) {
+	r := newTestPipelineReader(t, 4)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errConsume := errors.New("consume error")
+	var consumed int
+	err := r.doPipelined(ctx, cancel, &fakeRowIterator{ctx: ctx, rows: newTestHeartbeatRows(t, 100)}, "a", func(result *ReadResult) error {
+		consumed++
+		if consumed == 10 {
+			return errConsume
+		}
+		return nil
+	})
+	if !errors.Is(err, errConsume) {
+		t.Errorf("doPipelined must return the error of f, but got %v", err)
+	}
+	if consumed != 10 {
+		t.Errorf("%d results are consumed, want 10", consumed)
+	}
+	if ctx.Err() == nil {
+		t.Errorf("the query must be canceled")
+	}
+}
@@ -0,0 +1,78 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryStats is the execution statistics of a partition query, e.g. to understand the cost of tailing the stream.
+// Spanner returns them when the query finishes, so they are not available while the partition is being read.
+type QueryStats struct {
+	StreamID       string
+	PartitionToken string
+	RowsReturned   int64
+	RowsScanned    int64
+	CPUTime        time.Duration
+	ElapsedTime    time.Duration
+}
+
+// newQueryStats converts the query stats returned by Spanner, e.g. {"cpu_time": "1.23 msecs", "rows_scanned": "10"}.
+// Missing or malformed values are left zero.
+func newQueryStats(streamID, partitionToken string, stats map[string]interface{}) *QueryStats {
+	return &QueryStats{
+		StreamID:       streamID,
+		PartitionToken: partitionToken,
+		RowsReturned:   queryStatsInt(stats["rows_returned"]),
+		RowsScanned:    queryStatsInt(stats["rows_scanned"]),
+		CPUTime:        queryStatsDuration(stats["cpu_time"]),
+		ElapsedTime:    queryStatsDuration(stats["elapsed_time"]),
+	}
+}
+
+func queryStatsInt(v interface{}) int64 {
+	s, _ := v.(string)
+	n, _ := strconv.ParseInt(s, 10, 64)
+	return n
+}
+
+// queryStatsDuration parses the durations in the form of "1.23 msecs".
+func queryStatsDuration(v interface{}) time.Duration {
+	s, _ := v.(string)
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return 0
+	}
+	n, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	var unit time.Duration
+	switch fields[1] {
+	case "secs":
+		unit = time.Second
+	case "msecs":
+		unit = time.Millisecond
+	case "usecs":
+		unit = time.Microsecond
+	default:
+		return 0
+	}
+	return time.Duration(n * float64(unit))
+}
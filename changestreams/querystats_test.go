@@ -0,0 +1,67 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewQueryStats(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		stats    map[string]interface{}
+		expected *QueryStats
+	}{
+		{
+			desc: "all",
+			stats: map[string]interface{}{
+				"rows_returned": "12",
+				"rows_scanned":  "340",
+				"cpu_time":      "1.5 msecs",
+				"elapsed_time":  "2.25 secs",
+				"query_text":    "SELECT ChangeRecord FROM READ_s(...)",
+			},
+			expected: &QueryStats{
+				StreamID:       "s",
+				PartitionToken: "a",
+				RowsReturned:   12,
+				RowsScanned:    340,
+				CPUTime:        1500 * time.Microsecond,
+				ElapsedTime:    2250 * time.Millisecond,
+			},
+		},
+		{
+			desc: "malformed",
+			stats: map[string]interface{}{
+				"rows_returned": 12.0,
+				"cpu_time":      "1.5 minutes",
+				"elapsed_time":  "2.25",
+			},
+			expected: &QueryStats{StreamID: "s", PartitionToken: "a"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got := newQueryStats("s", "a", test.stats)
+			if diff := cmp.Diff(got, test.expected); diff != "" {
+				t.Errorf("newQueryStats has diff = %v", diff)
+			}
+		})
+	}
+}
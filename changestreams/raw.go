@@ -0,0 +1,184 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// decodeRawRow decodes the row without the mods and the column types of the data change records, and sets the change
+// record column in JSON to the result as is.
+func (r *Reader) decodeRawRow(row *spanner.Row, result *ReadResult) error {
+	switch r.dialect {
+	case dialectGoogleSQL:
+		col, err := changeRecordColumn(row)
+		if errors.Is(err, errDecodeFallback) {
+			return fmt.Errorf("unexpected columns of the change stream: %v", row.ColumnNames())
+		}
+		if err != nil {
+			return err
+		}
+		raw, err := appendValueJSON(nil, col.Type, col.Value)
+		if err != nil {
+			return err
+		}
+		changeRecords, err := decodeStructArray(col.Type, col.Value, changeRecordDecoder(modsSkipped))
+		if errors.Is(err, errDecodeFallback) {
+			err = row.ToStructLenient(result)
+			changeRecords = result.ChangeRecords
+		}
+		if err != nil {
+			return err
+		}
+		result.ChangeRecords = changeRecords
+		result.RawChangeRecord = raw
+		return nil
+	case dialectPostgreSQL:
+		var col spanner.GenericColumnValue
+		if err := row.Column(0, &col); err != nil {
+			return err
+		}
+		s, ok := col.Value.GetKind().(*structpb.Value_StringValue)
+		if !ok {
+			return fmt.Errorf("unexpected value of the change record: %v", col.Value)
+		}
+		raw := []byte(s.StringValue)
+		changeRecord, err := decodePostgresJSON(raw)
+		if err != nil {
+			return err
+		}
+		result.ChangeRecords = []*ChangeRecord{changeRecord}
+		result.RawChangeRecord = raw
+		return nil
+	default:
+		return fmt.Errorf("unexpected dialect: %s", r.dialect)
+	}
+}
+
+// appendValueJSON appends the value of the type in JSON to b. The structs are encoded into the objects of their
+// fields, the INT64 values into the numbers, and the JSON values as they are.
+func appendValueJSON(b []byte, t *sppb.Type, v *structpb.Value) ([]byte, error) {
+	if _, ok := v.GetKind().(*structpb.Value_NullValue); ok {
+		return append(b, "null"...), nil
+	}
+	switch t.GetCode() {
+	case sppb.TypeCode_STRUCT:
+		fields := t.GetStructType().GetFields()
+		values := v.GetListValue().GetValues()
+		if len(values) != len(fields) {
+			return nil, fmt.Errorf("unexpected number of the struct fields: %d, expected %d", len(values), len(fields))
+		}
+		b = append(b, '{')
+		for i, f := range fields {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendStringJSON(b, f.Name)
+			b = append(b, ':')
+			var err error
+			if b, err = appendValueJSON(b, f.Type, values[i]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+	case sppb.TypeCode_ARRAY:
+		list := v.GetListValue()
+		if list == nil {
+			return nil, fmt.Errorf("unexpected value of the array: %v", v)
+		}
+		b = append(b, '[')
+		for i, element := range list.Values {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendValueJSON(b, t.GetArrayElementType(), element); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+	case sppb.TypeCode_BOOL:
+		return strconv.AppendBool(b, v.GetBoolValue()), nil
+	case sppb.TypeCode_INT64:
+		n, err := strconv.ParseInt(v.GetStringValue(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected value of INT64: %v", v)
+		}
+		return strconv.AppendInt(b, n, 10), nil
+	case sppb.TypeCode_FLOAT64:
+		// NaN and Infinity are encoded into the strings, as JSON has no numbers for them.
+		if f, ok := v.GetKind().(*structpb.Value_NumberValue); ok && !math.IsInf(f.NumberValue, 0) && !math.IsNaN(f.NumberValue) {
+			return strconv.AppendFloat(b, f.NumberValue, 'g', -1, 64), nil
+		}
+		return appendStringJSON(b, v.GetStringValue()), nil
+	case sppb.TypeCode_JSON:
+		s := v.GetStringValue()
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("invalid JSON value: %s", s)
+		}
+		return append(b, s...), nil
+	default:
+		return appendStringJSON(b, v.GetStringValue()), nil
+	}
+}
+
+// appendStringJSON appends s as a JSON string to b, escaping the same characters as encoding/json except for HTML.
+func appendStringJSON(b []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	b = append(b, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				b = append(b, '\\', c)
+			case c == '\n':
+				b = append(b, '\\', 'n')
+			case c == '\r':
+				b = append(b, '\\', 'r')
+			case c == '\t':
+				b = append(b, '\\', 't')
+			case c < 0x20:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xf])
+			default:
+				b = append(b, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			b = append(b, `\ufffd`...)
+		case r == '\u2028' || r == '\u2029':
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xf])
+		default:
+			b = append(b, s[i:i+size]...)
+		}
+		i += size
+	}
+	return append(b, '"')
+}
@@ -0,0 +1,139 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDecodeRawRow(t *testing.T) {
+	row := newTestChangeRecordRow(t)
+	r := &Reader{dialect: dialectGoogleSQL, rawJSON: true}
+
+	var result ReadResult
+	if err := r.decodeRow(row, &result); err != nil {
+		t.Fatalf("decodeRow error: %v", err)
+	}
+
+	var expected ReadResult
+	if err := row.ToStructLenient(&expected); err != nil {
+		t.Fatalf("ToStructLenient error: %v", err)
+	}
+	var got []*ChangeRecord
+	if err := json.Unmarshal(result.RawChangeRecord, &got); err != nil {
+		t.Fatalf("invalid raw change record %s: %v", result.RawChangeRecord, err)
+	}
+	if diff := cmp.Diff(got, expected.ChangeRecords); diff != "" {
+		t.Errorf("raw change record has diff = %v", diff)
+	}
+
+	// The mods and the column types are skipped.
+	for _, changeRecord := range expected.ChangeRecords {
+		for _, dataChangeRecord := range changeRecord.DataChangeRecords {
+			dataChangeRecord.Mods = nil
+			dataChangeRecord.ColumnTypes = nil
+		}
+	}
+	if diff := cmp.Diff(result.ChangeRecords, expected.ChangeRecords); diff != "" {
+		t.Errorf("change records have diff = %v", diff)
+	}
+}
+
+func TestDecodeRawRowPostgreSQL(t *testing.T) {
+	raw := `{"heartbeat_record":{"timestamp":"2023-02-24T17:16:43.811345-08:00"}}`
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	row, err := spanner.NewRow([]string{"read_json_playersstream"}, []interface{}{spanner.NullJSON{Value: value, Valid: true}})
+	if err != nil {
+		t.Fatalf("NewRow error: %v", err)
+	}
+	r := &Reader{dialect: dialectPostgreSQL, rawJSON: true}
+
+	var result ReadResult
+	if err := r.decodeRow(row, &result); err != nil {
+		t.Fatalf("decodeRow error: %v", err)
+	}
+	if diff := cmp.Diff(string(result.RawChangeRecord), raw); diff != "" {
+		t.Errorf("raw change record has diff = %v", diff)
+	}
+	expected := []*ChangeRecord{{
+		DataChangeRecords:      []*DataChangeRecord{},
+		HeartbeatRecords:       []*HeartbeatRecord{{Timestamp: mustParseTime("2023-02-24T17:16:43.811345-08:00")}},
+		ChildPartitionsRecords: []*ChildPartitionsRecord{},
+	}}
+	if diff := cmp.Diff(result.ChangeRecords, expected); diff != "" {
+		t.Errorf("change records have diff = %v", diff)
+	}
+}
+
+func TestAppendStringJSON(t *testing.T) {
+	for _, s := range []string{"", "foo", `"quoted" \ back`, "new\nline\ttab\x00\x1f", "<html>&", "日本語", "\u2028\u2029", "invalid\xff"} {
+		b := appendStringJSON(nil, s)
+		var got string
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("invalid JSON string %s: %v", b, err)
+		}
+		if expected := strings.ToValidUTF8(s, "\ufffd"); got != expected {
+			t.Errorf("appendStringJSON(%q) = %s, decoded into %q", s, b, got)
+		}
+	}
+}
+
+func BenchmarkDecodeRawRow(b *testing.B) {
+	row := newTestChangeRecordRow(b)
+	r := &Reader{dialect: dialectGoogleSQL, rawJSON: true}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var result ReadResult
+		if err := r.decodeRow(row, &result); err != nil {
+			b.Fatalf("decodeRow error: %v", err)
+		}
+	}
+}
+
+func TestDecodeRawRows(t *testing.T) {
+	row := newTestChangeRecordRow(t)
+	r := &Reader{streamID: "s", dialect: dialectGoogleSQL, rawRows: true, tracer: newTracer(nil)}
+
+	result, err := r.decode(context.Background(), row, "a")
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if result.Row != row {
+		t.Errorf("Row must be the row as is")
+	}
+	if len(result.ChangeRecords) == 0 || len(result.ChangeRecords[0].DataChangeRecords) == 0 {
+		t.Errorf("the records must be decoded with the row, but got %v", result.ChangeRecords)
+	}
+
+	r.rawRows = false
+	result, err = r.decode(context.Background(), row, "a")
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if result.Row != nil {
+		t.Errorf("Row must not be set without RawRows")
+	}
+}
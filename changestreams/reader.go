@@ -21,18 +21,28 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
 	"cloud.google.com/go/spanner"
-	"golang.org/x/sync/errgroup"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
 )
 
 // ReadResult is the result of the read change records from the partition.
 type ReadResult struct {
+	StreamID       string          `json:"stream_id"`
 	PartitionToken string          `json:"partition_token"`
 	ChangeRecords  []*ChangeRecord `spanner:"ChangeRecord" json:"change_record"`
+	// RawChangeRecord is the change record column of the row in JSON, set only if Config.RawJSON is set.
+	RawChangeRecord json.RawMessage `spanner:"-" json:"-"`
+	// Row is the row of the result as is, set only if Config.RawRows is set, e.g. to read the fields of the records
+	// added to the change streams after this package.
+	Row *spanner.Row `spanner:"-" json:"-"`
 }
 
 // ChangeRecord is the single unit of the records from the change stream.
@@ -57,6 +67,8 @@ type DataChangeRecord struct {
 	NumberOfPartitionsInTransaction      int64         `spanner:"number_of_partitions_in_transaction" json:"number_of_partitions_in_transaction"`
 	TransactionTag                       string        `spanner:"transaction_tag" json:"transaction_tag"`
 	IsSystemTransaction                  bool          `spanner:"is_system_transaction" json:"is_system_transaction"`
+	// LazyMods is the mods left undecoded if Config.LazyMods is set, which DecodeMods decodes into Mods.
+	LazyMods *LazyMods `spanner:"-" json:"-"`
 }
 
 // ColumnType is the metadata of the column.
@@ -72,6 +84,9 @@ type Mod struct {
 	Keys      spanner.NullJSON `spanner:"keys" json:"keys"`
 	NewValues spanner.NullJSON `spanner:"new_values" json:"new_values"`
 	OldValues spanner.NullJSON `spanner:"old_values" json:"old_values"`
+	// FullRow is the values of all the columns of the row at the commit timestamp in a JSON object, set only if
+	// Config.EnrichRows is set.
+	FullRow json.RawMessage `spanner:"-" json:"full_row,omitempty"`
 }
 
 // HeartbeatRecord is the heartbeat record returned from Cloud Spanner.
@@ -110,25 +125,91 @@ const (
 // Reader is the change stream reader.
 type Reader struct {
 	client            *spanner.Client
+	ownsClient        bool
 	streamID          string
 	startTimestamp    time.Time
 	endTimestamp      time.Time
 	heartbeatInterval time.Duration
+	redactor          Redactor
+	priority          sppb.RequestOptions_Priority
+	maxPartitions     int
+	startLimiter      *startLimiter
+	retry             RetryPolicy
+	retryBudget       *retryBudget
+	onQueryStats      func(stats *QueryStats)
+	decodeWorkers     int
+	decodeJobs        chan *decodeJob
+	rawJSON           bool
+	rawRows           bool
+	lazyMods          bool
+	enrichRows        bool
+	tracer            trace.Tracer
+	metrics           *readerMetrics
+	logger            *slog.Logger
 	dialect           dialect
 	states            map[string]partitionState
-	group             *errgroup.Group
+	announced         map[string]*UnreadPartition
+	scheduler         *scheduler
 	mu                sync.Mutex
 }
 
 // Config is the configuration for the reader.
 type Config struct {
-	// If StartTimestamp is a zero value of time.Time, reader reads from the current timestamp.
+	// If StartTimestamp is a zero value of time.Time, reader reads from the current timestamp of the database.
 	StartTimestamp time.Time
 	// If EndTimestamp is a zero value of time.Time, reader reads until it is cancelled.
 	EndTimestamp         time.Time
 	HeartbeatInterval    time.Duration
 	SpannerClientConfig  spanner.ClientConfig
 	SpannerClientOptions []option.ClientOption
+	// If Redactor is set, every data change record is passed to it before being passed to the consumer.
+	Redactor Redactor
+	// Priority is the request priority of the change stream queries, e.g. PRIORITY_LOW not to compete with other traffic.
+	Priority sppb.RequestOptions_Priority
+	// MaxConcurrentPartitions is the number of the workers reading the partitions, or zero to start a worker whenever
+	// a partition is ready and no worker is idle. The other partitions wait in the queue for the workers to finish their
+	// partitions, so without EndTimestamp, it must not be less than the number of partitions of the stream, otherwise
+	// some partitions are never read.
+	MaxConcurrentPartitions int
+	// PartitionStartRate limits the number of partitions started per second, or zero for no limit, not to query
+	// Cloud Spanner with all partitions at once, e.g. when reading from an old StartTimestamp with many partitions.
+	PartitionStartRate float64
+	// Retry is the retry policy of the partition queries. By default, the failed queries are not retried.
+	Retry RetryPolicy
+	// If OnQueryStats is set, the partition queries are executed in the PROFILE mode, and it's called with the
+	// execution statistics when each query finishes.
+	OnQueryStats func(stats *QueryStats)
+	// DecodeWorkers is the number of the goroutines decoding the rows of all partitions, in parallel with fetching
+	// them, so that decoding a hot partition doesn't serialize behind its network reads. The results of each
+	// partition are still passed in order. If it's less than 2, the rows are decoded by the goroutine of the partition.
+	DecodeWorkers int
+	// If RawJSON is set, the change record column of each row is passed in JSON as RawChangeRecord, and the mods and
+	// the column types of the data change records are not decoded, to save the CPU when the records are written as is.
+	// It cannot be used with Redactor.
+	RawJSON bool
+	// If ClampStartTimestamp is set, StartTimestamp older than the retention period of the stream is moved to the
+	// earliest readable timestamp with a warning. Otherwise, creating the reader fails with ErrStartBeforeRetention.
+	ClampStartTimestamp bool
+	// If RawRows is set, the row of each result is passed as Row in addition to the decoded records.
+	// It cannot be used with Redactor.
+	RawRows bool
+	// If LazyMods is set, the mods of the data change records are left in LazyMods until DecodeMods is called, so that
+	// the consumer dropping most records, e.g. by the table name or the mod type, doesn't pay decoding the JSON values.
+	// It's ignored with Redactor or EnrichRows, which need the mods, and for PostgreSQL, whose records are decoded at once.
+	LazyMods bool
+	// If EnrichRows is set, the row of each mod except DELETE is read by its keys at the commit timestamp, and passed
+	// as FullRow, e.g. for the consumers needing the columns not watched by the stream. It costs a read per mod.
+	// It cannot be used with RawJSON.
+	EnrichRows bool
+	// TracerProvider provides the tracer of the spans of the partition queries, the decoding and the consumer.
+	// If nil, the global provider of OpenTelemetry is used.
+	TracerProvider trace.TracerProvider
+	// MeterProvider provides the meter of the metrics of the records, the partitions and the consumer latency.
+	// If nil, the global provider of OpenTelemetry is used.
+	MeterProvider metric.MeterProvider
+	// Logger logs the internal events, e.g. the start and the end of the partitions and the retries.
+	// If nil, nothing is logged.
+	Logger *slog.Logger
 }
 
 // NewReader creates a new reader.
@@ -148,62 +229,286 @@ func NewReaderWithConfig(ctx context.Context, projectID, instanceID, databaseID,
 		return nil, err
 	}
 
+	r, err := NewReaderWithClient(ctx, client, streamID, config)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	r.ownsClient = true
+	return r, nil
+}
+
+// NewReaderWithClient creates a new reader with a given client, e.g. to read multiple change streams with the same client.
+// SpannerClientConfig and SpannerClientOptions of the configuration are ignored, and Close doesn't close the client.
+func NewReaderWithClient(ctx context.Context, client *spanner.Client, streamID string, config Config) (*Reader, error) {
+	if config.RawJSON && config.Redactor != nil {
+		return nil, errors.New("the redactor cannot be used with raw JSON, as it can't redact the raw change records")
+	}
+	if config.RawRows && config.Redactor != nil {
+		return nil, errors.New("the redactor cannot be used with raw rows, as it can't redact the rows")
+	}
+	if config.RawJSON && config.EnrichRows {
+		return nil, errors.New("the rows cannot be enriched with raw JSON, as the mods are not decoded")
+	}
+
 	dialect, err := detectDialect(ctx, client)
 	if err != nil {
 		return nil, fmt.Errorf("failed to detect dialect: %w", err)
 	}
+	if err := checkStreamExists(ctx, client, dialect, streamID); err != nil {
+		return nil, err
+	}
 
 	heartbeatInterval := config.HeartbeatInterval
 	if heartbeatInterval == 0 {
 		heartbeatInterval = 10 * time.Second
 	}
 
+	metrics, err := newReaderMetrics(config.MeterProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(discardHandler{})
+	}
+	logger = logger.With("stream", streamID)
+	logger.Debug("Detected the database dialect", "dialect", dialect)
+
+	startTimestamp := config.StartTimestamp
+	if !startTimestamp.IsZero() && startTimestamp.Before(time.Now().Add(-minRetentionPeriod)) {
+		earliest, retentionPeriod, err := earliestReadableTimestamp(ctx, client, dialect, streamID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the retention period: %w", err)
+		}
+		startTimestamp, err = checkStartTimestamp(startTimestamp, earliest, retentionPeriod, config.ClampStartTimestamp)
+		if err != nil {
+			return nil, err
+		}
+		if !startTimestamp.Equal(config.StartTimestamp) {
+			logger.Warn("Start timestamp is older than the retention period, reading from the earliest readable timestamp",
+				"start_timestamp", config.StartTimestamp, "earliest_timestamp", startTimestamp, "retention_period", retentionPeriod)
+		}
+	}
+
 	return &Reader{
 		client:            client,
 		streamID:          streamID,
-		startTimestamp:    config.StartTimestamp,
+		startTimestamp:    startTimestamp,
 		endTimestamp:      config.EndTimestamp,
 		heartbeatInterval: heartbeatInterval,
+		redactor:          config.Redactor,
+		priority:          config.Priority,
+		maxPartitions:     config.MaxConcurrentPartitions,
+		startLimiter:      newStartLimiter(config.PartitionStartRate),
+		retry:             config.Retry,
+		retryBudget:       newRetryBudget(config.Retry),
+		onQueryStats:      config.OnQueryStats,
+		decodeWorkers:     config.DecodeWorkers,
+		rawJSON:           config.RawJSON,
+		rawRows:           config.RawRows,
+		lazyMods:          config.LazyMods && config.Redactor == nil && !config.EnrichRows,
+		enrichRows:        config.EnrichRows,
+		tracer:            newTracer(config.TracerProvider),
+		metrics:           metrics,
+		logger:            logger,
 		dialect:           dialect,
 		states:            make(map[string]partitionState),
+		announced:         make(map[string]*UnreadPartition),
 	}, nil
 }
 
 // Close closes the reader.
 func (r *Reader) Close() {
-	r.client.Close()
+	if r.ownsClient {
+		r.client.Close()
+	}
 }
 
+// ErrStop can be returned by the function passed to Read to stop reading without an error.
+var ErrStop = errors.New("stop reading")
+
 // Read starts reading the change stream.
 //
 // If function f returns an error, Read finishes the process and returns the error.
+// If the error is ErrStop, Read returns nil instead. The other errors of the partitions are wrapped by PartitionError.
+// With EndTimestamp, Read returns after all the partitions are read through it, or ErrIncompleteRead if any of them
+// were not, e.g. a child partition whose parents didn't all finish.
 // Once this method is called, reader must not be reused in any other places (i.e. not reentrant).
 func (r *Reader) Read(ctx context.Context, f func(result *ReadResult) error) error {
 	r.mu.Lock()
-	if r.group != nil {
+	if r.scheduler != nil {
 		r.mu.Unlock()
 		return errors.New("reader has already been read")
 	}
-	group, ctx := errgroup.WithContext(ctx)
-	r.group = group
+	r.scheduler = newScheduler(r.maxPartitions, func(ctx context.Context, p *partition) error {
+		err := r.readPartition(ctx, p.token, p.startTimestamp, f)
+		if err != nil && !errors.Is(err, ErrStop) {
+			return &PartitionError{StreamID: r.streamID, PartitionToken: p.token, StartTimestamp: p.startTimestamp, Err: err}
+		}
+		return err
+	})
+	r.scheduler.onQueue = func(delta int64) {
+		r.metrics.addQueuedPartitions(ctx, r.streamID, delta)
+	}
+	if r.endTimestamp.IsZero() {
+		// Without the end timestamp, the busy workers never finish their partitions, so the queued one is never read.
+		r.scheduler.onWait = func(p *partition) {
+			r.logger.Warn("Partition is queued while all the workers are busy, and it will not be read until any of them finishes",
+				"partition_token", p.token, "max_concurrent_partitions", r.maxPartitions)
+		}
+	}
 	r.mu.Unlock()
 
-	r.group.Go(func() error {
-		start := r.startTimestamp
-		if start.IsZero() {
-			start = time.Now()
+	if r.decodeWorkers > 1 {
+		r.decodeJobs = make(chan *decodeJob)
+		defer close(r.decodeJobs)
+		for i := 0; i < r.decodeWorkers; i++ {
+			go r.decodeWorker()
 		}
-		return r.startRead(ctx, "", start, f)
-	})
+	}
 
-	return group.Wait()
+	start := r.startTimestamp
+	if start.IsZero() {
+		ts, err := CurrentTimestamp(ctx, r.client)
+		if err != nil {
+			return fmt.Errorf("failed to get the current timestamp: %w", err)
+		}
+		start = ts
+	}
+	// The initial query is scheduled as a partition of the empty token.
+	if err := r.scheduler.run(ctx, &partition{startTimestamp: start}); err != nil {
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		return err
+	}
+	if !r.endTimestamp.IsZero() {
+		return r.checkComplete()
+	}
+	return nil
+}
+
+// CurrentTimestamp returns the current timestamp of the database, which is the read timestamp of a strong read, not
+// to miss the commits or to be rejected when the local clock is ahead of the database.
+func CurrentTimestamp(ctx context.Context, client *spanner.Client) (time.Time, error) {
+	tx := client.Single()
+	defer tx.Close()
+	if err := tx.Query(ctx, spanner.NewStatement("SELECT 1")).Do(func(*spanner.Row) error {
+		return nil
+	}); err != nil {
+		return time.Time{}, err
+	}
+	return tx.Timestamp()
 }
 
-func (r *Reader) startRead(ctx context.Context, partitionToken string, startTimestamp time.Time, f func(result *ReadResult) error) error {
+// readPartition reads the partition, and schedules its child partitions ready to be read.
+func (r *Reader) readPartition(ctx context.Context, partitionToken string, startTimestamp time.Time, f func(result *ReadResult) error) error {
 	if !r.markStateReading(partitionToken) {
 		return nil
 	}
 
+	// Wait for the turn if the partition starts are rate limited. The initial query is not a partition.
+	if r.startLimiter != nil && partitionToken != "" {
+		if err := r.startLimiter.wait(ctx); err != nil {
+			return err
+		}
+	}
+	// The initial query is not a partition.
+	if partitionToken != "" {
+		r.metrics.addActivePartitions(ctx, r.streamID, 1)
+		r.logger.Debug("Started reading the partition", "partition_token", partitionToken, "start_timestamp", startTimestamp)
+	}
+	childPartitionRecords, err := r.queryWithRetry(ctx, partitionToken, startTimestamp, f)
+	if partitionToken != "" {
+		r.metrics.addActivePartitions(ctx, r.streamID, -1)
+	}
+	if err != nil {
+		// The query of a missing stream fails as an invalid query, e.g. when the stream is dropped after creating the reader.
+		if code := spanner.ErrCode(err); partitionToken == "" && (code == codes.InvalidArgument || code == codes.NotFound) {
+			if exists, existsErr := streamExists(ctx, r.client, r.streamID); existsErr == nil && !exists {
+				return fmt.Errorf("%w: %s", ErrStreamNotFound, r.streamID)
+			}
+		}
+		return err
+	}
+
+	r.markStateFinished(partitionToken)
+	if partitionToken != "" {
+		r.logger.Debug("Finished reading the partition", "partition_token", partitionToken, "child_partitions_records", len(childPartitionRecords))
+	}
+
+	for _, childPartitionsRecord := range childPartitionRecords {
+		// childStartTimestamp is always later than r.startTimestamp.
+		childStartTimestamp := childPartitionsRecord.StartTimestamp
+		for _, childPartition := range childPartitionsRecord.ChildPartitions {
+			r.announceChild(childPartition, childStartTimestamp)
+			if r.canReadChild(childPartition) {
+				r.scheduler.schedule(&partition{token: childPartition.Token, startTimestamp: childStartTimestamp})
+			}
+		}
+	}
+
+	return nil
+}
+
+// queryWithRetry queries the partition, retrying it by the retry policy from the timestamp of the last record.
+// It returns the child partitions records.
+func (r *Reader) queryWithRetry(ctx context.Context, partitionToken string, startTimestamp time.Time, f func(result *ReadResult) error) ([]*ChildPartitionsRecord, error) {
+	var childPartitionRecords []*ChildPartitionsRecord
+	progress := newPartitionProgress(startTimestamp)
+	for attempt := 1; ; attempt++ {
+		var fErr error
+		queryCtx, span := r.tracer.Start(ctx, "changestreams.PartitionQuery", trace.WithAttributes(
+			attributeStreamID.String(r.streamID),
+			attributePartitionToken.String(partitionToken),
+			attributeAttempt.Int(attempt),
+		))
+		err := r.query(queryCtx, partitionToken, progress.timestamp, func(result *ReadResult) error {
+			// The records already passed before the retry are skipped.
+			if !progress.update(result) {
+				return nil
+			}
+			for _, changeRecord := range result.ChangeRecords {
+				childPartitionRecords = append(childPartitionRecords, changeRecord.ChildPartitionsRecords...)
+			}
+			fErr = f(result)
+			return fErr
+		})
+		endSpan(span, err)
+		// The errors of f are never retried.
+		if err == nil || fErr != nil || !r.retry.shouldRetry(ctx, err, attempt) {
+			return childPartitionRecords, err
+		}
+		if r.retryBudget != nil {
+			if budgetErr := r.retryBudget.take(err); budgetErr != nil {
+				return childPartitionRecords, budgetErr
+			}
+		}
+		backoff := r.retry.backoff(attempt)
+		if r.retry.OnRetry != nil {
+			r.retry.OnRetry(&RetryEvent{
+				StreamID:        r.streamID,
+				PartitionToken:  partitionToken,
+				Attempt:         attempt,
+				Err:             err,
+				Backoff:         backoff,
+				ResumeTimestamp: progress.timestamp,
+			})
+		}
+		r.logger.Warn("Retrying the partition query", "partition_token", partitionToken, "attempt", attempt, "backoff", backoff, "resume_timestamp", progress.timestamp, "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// query queries the partition from the start timestamp, and passes the results to f.
+func (r *Reader) query(ctx context.Context, partitionToken string, startTimestamp time.Time, f func(result *ReadResult) error) error {
 	var stmt spanner.Statement
 	switch r.dialect {
 	case dialectGoogleSQL:
@@ -246,51 +551,92 @@ func (r *Reader) startRead(ctx context.Context, partitionToken string, startTime
 		return fmt.Errorf("unexpected dialect: %s", r.dialect)
 	}
 
-	var childPartitionRecords []*ChildPartitionsRecord
-	if err := r.client.Single().Query(ctx, stmt).Do(func(row *spanner.Row) error {
-		readResult := ReadResult{PartitionToken: partitionToken}
-		switch r.dialect {
-		case dialectGoogleSQL:
-			if err := row.ToStructLenient(&readResult); err != nil {
-				return err
-			}
-		case dialectPostgreSQL:
-			changeRecord, err := decodePostgresRow(row)
+	opts := spanner.QueryOptions{Priority: r.priority}
+	if r.onQueryStats != nil {
+		mode := sppb.ExecuteSqlRequest_PROFILE
+		opts.Mode = &mode
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	iter := r.client.Single().QueryWithOptions(ctx, stmt, opts)
+	var err error
+	if r.decodeJobs != nil {
+		err = r.doPipelined(ctx, cancel, iter, partitionToken, f)
+	} else {
+		err = iter.Do(func(row *spanner.Row) error {
+			readResult, err := r.decode(ctx, row, partitionToken)
 			if err != nil {
 				return err
 			}
-			readResult.ChangeRecords = []*ChangeRecord{changeRecord}
-		default:
-			return fmt.Errorf("unexpected dialect: %s", r.dialect)
-		}
-
-		for _, changeRecord := range readResult.ChangeRecords {
-			if len(changeRecord.ChildPartitionsRecords) > 0 {
-				childPartitionRecords = append(childPartitionRecords, changeRecord.ChildPartitionsRecords...)
-			}
-		}
-
-		return f(&readResult)
-	}); err != nil {
-		return err
+			return r.consume(ctx, readResult, f)
+		})
 	}
+	if r.onQueryStats != nil && iter.QueryStats != nil {
+		r.onQueryStats(newQueryStats(r.streamID, partitionToken, iter.QueryStats))
+	}
+	return err
+}
 
-	r.markStateFinished(partitionToken)
+// decode decodes the row into the result, and enriches and redacts its data change records.
+func (r *Reader) decode(ctx context.Context, row *spanner.Row, partitionToken string) (*ReadResult, error) {
+	readResult := &ReadResult{StreamID: r.streamID, PartitionToken: partitionToken}
+	_, span := r.tracer.Start(ctx, "changestreams.DecodeRecord")
+	err := r.decodeRow(row, readResult)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	if r.rawRows {
+		readResult.Row = row
+	}
 
-	for _, childPartitionsRecord := range childPartitionRecords {
-		// childStartTimestamp is always later than r.startTimestamp.
-		childStartTimestamp := childPartitionsRecord.StartTimestamp
-		for _, childPartition := range childPartitionsRecord.ChildPartitions {
-			if r.canReadChild(childPartition) {
-				partition := childPartition
-				r.group.Go(func() error {
-					return r.startRead(ctx, partition.Token, childStartTimestamp, f)
-				})
+	for _, changeRecord := range readResult.ChangeRecords {
+		if r.enrichRows {
+			for _, dataChangeRecord := range changeRecord.DataChangeRecords {
+				if err := r.enrichRecord(ctx, dataChangeRecord); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if r.redactor != nil {
+			for _, dataChangeRecord := range changeRecord.DataChangeRecords {
+				r.redactor.Redact(dataChangeRecord)
 			}
 		}
 	}
+	return readResult, nil
+}
 
-	return nil
+// decodeRow decodes the change records of the row into the result.
+func (r *Reader) decodeRow(row *spanner.Row, result *ReadResult) error {
+	if r.rawJSON {
+		return r.decodeRawRow(row, result)
+	}
+	switch r.dialect {
+	case dialectGoogleSQL:
+		mods := modsDecoded
+		if r.lazyMods {
+			mods = modsLazy
+		}
+		changeRecords, err := decodeChangeRecords(row, mods)
+		if errors.Is(err, errDecodeFallback) {
+			return row.ToStructLenient(result)
+		}
+		if err != nil {
+			return err
+		}
+		result.ChangeRecords = changeRecords
+		return nil
+	case dialectPostgreSQL:
+		changeRecord, err := decodePostgresRow(row)
+		if err != nil {
+			return err
+		}
+		result.ChangeRecords = []*ChangeRecord{changeRecord}
+		return nil
+	default:
+		return fmt.Errorf("unexpected dialect: %s", r.dialect)
+	}
 }
 
 func (r *Reader) markStateReading(partitionToken string) bool {
@@ -302,6 +648,7 @@ func (r *Reader) markStateReading(partitionToken string) bool {
 		return false
 	}
 	r.states[partitionToken] = partitionStateReading
+	delete(r.announced, partitionToken)
 	return true
 }
 
@@ -334,7 +681,11 @@ func decodePostgresRow(row *spanner.Row) (*ChangeRecord, error) {
 	if err != nil {
 		return nil, err
 	}
+	return decodePostgresJSON(jsonBytes)
+}
 
+// decodePostgresJSON decodes the change record of PostgreSQL in JSON.
+func decodePostgresJSON(jsonBytes []byte) (*ChangeRecord, error) {
 	var changeRecordPG changeRecordPostgres
 	if err := json.Unmarshal(jsonBytes, &changeRecordPG); err != nil {
 		return nil, err
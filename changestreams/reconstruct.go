@@ -0,0 +1,200 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+)
+
+// RowStore stores the last known values of the rows for OldValuesReconstructor, by the table name and the keys of the
+// rows in JSON. It's called by one goroutine at a time.
+type RowStore interface {
+	// Get returns the values of the row, or false if the row is unknown.
+	Get(tableName, keys string) (map[string]interface{}, bool)
+	// Put replaces the values of the row.
+	Put(tableName, keys string, values map[string]interface{})
+	// Delete forgets the row.
+	Delete(tableName, keys string)
+}
+
+// LRURowStore is a RowStore in memory, which keeps up to the fixed number of the rows, evicting the least recently
+// used ones.
+type LRURowStore struct {
+	size  int
+	rows  map[rowKey]*list.Element
+	order *list.List
+}
+
+type rowKey struct {
+	tableName string
+	keys      string
+}
+
+type lruRow struct {
+	key    rowKey
+	values map[string]interface{}
+}
+
+// NewLRURowStore creates a new LRURowStore keeping up to size rows.
+func NewLRURowStore(size int) *LRURowStore {
+	return &LRURowStore{size: size, rows: make(map[rowKey]*list.Element), order: list.New()}
+}
+
+func (s *LRURowStore) Get(tableName, keys string) (map[string]interface{}, bool) {
+	e, ok := s.rows[rowKey{tableName, keys}]
+	if !ok {
+		return nil, false
+	}
+	s.order.MoveToFront(e)
+	return e.Value.(*lruRow).values, true
+}
+
+func (s *LRURowStore) Put(tableName, keys string, values map[string]interface{}) {
+	key := rowKey{tableName, keys}
+	if e, ok := s.rows[key]; ok {
+		e.Value.(*lruRow).values = values
+		s.order.MoveToFront(e)
+		return
+	}
+	s.rows[key] = s.order.PushFront(&lruRow{key: key, values: values})
+	for s.size > 0 && s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.rows, oldest.Value.(*lruRow).key)
+	}
+}
+
+func (s *LRURowStore) Delete(tableName, keys string) {
+	key := rowKey{tableName, keys}
+	if e, ok := s.rows[key]; ok {
+		s.order.Remove(e)
+		delete(s.rows, key)
+	}
+}
+
+// Len returns the number of the rows in the store.
+func (s *LRURowStore) Len() int {
+	return s.order.Len()
+}
+
+// OldValuesReconstructor fills the old values of the data change records of NEW_VALUES and NEW_ROW streams, which
+// Cloud Spanner doesn't capture, from the last known values of the rows, so that the consumers comparing the old and
+// new values work regardless of the value capture type.
+//
+// The rows are known by the changes read so far, so the old values of a row are filled only after its INSERT, or only
+// for the columns of the previous UPDATEs, unless the store has kept the row, e.g. in a database across the runs.
+// The old values of UPDATE are the columns of the new values, and those of DELETE are all the known columns, the same
+// as OLD_AND_NEW_VALUES streams. The records of the other value capture types are left as they are.
+type OldValuesReconstructor struct {
+	store RowStore
+	mu    sync.Mutex
+}
+
+// NewOldValuesReconstructor creates a new OldValuesReconstructor with the store of the rows.
+func NewOldValuesReconstructor(store RowStore) *OldValuesReconstructor {
+	return &OldValuesReconstructor{store: store}
+}
+
+// Reconstruct fills the old values of the record, and updates the store with its new values. The records of a row
+// must be passed in the commit order, as they are passed by Read.
+func (r *OldValuesReconstructor) Reconstruct(record *DataChangeRecord) error {
+	if record.ValueCaptureType != "NEW_VALUES" && record.ValueCaptureType != "NEW_ROW" {
+		return nil
+	}
+	if err := record.DecodeMods(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, mod := range record.Mods {
+		b, err := json.Marshal(mod.Keys.Value)
+		if err != nil {
+			return fmt.Errorf("failed to encode the keys of %s: %w", record.TableName, err)
+		}
+		keys := string(b)
+		newValues, _ := mod.NewValues.Value.(map[string]interface{})
+		known, ok := r.store.Get(record.TableName, keys)
+
+		switch record.ModType {
+		case "INSERT":
+			r.store.Put(record.TableName, keys, copyValues(newValues))
+		case "UPDATE":
+			merged := make(map[string]interface{}, len(known)+len(newValues))
+			oldValues := make(map[string]interface{}, len(newValues))
+			for name, value := range known {
+				merged[name] = value
+				if _, changed := newValues[name]; changed {
+					oldValues[name] = value
+				}
+			}
+			for name, value := range newValues {
+				merged[name] = value
+			}
+			if ok {
+				mod.OldValues = nullJSONObject(oldValues)
+			}
+			r.store.Put(record.TableName, keys, merged)
+		case "DELETE":
+			if ok {
+				mod.OldValues = nullJSONObject(copyValues(known))
+			}
+			r.store.Delete(record.TableName, keys)
+		}
+	}
+	return nil
+}
+
+func nullJSONObject(values map[string]interface{}) spanner.NullJSON {
+	return spanner.NullJSON{Value: values, Valid: true}
+}
+
+func copyValues(values map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(values))
+	for name, value := range values {
+		copied[name] = value
+	}
+	return copied
+}
+
+// ReconstructSink fills the old values of the data change records by OldValuesReconstructor before writing them to
+// the sink.
+type ReconstructSink struct {
+	Sink
+	reconstructor *OldValuesReconstructor
+}
+
+// NewReconstructSink creates a new ReconstructSink.
+func NewReconstructSink(sink Sink, reconstructor *OldValuesReconstructor) *ReconstructSink {
+	return &ReconstructSink{Sink: sink, reconstructor: reconstructor}
+}
+
+func (s *ReconstructSink) Write(result *ReadResult) error {
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			if err := s.reconstructor.Reconstruct(r); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Sink.Write(result)
+}
@@ -0,0 +1,131 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestModRecord(valueCaptureType, modType string, keys, newValues map[string]interface{}) *DataChangeRecord {
+	return &DataChangeRecord{
+		TableName:        "Players",
+		ModType:          modType,
+		ValueCaptureType: valueCaptureType,
+		Mods: []*Mod{{
+			Keys:      spanner.NullJSON{Value: keys, Valid: true},
+			NewValues: spanner.NullJSON{Value: newValues, Valid: true},
+			OldValues: spanner.NullJSON{Value: map[string]interface{}{}, Valid: true},
+		}},
+	}
+}
+
+func TestOldValuesReconstructor(t *testing.T) {
+	key1 := map[string]interface{}{"PlayerId": "1"}
+	key2 := map[string]interface{}{"PlayerId": "2"}
+	for _, test := range []struct {
+		desc    string
+		records []*DataChangeRecord
+		want    []map[string]interface{}
+	}{
+		{
+			desc: "insert, update and delete",
+			records: []*DataChangeRecord{
+				newTestModRecord("NEW_VALUES", "INSERT", key1, map[string]interface{}{"Name": "foo", "Score": 1.0}),
+				newTestModRecord("NEW_VALUES", "UPDATE", key1, map[string]interface{}{"Score": 2.0}),
+				newTestModRecord("NEW_VALUES", "DELETE", key1, map[string]interface{}{}),
+			},
+			want: []map[string]interface{}{
+				{},
+				{"Score": 1.0},
+				{"Name": "foo", "Score": 2.0},
+			},
+		},
+		{
+			desc: "unknown row",
+			records: []*DataChangeRecord{
+				newTestModRecord("NEW_VALUES", "UPDATE", key1, map[string]interface{}{"Score": 2.0}),
+				newTestModRecord("NEW_VALUES", "UPDATE", key1, map[string]interface{}{"Name": "foo", "Score": 3.0}),
+				newTestModRecord("NEW_VALUES", "DELETE", key2, map[string]interface{}{}),
+			},
+			want: []map[string]interface{}{
+				{},
+				{"Score": 2.0},
+				{},
+			},
+		},
+		{
+			desc: "new row",
+			records: []*DataChangeRecord{
+				newTestModRecord("NEW_ROW", "INSERT", key1, map[string]interface{}{"Name": "foo", "Score": 1.0}),
+				newTestModRecord("NEW_ROW", "UPDATE", key1, map[string]interface{}{"Name": "foo", "Score": 2.0}),
+			},
+			want: []map[string]interface{}{
+				{},
+				{"Name": "foo", "Score": 1.0},
+			},
+		},
+		{
+			desc: "old values captured",
+			records: []*DataChangeRecord{
+				newTestModRecord("OLD_AND_NEW_VALUES", "INSERT", key1, map[string]interface{}{"Name": "foo"}),
+				newTestModRecord("OLD_AND_NEW_VALUES", "DELETE", key1, map[string]interface{}{}),
+			},
+			want: []map[string]interface{}{
+				{},
+				{},
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			reconstructor := NewOldValuesReconstructor(NewLRURowStore(10))
+			var got []map[string]interface{}
+			for _, r := range test.records {
+				if err := reconstructor.Reconstruct(r); err != nil {
+					t.Fatalf("Reconstruct error: %v", err)
+				}
+				got = append(got, r.Mods[0].OldValues.Value.(map[string]interface{}))
+			}
+			if diff := cmp.Diff(got, test.want); diff != "" {
+				t.Errorf("old values have diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestLRURowStore(t *testing.T) {
+	s := NewLRURowStore(2)
+	s.Put("Players", "1", map[string]interface{}{"Name": "a"})
+	s.Put("Players", "2", map[string]interface{}{"Name": "b"})
+	if _, ok := s.Get("Players", "1"); !ok {
+		t.Fatal("row 1 is not found")
+	}
+	s.Put("Players", "3", map[string]interface{}{"Name": "c"})
+
+	if _, ok := s.Get("Players", "2"); ok {
+		t.Error("least recently used row 2 is not evicted")
+	}
+	if _, ok := s.Get("Players", "1"); !ok {
+		t.Error("row 1 is evicted")
+	}
+	s.Delete("Players", "1")
+	if got := s.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+}
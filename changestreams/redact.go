@@ -0,0 +1,133 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// RedactPlaceholder is the value that replaces redacted columns in RedactModePlaceholder.
+const RedactPlaceholder = "[REDACTED]"
+
+// Redactor masks column values of a data change record before it is passed to the consumer.
+type Redactor interface {
+	Redact(record *DataChangeRecord)
+}
+
+// RedactMode specifies how ColumnRedactor replaces column values.
+type RedactMode int
+
+const (
+	// RedactModePlaceholder replaces values with RedactPlaceholder.
+	RedactModePlaceholder RedactMode = iota
+	// RedactModeSHA256 replaces values with the hex encoded SHA-256 digest of their JSON representation,
+	// so that equal values can still be correlated.
+	RedactModeSHA256
+)
+
+// ColumnRedactor is a Redactor that replaces the values of the specified columns.
+type ColumnRedactor struct {
+	columns map[string]map[string]bool
+	mode    RedactMode
+}
+
+// NewColumnRedactor creates a new ColumnRedactor.
+// Each column must be specified in the "table.column" form.
+func NewColumnRedactor(columns []string, mode RedactMode) (*ColumnRedactor, error) {
+	if mode != RedactModePlaceholder && mode != RedactModeSHA256 {
+		return nil, fmt.Errorf("invalid redact mode: %d", mode)
+	}
+
+	m := make(map[string]map[string]bool)
+	for _, column := range columns {
+		parts := strings.SplitN(column, ".", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid column %q, must be in the form of table.column", column)
+		}
+		tableName, columnName := parts[0], parts[1]
+		if _, ok := m[tableName]; !ok {
+			m[tableName] = make(map[string]bool)
+		}
+		m[tableName][columnName] = true
+	}
+	return &ColumnRedactor{
+		columns: m,
+		mode:    mode,
+	}, nil
+}
+
+// Redact replaces the values of the redacted columns in keys, new values, old values and full rows of the record.
+func (r *ColumnRedactor) Redact(record *DataChangeRecord) {
+	columns, ok := r.columns[record.TableName]
+	if !ok {
+		return
+	}
+	for _, mod := range record.Mods {
+		r.redactValues(mod.Keys.Value, columns)
+		r.redactValues(mod.NewValues.Value, columns)
+		r.redactValues(mod.OldValues.Value, columns)
+		mod.FullRow = r.redactJSON(mod.FullRow, columns)
+	}
+}
+
+// redactJSON returns the JSON object of the values with the redacted columns replaced. If the object is not valid,
+// it's dropped not to leak the values.
+func (r *ColumnRedactor) redactJSON(b json.RawMessage, columns map[string]bool) json.RawMessage {
+	if len(b) == 0 {
+		return b
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil
+	}
+	r.redactValues(values, columns)
+	redacted, err := json.Marshal(values)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+func (r *ColumnRedactor) redactValues(value interface{}, columns map[string]bool) {
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, v := range values {
+		if columns[name] {
+			values[name] = r.redactValue(v)
+		}
+	}
+}
+
+func (r *ColumnRedactor) redactValue(value interface{}) interface{} {
+	switch r.mode {
+	case RedactModeSHA256:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return RedactPlaceholder
+		}
+		sum := sha256.Sum256(b)
+		return "sha256:" + hex.EncodeToString(sum[:])
+	default:
+		return RedactPlaceholder
+	}
+}
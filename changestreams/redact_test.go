@@ -0,0 +1,143 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestColumnRedactor(t *testing.T) {
+	newRecord := func() *DataChangeRecord {
+		return &DataChangeRecord{
+			TableName: "Players",
+			Mods: []*Mod{
+				{
+					Keys: spanner.NullJSON{
+						Value: map[string]interface{}{"PlayerId": "1"},
+						Valid: true,
+					},
+					NewValues: spanner.NullJSON{
+						Value: map[string]interface{}{"Name": "foo", "Email": "foo@example.com"},
+						Valid: true,
+					},
+					OldValues: spanner.NullJSON{
+						Value: map[string]interface{}{"Name": "bar", "Email": "bar@example.com"},
+						Valid: true,
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		desc    string
+		columns []string
+		mode    RedactMode
+		want    []*Mod
+	}{
+		{
+			desc:    "placeholder",
+			columns: []string{"Players.Email", "Players.PlayerId"},
+			mode:    RedactModePlaceholder,
+			want: []*Mod{
+				{
+					Keys: spanner.NullJSON{
+						Value: map[string]interface{}{"PlayerId": RedactPlaceholder},
+						Valid: true,
+					},
+					NewValues: spanner.NullJSON{
+						Value: map[string]interface{}{"Name": "foo", "Email": RedactPlaceholder},
+						Valid: true,
+					},
+					OldValues: spanner.NullJSON{
+						Value: map[string]interface{}{"Name": "bar", "Email": RedactPlaceholder},
+						Valid: true,
+					},
+				},
+			},
+		},
+		{
+			desc:    "sha256",
+			columns: []string{"Players.Name"},
+			mode:    RedactModeSHA256,
+			want: []*Mod{
+				{
+					Keys: spanner.NullJSON{
+						Value: map[string]interface{}{"PlayerId": "1"},
+						Valid: true,
+					},
+					NewValues: spanner.NullJSON{
+						// sha256 of `"foo"`.
+						Value: map[string]interface{}{"Name": "sha256:b2213295d564916f89a6a42455567c87c3f480fcd7a1c15e220f17d7169a790b", "Email": "foo@example.com"},
+						Valid: true,
+					},
+					OldValues: spanner.NullJSON{
+						// sha256 of `"bar"`.
+						Value: map[string]interface{}{"Name": "sha256:4c293ff010a730f0972761331d1b5678478d425c2dc5cefd16d8f20059e497f3", "Email": "bar@example.com"},
+						Valid: true,
+					},
+				},
+			},
+		},
+		{
+			desc:    "other table",
+			columns: []string{"Singers.Name"},
+			mode:    RedactModePlaceholder,
+			want:    newRecord().Mods,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			redactor, err := NewColumnRedactor(test.columns, test.mode)
+			if err != nil {
+				t.Fatalf("NewColumnRedactor error: %v", err)
+			}
+			record := newRecord()
+			redactor.Redact(record)
+			if diff := cmp.Diff(record.Mods, test.want); diff != "" {
+				t.Errorf("diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestColumnRedactorFullRow(t *testing.T) {
+	redactor, err := NewColumnRedactor([]string{"Players.Email"}, RedactModePlaceholder)
+	if err != nil {
+		t.Fatalf("NewColumnRedactor error: %v", err)
+	}
+	record := &DataChangeRecord{
+		TableName: "Players",
+		Mods:      []*Mod{{FullRow: []byte(`{"PlayerId":"1","Email":"foo@example.com"}`)}},
+	}
+	redactor.Redact(record)
+	want := `{"Email":"[REDACTED]","PlayerId":"1"}`
+	if got := string(record.Mods[0].FullRow); got != want {
+		t.Errorf("full row = %s, want %s", got, want)
+	}
+}
+
+func TestNewColumnRedactorInvalidColumn(t *testing.T) {
+	for _, column := range []string{"Players", ".Name", "Players."} {
+		if _, err := NewColumnRedactor([]string{column}, RedactModePlaceholder); err == nil {
+			t.Errorf("NewColumnRedactor(%q) should return an error", column)
+		}
+	}
+}
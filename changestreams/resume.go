@@ -0,0 +1,83 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import "time"
+
+// partitionProgress is the last timestamp observed in a partition, from which its query resumes on retry.
+// The resumed query returns the data change records committed at the timestamp again, so the records already passed
+// are remembered to be skipped.
+type partitionProgress struct {
+	timestamp time.Time
+	// passed is the set of the data change records passed at the timestamp.
+	passed map[dataChangeRecordKey]struct{}
+}
+
+// dataChangeRecordKey identifies a data change record in a partition.
+type dataChangeRecordKey struct {
+	serverTransactionID string
+	recordSequence      string
+}
+
+func newPartitionProgress(startTimestamp time.Time) *partitionProgress {
+	return &partitionProgress{timestamp: startTimestamp}
+}
+
+// update advances the progress by the records of the result, and removes the data change records already passed from
+// it. It reports whether the result has any records left.
+func (p *partitionProgress) update(result *ReadResult) bool {
+	var left bool
+	for _, changeRecord := range result.ChangeRecords {
+		records := changeRecord.DataChangeRecords[:0]
+		for _, r := range changeRecord.DataChangeRecords {
+			key := dataChangeRecordKey{serverTransactionID: r.ServerTransactionID, recordSequence: r.RecordSequence}
+			if r.CommitTimestamp.After(p.timestamp) {
+				p.advance(r.CommitTimestamp)
+			} else if _, ok := p.passed[key]; ok && r.CommitTimestamp.Equal(p.timestamp) {
+				continue
+			}
+			if r.CommitTimestamp.Equal(p.timestamp) {
+				if p.passed == nil {
+					p.passed = make(map[dataChangeRecordKey]struct{})
+				}
+				p.passed[key] = struct{}{}
+			}
+			records = append(records, r)
+		}
+		changeRecord.DataChangeRecords = records
+
+		for _, r := range changeRecord.HeartbeatRecords {
+			if r.Timestamp.After(p.timestamp) {
+				p.advance(r.Timestamp)
+			}
+		}
+		for _, r := range changeRecord.ChildPartitionsRecords {
+			if r.StartTimestamp.After(p.timestamp) {
+				p.advance(r.StartTimestamp)
+			}
+		}
+		if len(records) > 0 || len(changeRecord.HeartbeatRecords) > 0 || len(changeRecord.ChildPartitionsRecords) > 0 {
+			left = true
+		}
+	}
+	return left
+}
+
+func (p *partitionProgress) advance(timestamp time.Time) {
+	p.timestamp = timestamp
+	p.passed = nil
+}
@@ -0,0 +1,65 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPartitionProgress(t *testing.T) {
+	t1 := mustParseTime("2022-12-04T18:00:00Z")
+	t2 := t1.Add(time.Second)
+	newResult := func(records ...*DataChangeRecord) *ReadResult {
+		return &ReadResult{ChangeRecords: []*ChangeRecord{{DataChangeRecords: records}}}
+	}
+	newRecord := func(ts time.Time, seq string) *DataChangeRecord {
+		return &DataChangeRecord{CommitTimestamp: ts, ServerTransactionID: "tx", RecordSequence: seq}
+	}
+
+	p := newPartitionProgress(t1)
+	if !p.update(newResult(newRecord(t2, "0"), newRecord(t2, "1"))) {
+		t.Fatalf("update must report the new records")
+	}
+	if !p.timestamp.Equal(t2) {
+		t.Errorf("timestamp = %v, but want %v", p.timestamp, t2)
+	}
+
+	// The retried query from t2 returns the records of t2 again.
+	result := newResult(newRecord(t2, "0"), newRecord(t2, "1"), newRecord(t2, "2"))
+	if !p.update(result) {
+		t.Fatalf("update must report the record not passed yet")
+	}
+	if records := result.ChangeRecords[0].DataChangeRecords; len(records) != 1 || records[0].RecordSequence != "2" {
+		t.Errorf("update must skip the records already passed, but got %d records", len(records))
+	}
+	if p.update(newResult(newRecord(t2, "2"))) {
+		t.Errorf("update must report no records left for the records already passed")
+	}
+
+	t3 := t2.Add(time.Second)
+	if !p.update(&ReadResult{ChangeRecords: []*ChangeRecord{{HeartbeatRecords: []*HeartbeatRecord{{Timestamp: t3}}}}}) {
+		t.Errorf("update must report the heartbeat records")
+	}
+	if !p.timestamp.Equal(t3) || p.passed != nil {
+		t.Errorf("heartbeat must advance the timestamp to %v and reset the passed records, but got %v", t3, p.timestamp)
+	}
+	// The same record sequence at a later timestamp is another record.
+	if !p.update(newResult(newRecord(t3, "0"))) {
+		t.Errorf("update must report the record at the heartbeat timestamp")
+	}
+}
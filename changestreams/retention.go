@@ -0,0 +1,114 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+// ErrStartBeforeRetention is returned when the start timestamp is older than the retention period of the change
+// stream, as the data change records before it are no longer readable.
+var ErrStartBeforeRetention = errors.New("start timestamp is older than the retention period of the change stream")
+
+// minRetentionPeriod is the minimum retention period of the change streams. The start timestamps newer than it before
+// now are readable without querying the retention period.
+const minRetentionPeriod = 24 * time.Hour
+
+// clampMargin is added to the earliest readable timestamp to clamp the start timestamp to, as the earliest readable
+// timestamp keeps moving until the partitions are queried.
+const clampMargin = time.Minute
+
+// earliestReadableTimestamp returns the earliest timestamp the change stream can be read from, which is its retention
+// period before the current timestamp of the database, and the retention period.
+func earliestReadableTimestamp(ctx context.Context, client *spanner.Client, dialect dialect, streamID string) (time.Time, string, error) {
+	queries, ok := describeQueriesByDialect[dialect]
+	if !ok {
+		return time.Time{}, "", fmt.Errorf("unexpected dialect: %s", dialect)
+	}
+
+	retentionPeriod := DefaultRetentionPeriod
+	// The read timestamp of the query is the current timestamp of the database, not to depend on the local clock.
+	tx := client.Single()
+	defer tx.Close()
+	stmt := spanner.Statement{SQL: queries.options, Params: map[string]interface{}{queries.param: streamID}}
+	if err := tx.Query(ctx, stmt).Do(func(row *spanner.Row) error {
+		var name, value string
+		if err := row.Columns(&name, &value); err != nil {
+			return err
+		}
+		if name == "retention_period" {
+			retentionPeriod = value
+		}
+		return nil
+	}); err != nil {
+		return time.Time{}, "", err
+	}
+	now, err := tx.Timestamp()
+	if err != nil {
+		return time.Time{}, "", err
+	}
+
+	retention, err := parseRetentionPeriod(retentionPeriod)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	return now.Add(-retention), retentionPeriod, nil
+}
+
+// parseRetentionPeriod parses the retention period option of the change stream, e.g. "36h" or "7d".
+func parseRetentionPeriod(s string) (time.Duration, error) {
+	if len(s) < 2 {
+		return 0, fmt.Errorf("invalid retention period: %q", s)
+	}
+	n, err := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid retention period: %q", s)
+	}
+	var unit time.Duration
+	switch s[len(s)-1] {
+	case 'd':
+		unit = 24 * time.Hour
+	case 'h':
+		unit = time.Hour
+	case 'm':
+		unit = time.Minute
+	case 's':
+		unit = time.Second
+	default:
+		return 0, fmt.Errorf("invalid retention period: %q", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// checkStartTimestamp returns the start timestamp if it's not older than the earliest readable timestamp. Otherwise,
+// it returns the earliest readable timestamp with clampMargin if clamp is set, or ErrStartBeforeRetention.
+func checkStartTimestamp(start, earliest time.Time, retentionPeriod string, clamp bool) (time.Time, error) {
+	if !start.Before(earliest) {
+		return start, nil
+	}
+	if clamp {
+		return earliest.Add(clampMargin), nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s is older than %s, the earliest readable timestamp by the retention period %s",
+		ErrStartBeforeRetention, start.Format(time.RFC3339Nano), earliest.Format(time.RFC3339Nano), retentionPeriod)
+}
@@ -0,0 +1,74 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRetentionPeriod(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		want time.Duration
+	}{
+		{"1d", 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"86400s", 24 * time.Hour},
+	} {
+		got, err := parseRetentionPeriod(tt.s)
+		if err != nil {
+			t.Errorf("parseRetentionPeriod(%q) error: %v", tt.s, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseRetentionPeriod(%q) = %v, but want %v", tt.s, got, tt.want)
+		}
+	}
+	for _, s := range []string{"", "d", "1w", "-1d", "0h", "1.5d"} {
+		if _, err := parseRetentionPeriod(s); err == nil {
+			t.Errorf("parseRetentionPeriod(%q) must fail", s)
+		}
+	}
+}
+
+func TestCheckStartTimestamp(t *testing.T) {
+	earliest := mustParseTime("2022-12-04T18:00:00Z")
+	newer := earliest.Add(time.Minute)
+	older := earliest.Add(-time.Minute)
+
+	for _, clamp := range []bool{false, true} {
+		got, err := checkStartTimestamp(newer, earliest, "1d", clamp)
+		if err != nil || !got.Equal(newer) {
+			t.Errorf("checkStartTimestamp of the readable start = %v, %v, but want %v", got, err, newer)
+		}
+		got, err = checkStartTimestamp(earliest, earliest, "1d", clamp)
+		if err != nil || !got.Equal(earliest) {
+			t.Errorf("checkStartTimestamp of the earliest start = %v, %v, but want %v", got, err, earliest)
+		}
+	}
+
+	if _, err := checkStartTimestamp(older, earliest, "1d", false); !errors.Is(err, ErrStartBeforeRetention) {
+		t.Errorf("checkStartTimestamp of the old start must fail with ErrStartBeforeRetention, but got %v", err)
+	}
+	got, err := checkStartTimestamp(older, earliest, "1d", true)
+	if want := earliest.Add(clampMargin); err != nil || !got.Equal(want) {
+		t.Errorf("checkStartTimestamp of the old start with clamp = %v, %v, but want %v", got, err, want)
+	}
+}
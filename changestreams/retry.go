@@ -0,0 +1,146 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/grpc/codes"
+)
+
+// Defaults of RetryPolicy.
+const (
+	DefaultRetryInitialBackoff = time.Second
+	DefaultRetryMaxBackoff     = 32 * time.Second
+	DefaultRetryBudgetWindow   = time.Minute
+)
+
+// ErrRetryBudgetExhausted is returned by Read when the retries of the partition queries exceed the budget of
+// RetryPolicy, e.g. on widespread persistent failures.
+var ErrRetryBudgetExhausted = errors.New("retry budget is exhausted")
+
+// RetryPolicy is the retry policy of the partition queries failed by transient errors, e.g. network errors.
+//
+// A retried query resumes from the timestamp of the last record read from the partition,
+// skipping the data change records of the timestamp already read.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per partition query, including the first one.
+	// Zero or one disables the retries.
+	MaxAttempts int
+	// InitialBackoff is the wait before the first retry, doubled for each retry. Defaults to DefaultRetryInitialBackoff.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum wait between the retries. Defaults to DefaultRetryMaxBackoff.
+	MaxBackoff time.Duration
+	// Budget is the maximum number of the retries of all the partitions of the reader within BudgetWindow, or zero
+	// for no budget. If a retry exceeds it, Read fails with ErrRetryBudgetExhausted instead of every partition
+	// retrying independently.
+	Budget int
+	// BudgetWindow is the sliding window of Budget. Defaults to DefaultRetryBudgetWindow.
+	BudgetWindow time.Duration
+	// OnRetry, if set, is called before each retry, e.g. to count the retries or to audit them.
+	OnRetry func(event *RetryEvent)
+}
+
+// RetryEvent is the retry of a failed partition query.
+type RetryEvent struct {
+	StreamID       string
+	PartitionToken string
+	// Attempt is the failed attempt, starting from 1.
+	Attempt int
+	// Err is the error of the failed attempt.
+	Err     error
+	Backoff time.Duration
+	// ResumeTimestamp is the timestamp the retried query starts from.
+	ResumeTimestamp time.Time
+}
+
+// backoff returns the wait before the retry following the attempt.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultRetryInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultRetryMaxBackoff
+	}
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// shouldRetry reports whether the query failed at the attempt should be retried.
+func (p RetryPolicy) shouldRetry(ctx context.Context, err error, attempt int) bool {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return false
+	}
+	switch spanner.ErrCode(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryBudget limits the retries of all the partitions within the sliding window, as a circuit breaker.
+type retryBudget struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+	// retries are the times of the retries within the window, in order.
+	retries []time.Time
+	mu      sync.Mutex
+}
+
+// newRetryBudget returns the budget of the policy, or nil if the policy has no budget.
+func newRetryBudget(p RetryPolicy) *retryBudget {
+	if p.Budget <= 0 {
+		return nil
+	}
+	window := p.BudgetWindow
+	if window <= 0 {
+		window = DefaultRetryBudgetWindow
+	}
+	return &retryBudget{limit: p.Budget, window: window, now: time.Now}
+}
+
+// take takes a retry from the budget, or returns ErrRetryBudgetExhausted with err if the budget is exhausted.
+func (b *retryBudget) take(err error) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	expired := 0
+	for expired < len(b.retries) && !b.retries[expired].After(now.Add(-b.window)) {
+		expired++
+	}
+	b.retries = b.retries[expired:]
+	if len(b.retries) >= b.limit {
+		return fmt.Errorf("%w by %d retries within %s: %w", ErrRetryBudgetExhausted, len(b.retries), b.window, err)
+	}
+	b.retries = append(b.retries, now)
+	return nil
+}
@@ -0,0 +1,169 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		desc    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{
+			desc:    "first retry",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+			attempt: 1,
+			want:    100 * time.Millisecond,
+		},
+		{
+			desc:    "doubled",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+			attempt: 3,
+			want:    400 * time.Millisecond,
+		},
+		{
+			desc:    "capped",
+			policy:  RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second},
+			attempt: 10,
+			want:    time.Second,
+		},
+		{
+			desc:    "defaults",
+			policy:  RetryPolicy{},
+			attempt: 100,
+			want:    DefaultRetryMaxBackoff,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.policy.backoff(test.attempt); got != test.want {
+				t.Errorf("backoff(%d) = %v, want %v", test.attempt, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		desc    string
+		ctx     context.Context
+		policy  RetryPolicy
+		err     error
+		attempt int
+		want    bool
+	}{
+		{
+			desc:    "unavailable",
+			ctx:     context.Background(),
+			policy:  RetryPolicy{MaxAttempts: 3},
+			err:     status.Error(codes.Unavailable, "unavailable"),
+			attempt: 2,
+			want:    true,
+		},
+		{
+			desc:    "attempts exhausted",
+			ctx:     context.Background(),
+			policy:  RetryPolicy{MaxAttempts: 3},
+			err:     status.Error(codes.Unavailable, "unavailable"),
+			attempt: 3,
+			want:    false,
+		},
+		{
+			desc:    "disabled",
+			ctx:     context.Background(),
+			policy:  RetryPolicy{},
+			err:     status.Error(codes.Unavailable, "unavailable"),
+			attempt: 1,
+			want:    false,
+		},
+		{
+			desc:    "permanent error",
+			ctx:     context.Background(),
+			policy:  RetryPolicy{MaxAttempts: 3},
+			err:     status.Error(codes.PermissionDenied, "permission denied"),
+			attempt: 1,
+			want:    false,
+		},
+		{
+			desc:    "non-gRPC error",
+			ctx:     context.Background(),
+			policy:  RetryPolicy{MaxAttempts: 3},
+			err:     errors.New("error"),
+			attempt: 1,
+			want:    false,
+		},
+		{
+			desc:    "context canceled",
+			ctx:     canceled,
+			policy:  RetryPolicy{MaxAttempts: 3},
+			err:     status.Error(codes.Unavailable, "unavailable"),
+			attempt: 1,
+			want:    false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := test.policy.shouldRetry(test.ctx, test.err, test.attempt); got != test.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	if b := newRetryBudget(RetryPolicy{MaxAttempts: 3}); b != nil {
+		t.Errorf("newRetryBudget without Budget must be nil")
+	}
+
+	now := mustParseTime("2022-12-04T18:00:00Z")
+	b := newRetryBudget(RetryPolicy{Budget: 2, BudgetWindow: time.Minute})
+	b.now = func() time.Time { return now }
+	queryErr := status.Error(codes.Unavailable, "unavailable")
+
+	for i := 0; i < 2; i++ {
+		if err := b.take(queryErr); err != nil {
+			t.Fatalf("take %d error: %v", i, err)
+		}
+		now = now.Add(10 * time.Second)
+	}
+	err := b.take(queryErr)
+	if !errors.Is(err, ErrRetryBudgetExhausted) || !errors.Is(err, queryErr) {
+		t.Errorf("take over the budget must fail with ErrRetryBudgetExhausted and the error, but got %v", err)
+	}
+
+	// The first retry leaves the window.
+	now = now.Add(41 * time.Second)
+	if err := b.take(queryErr); err != nil {
+		t.Errorf("take after the window error: %v", err)
+	}
+	if err := b.take(queryErr); !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Errorf("take over the budget must fail with ErrRetryBudgetExhausted, but got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// partition is a partition ready to be read.
+type partition struct {
+	token          string
+	startTimestamp time.Time
+}
+
+// scheduler reads the ready partitions in its queue by a pool of workers. If maxWorkers is positive, the number of the
+// workers is bounded by it, and the other partitions wait in the queue for a worker to finish its partition. Otherwise,
+// a worker is started whenever no worker is idle.
+type scheduler struct {
+	maxWorkers int
+	read       func(ctx context.Context, p *partition) error
+	// If onQueue is set, it's called with the change of the number of the partitions in the queue.
+	onQueue func(delta int64)
+	// If onWait is set, it's called when a partition is queued while all the workers are busy, so it waits for one of
+	// them to finish its partition.
+	onWait func(p *partition)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	queue  []*partition
+	// workers is the number of the started workers, of which idle are waiting for a partition.
+	workers int
+	idle    int
+	// pending is the number of the partitions queued or being read.
+	pending int
+	done    bool
+	err     error
+	mu      sync.Mutex
+	cond    *sync.Cond
+}
+
+func newScheduler(maxWorkers int, read func(ctx context.Context, p *partition) error) *scheduler {
+	s := &scheduler{maxWorkers: maxWorkers, read: read}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// run reads the root partition and the partitions scheduled while reading, until all of them finish or any of them
+// fails, in which case the others are canceled and the first error is returned.
+func (s *scheduler) run(ctx context.Context, root *partition) error {
+	s.mu.Lock()
+	s.ctx, s.cancel = context.WithCancel(ctx)
+	s.mu.Unlock()
+	defer s.cancel()
+
+	s.schedule(root)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for !s.done || s.workers > 0 {
+		s.cond.Wait()
+	}
+	return s.err
+}
+
+// schedule queues the partition to be read by a worker.
+func (s *scheduler) schedule(p *partition) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return
+	}
+	s.queue = append(s.queue, p)
+	s.pending++
+	if s.onQueue != nil {
+		s.onQueue(1)
+	}
+	if s.idle > 0 {
+		s.cond.Broadcast()
+	}
+	// The idle workers don't leave idle until they get the lock, so they may be woken already for the partitions queued
+	// before this one, which would wait forever if the others never finish.
+	if len(s.queue) <= s.idle {
+		return
+	}
+	if s.maxWorkers <= 0 || s.workers < s.maxWorkers {
+		s.workers++
+		go s.work()
+		return
+	}
+	if s.onWait != nil {
+		s.onWait(p)
+	}
+}
+
+func (s *scheduler) work() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer func() {
+		s.workers--
+		s.cond.Broadcast()
+	}()
+
+	for {
+		for len(s.queue) == 0 && !s.done {
+			s.idle++
+			s.cond.Wait()
+			s.idle--
+		}
+		if s.done {
+			return
+		}
+		p := s.queue[0]
+		s.queue[0] = nil
+		s.queue = s.queue[1:]
+		if s.onQueue != nil {
+			s.onQueue(-1)
+		}
+
+		s.mu.Unlock()
+		err := s.read(s.ctx, p)
+		s.mu.Lock()
+
+		s.pending--
+		if err != nil && s.err == nil {
+			s.err = err
+			s.finish()
+		}
+		if s.pending == 0 {
+			s.finish()
+		}
+	}
+}
+
+// finish stops the workers, and cancels the partitions being read.
+func (s *scheduler) finish() {
+	if s.done {
+		return
+	}
+	s.done = true
+	s.cancel()
+	if s.onQueue != nil && len(s.queue) > 0 {
+		s.onQueue(-int64(len(s.queue)))
+	}
+	s.queue = nil
+	s.cond.Broadcast()
+}
@@ -0,0 +1,179 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScheduler(t *testing.T) {
+	children := map[string][]string{
+		"":  {"a", "b", "c"},
+		"a": {"d"},
+		"b": {"e", "f"},
+	}
+	for _, maxWorkers := range []int{0, 1, 2} {
+		var s *scheduler
+		var read []string
+		var running, maxRunning int
+		var mu sync.Mutex
+		s = newScheduler(maxWorkers, func(ctx context.Context, p *partition) error {
+			mu.Lock()
+			read = append(read, p.token)
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			for _, child := range children[p.token] {
+				s.schedule(&partition{token: child})
+			}
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+		var queued int64
+		s.onQueue = func(delta int64) { queued += delta }
+
+		if err := s.run(context.Background(), &partition{}); err != nil {
+			t.Fatalf("run error: %v", err)
+		}
+		sort.Strings(read)
+		if diff := cmp.Diff(read, []string{"", "a", "b", "c", "d", "e", "f"}); diff != "" {
+			t.Errorf("read partitions have diff = %v", diff)
+		}
+		if maxWorkers > 0 && maxRunning > maxWorkers {
+			t.Errorf("%d partitions are read concurrently by %d workers", maxRunning, maxWorkers)
+		}
+		if queued != 0 {
+			t.Errorf("queue must be empty, but got %d", queued)
+		}
+		if s.workers != 0 {
+			t.Errorf("workers must finish, but got %d", s.workers)
+		}
+	}
+}
+
+func TestSchedulerError(t *testing.T) {
+	errRead := errors.New("read error")
+	var s *scheduler
+	s = newScheduler(2, func(ctx context.Context, p *partition) error {
+		switch p.token {
+		case "":
+			s.schedule(&partition{token: "blocked"})
+			s.schedule(&partition{token: "failed"})
+			// Waits in the queue until the scheduler finishes.
+			s.schedule(&partition{token: "queued"})
+			return nil
+		case "blocked":
+			<-ctx.Done()
+			return ctx.Err()
+		case "failed":
+			return errRead
+		default:
+			t.Errorf("partition %q must not be read", p.token)
+			return nil
+		}
+	})
+	if err := s.run(context.Background(), &partition{}); !errors.Is(err, errRead) {
+		t.Errorf("run must fail with the first error, but got %v", err)
+	}
+}
+
+func TestSchedulerWait(t *testing.T) {
+	var s *scheduler
+	s = newScheduler(1, func(ctx context.Context, p *partition) error {
+		if p.token == "" {
+			s.schedule(&partition{token: "a"})
+			s.schedule(&partition{token: "b"})
+		}
+		return nil
+	})
+	var waited []string
+	s.onWait = func(p *partition) { waited = append(waited, p.token) }
+
+	if err := s.run(context.Background(), &partition{}); err != nil {
+		t.Fatalf("run error: %v", err)
+	}
+	// The only worker is busy reading the root partition while its children are queued.
+	if diff := cmp.Diff(waited, []string{"a", "b"}); diff != "" {
+		t.Errorf("waited partitions have diff = %v", diff)
+	}
+}
+
+func TestSchedulerIdleWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan string)
+	var s *scheduler
+	s = newScheduler(0, func(ctx context.Context, p *partition) error {
+		switch p.token {
+		case "":
+			s.schedule(&partition{token: "finished"})
+			// Waits for the worker of the finished partition to be idle.
+			for {
+				s.mu.Lock()
+				idle := s.idle
+				s.mu.Unlock()
+				if idle > 0 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			// The children are never finished like without the end timestamp, so each of them needs its own worker.
+			for _, token := range []string{"a", "b", "c"} {
+				s.schedule(&partition{token: token})
+			}
+		case "finished":
+			return nil
+		default:
+			started <- p.token
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	errc := make(chan error, 1)
+	go func() { errc <- s.run(ctx, &partition{}) }()
+
+	var got []string
+	timeout := time.After(time.Second)
+	for len(got) < 3 {
+		select {
+		case token := <-started:
+			got = append(got, token)
+		case <-timeout:
+			t.Fatalf("partitions must be read concurrently, but only %v started", got)
+		}
+	}
+	sort.Strings(got)
+	if diff := cmp.Diff(got, []string{"a", "b", "c"}); diff != "" {
+		t.Errorf("started partitions have diff = %v", diff)
+	}
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Errorf("run must fail with the cancellation, but got %v", err)
+	}
+}
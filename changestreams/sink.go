@@ -0,0 +1,127 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Sink is the destination of the records read from the change stream.
+type Sink interface {
+	// Open prepares the sink. It is called once before any Write.
+	// The context is only for opening, and must not be retained by the sink.
+	Open(ctx context.Context) error
+	// Write writes the result. It may be called concurrently from multiple partitions.
+	Write(result *ReadResult) error
+	// Flush writes out the buffered records, if any.
+	Flush() error
+	// Close releases the resources of the sink. It is called once after the last Flush.
+	Close() error
+}
+
+// SinkParams is the parameters for creating a sink. A key may have multiple values.
+type SinkParams map[string][]string
+
+// Get returns the first value of the key, or an empty string if the key doesn't exist.
+func (p SinkParams) Get(key string) string {
+	if values := p[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set replaces the values of the key with the value.
+func (p SinkParams) Set(key, value string) {
+	p[key] = []string{value}
+}
+
+// Add appends the value to the key.
+func (p SinkParams) Add(key, value string) {
+	p[key] = append(p[key], value)
+}
+
+// SinkFactory creates a new sink from the parameters.
+type SinkFactory func(params SinkParams) (Sink, error)
+
+var (
+	sinkFactories   = make(map[string]SinkFactory)
+	sinkFactoriesMu sync.RWMutex
+)
+
+// RegisterSink makes a sink available by the name. It is intended to be called from the init function of the package
+// implementing the sink. If RegisterSink is called twice with the same name or the factory is nil, it panics.
+func RegisterSink(name string, factory SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+
+	if factory == nil {
+		panic("changestreams: RegisterSink factory is nil")
+	}
+	if _, ok := sinkFactories[name]; ok {
+		panic("changestreams: RegisterSink called twice for sink " + name)
+	}
+	sinkFactories[name] = factory
+}
+
+// NewSink creates a new sink registered by the name.
+func NewSink(name string, params SinkParams) (Sink, error) {
+	sinkFactoriesMu.RLock()
+	factory, ok := sinkFactories[name]
+	sinkFactoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown sink: %q", name)
+	}
+	if params == nil {
+		params = SinkParams{}
+	}
+	return factory(params)
+}
+
+// Sinks returns the sorted names of the registered sinks.
+func Sinks() []string {
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+
+	names := make([]string, 0, len(sinkFactories))
+	for name := range sinkFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ReadToSink opens the sink, reads the change stream into it, and then flushes and closes it.
+//
+// The sink is flushed and closed even if reading fails, so that the records written so far are not lost.
+func (r *Reader) ReadToSink(ctx context.Context, sink Sink) error {
+	if err := sink.Open(ctx); err != nil {
+		return fmt.Errorf("failed to open sink: %w", err)
+	}
+
+	err := r.Read(ctx, sink.Write)
+	if flushErr := sink.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to flush sink: %w", flushErr)
+	}
+	if closeErr := sink.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close sink: %w", closeErr)
+	}
+	return err
+}
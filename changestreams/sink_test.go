@@ -0,0 +1,70 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type testSink struct {
+	params SinkParams
+}
+
+func (s *testSink) Open(ctx context.Context) error { return nil }
+func (s *testSink) Write(result *ReadResult) error { return nil }
+func (s *testSink) Flush() error                   { return nil }
+func (s *testSink) Close() error                   { return nil }
+
+func TestSinkRegistry(t *testing.T) {
+	RegisterSink("test", func(params SinkParams) (Sink, error) {
+		return &testSink{params: params}, nil
+	})
+
+	if diff := cmp.Diff(Sinks(), []string{"test"}); diff != "" {
+		t.Errorf("Sinks has diff = %v", diff)
+	}
+
+	params := SinkParams{}
+	params.Set("path", "a")
+	params.Add("header", "X-A: 1")
+	params.Add("header", "X-B: 2")
+	sink, err := NewSink("test", params)
+	if err != nil {
+		t.Fatalf("NewSink error: %v", err)
+	}
+	got := sink.(*testSink).params
+	if got.Get("path") != "a" || got.Get("missing") != "" {
+		t.Errorf("unexpected params: %v", got)
+	}
+	if diff := cmp.Diff(got["header"], []string{"X-A: 1", "X-B: 2"}); diff != "" {
+		t.Errorf("params has diff = %v", diff)
+	}
+
+	if _, err := NewSink("unknown", nil); err == nil {
+		t.Errorf("NewSink must fail for an unknown sink")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("RegisterSink must panic for a duplicate name")
+		}
+	}()
+	RegisterSink("test", func(params SinkParams) (Sink, error) { return nil, nil })
+}
@@ -0,0 +1,85 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+
+// Attribute keys of the spans.
+const (
+	attributeStreamID       = attribute.Key("changestreams.stream_id")
+	attributePartitionToken = attribute.Key("changestreams.partition_token")
+	attributeAttempt        = attribute.Key("changestreams.attempt")
+	attributeTableName      = attribute.Key("changestreams.table_name")
+	attributeModType        = attribute.Key("changestreams.mod_type")
+	attributeTransactionID  = attribute.Key("changestreams.server_transaction_id")
+)
+
+// newTracer returns the tracer of the provider, or of the global provider if it's nil.
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(instrumentationName)
+}
+
+// endSpan ends the span, recording the error if any. ErrStop is not an error.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrStop) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// consume passes the result to f in a span, and records the metrics. The spans of the data change records start at the commit timestamps, and
+// end after f returns, to trace the latency from the commits to the delivery, e.g. to the sink.
+func (r *Reader) consume(ctx context.Context, result *ReadResult, f func(result *ReadResult) error) error {
+	var recordSpans []trace.Span
+	for _, changeRecord := range result.ChangeRecords {
+		for _, dataChangeRecord := range changeRecord.DataChangeRecords {
+			_, span := r.tracer.Start(ctx, "changestreams.DataChangeRecord",
+				trace.WithTimestamp(dataChangeRecord.CommitTimestamp),
+				trace.WithAttributes(
+					attributeTableName.String(dataChangeRecord.TableName),
+					attributeModType.String(dataChangeRecord.ModType),
+					attributeTransactionID.String(dataChangeRecord.ServerTransactionID),
+				))
+			recordSpans = append(recordSpans, span)
+		}
+	}
+
+	_, span := r.tracer.Start(ctx, "changestreams.Consume")
+	start := time.Now()
+	err := f(result)
+	r.metrics.recordResult(ctx, r.streamID, result, time.Since(start))
+	endSpan(span, err)
+	for _, recordSpan := range recordSpans {
+		endSpan(recordSpan, err)
+	}
+	return err
+}
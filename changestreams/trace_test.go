@@ -0,0 +1,94 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric/noop"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestReaderConsume(t *testing.T) {
+	commitTimestamp := time.Date(2022, 12, 4, 18, 0, 0, 0, time.UTC)
+	result := &ReadResult{
+		ChangeRecords: []*ChangeRecord{
+			{DataChangeRecords: []*DataChangeRecord{{CommitTimestamp: commitTimestamp, TableName: "Players", ModType: "INSERT"}}},
+		},
+	}
+
+	tests := []struct {
+		desc       string
+		err        error
+		wantStatus codes.Code
+	}{
+		{
+			desc:       "success",
+			wantStatus: codes.Unset,
+		},
+		{
+			desc:       "stop",
+			err:        ErrStop,
+			wantStatus: codes.Unset,
+		},
+		{
+			desc:       "error",
+			err:        errors.New("failed to write"),
+			wantStatus: codes.Error,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			metrics, err := newReaderMetrics(noop.NewMeterProvider())
+			if err != nil {
+				t.Fatalf("newReaderMetrics error: %v", err)
+			}
+			r := &Reader{
+				tracer:  newTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))),
+				metrics: metrics,
+			}
+
+			err = r.consume(context.Background(), result, func(result *ReadResult) error {
+				return test.err
+			})
+			if err != test.err {
+				t.Errorf("consume error = %v, want %v", err, test.err)
+			}
+
+			var names []string
+			for _, span := range recorder.Ended() {
+				names = append(names, span.Name())
+				if span.Status().Code != test.wantStatus {
+					t.Errorf("span %s has status %v, want %v", span.Name(), span.Status().Code, test.wantStatus)
+				}
+				if span.Name() == "changestreams.DataChangeRecord" && !span.StartTime().Equal(commitTimestamp) {
+					t.Errorf("span %s starts at %v, want the commit timestamp", span.Name(), span.StartTime())
+				}
+			}
+			if diff := cmp.Diff(names, []string{"changestreams.Consume", "changestreams.DataChangeRecord"}); diff != "" {
+				t.Errorf("spans have diff = %v", diff)
+			}
+		})
+	}
+}
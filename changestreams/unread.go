@@ -0,0 +1,119 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrIncompleteRead is returned by Read with EndTimestamp when some partitions were not read through the end timestamp.
+var ErrIncompleteRead = errors.New("some partitions were not read through the end timestamp")
+
+// UnreadPartition is a child partition announced by its parents but not read, e.g. as the reading stopped before all
+// of its parents finished, so the data change records of the partition are missing from the read.
+type UnreadPartition struct {
+	StreamID              string    `json:"stream_id"`
+	Token                 string    `json:"token"`
+	StartTimestamp        time.Time `json:"start_timestamp"`
+	ParentPartitionTokens []string  `json:"parent_partition_tokens"`
+	// UnfinishedParents are the parent partitions not finished, for which the partition waited. If empty, the
+	// partition was waiting in the queue for a worker.
+	UnfinishedParents []string `json:"unfinished_parents"`
+}
+
+// announceChild records the child partition announced by a finished parent in announced, until it starts to be read.
+func (r *Reader) announceChild(partition *ChildPartition, startTimestamp time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.states[partition.Token]; ok {
+		return
+	}
+	r.announced[partition.Token] = &UnreadPartition{
+		Token:                 partition.Token,
+		StartTimestamp:        startTimestamp,
+		ParentPartitionTokens: partition.ParentPartitionTokens,
+	}
+}
+
+// UnreadPartitions returns the child partitions announced but not read, ordered by the start timestamp. It's called
+// after Read returns, to tell whether the read may be incomplete.
+func (r *Reader) UnreadPartitions() []UnreadPartition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var partitions []UnreadPartition
+	for token, p := range r.announced {
+		if _, ok := r.states[token]; ok {
+			continue
+		}
+		unread := *p
+		unread.StreamID = r.streamID
+		unread.UnfinishedParents = nil
+		for _, parent := range p.ParentPartitionTokens {
+			if r.states[parent] != partitionStateFinished {
+				unread.UnfinishedParents = append(unread.UnfinishedParents, parent)
+			}
+		}
+		partitions = append(partitions, unread)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		if !partitions[i].StartTimestamp.Equal(partitions[j].StartTimestamp) {
+			return partitions[i].StartTimestamp.Before(partitions[j].StartTimestamp)
+		}
+		return partitions[i].Token < partitions[j].Token
+	})
+	return partitions
+}
+
+// unfinishedPartitions returns the partitions started but not finished, in the order of the tokens.
+func (r *Reader) unfinishedPartitions() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var tokens []string
+	for token, state := range r.states {
+		if state != partitionStateFinished {
+			tokens = append(tokens, token)
+		}
+	}
+	sort.Strings(tokens)
+	return tokens
+}
+
+// checkComplete returns ErrIncompleteRead with the partitions not read through the end timestamp, if any.
+func (r *Reader) checkComplete() error {
+	var reasons []string
+	if unfinished := r.unfinishedPartitions(); len(unfinished) > 0 {
+		reasons = append(reasons, fmt.Sprintf("unfinished partitions %q", unfinished))
+	}
+	if unread := r.UnreadPartitions(); len(unread) > 0 {
+		tokens := make([]string, len(unread))
+		for i, p := range unread {
+			tokens[i] = p.Token
+		}
+		reasons = append(reasons, fmt.Sprintf("unread partitions %q", tokens))
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrIncompleteRead, strings.Join(reasons, ", "))
+}
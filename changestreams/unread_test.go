@@ -0,0 +1,74 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package changestreams
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnreadPartitions(t *testing.T) {
+	r := &Reader{streamID: "s", states: make(map[string]partitionState), announced: make(map[string]*UnreadPartition)}
+	t1 := mustParseTime("2022-12-04T18:00:00Z")
+	t2 := mustParseTime("2022-12-04T18:01:00Z")
+
+	r.markStateReading("a")
+	r.markStateFinished("a")
+	r.markStateReading("b")
+	// "merged" waits for "b", "queued" waits for a worker, and "started" is being read.
+	r.announceChild(&ChildPartition{Token: "merged", ParentPartitionTokens: []string{"a", "b"}}, t2)
+	r.announceChild(&ChildPartition{Token: "queued", ParentPartitionTokens: []string{"a"}}, t1)
+	r.announceChild(&ChildPartition{Token: "started", ParentPartitionTokens: []string{"a"}}, t1)
+	r.markStateReading("started")
+	// The partition already started isn't announced again by another parent.
+	r.announceChild(&ChildPartition{Token: "started", ParentPartitionTokens: []string{"a"}}, t1)
+
+	expected := []UnreadPartition{
+		{StreamID: "s", Token: "queued", StartTimestamp: t1, ParentPartitionTokens: []string{"a"}},
+		{StreamID: "s", Token: "merged", StartTimestamp: t2, ParentPartitionTokens: []string{"a", "b"}, UnfinishedParents: []string{"b"}},
+	}
+	if diff := cmp.Diff(r.UnreadPartitions(), expected); diff != "" {
+		t.Errorf("UnreadPartitions has diff = %v", diff)
+	}
+}
+
+func TestCheckComplete(t *testing.T) {
+	r := &Reader{states: make(map[string]partitionState), announced: make(map[string]*UnreadPartition)}
+	t1 := mustParseTime("2022-12-04T18:00:00Z")
+
+	// The initial query and the partitions all finished.
+	for _, token := range []string{"", "a", "b"} {
+		r.markStateReading(token)
+		r.markStateFinished(token)
+	}
+	r.announceChild(&ChildPartition{Token: "a", ParentPartitionTokens: []string{""}}, t1)
+	if err := r.checkComplete(); err != nil {
+		t.Errorf("checkComplete of the finished partitions error: %v", err)
+	}
+
+	r.markStateReading("c")
+	r.announceChild(&ChildPartition{Token: "d", ParentPartitionTokens: []string{"b", "c"}}, t1)
+	err := r.checkComplete()
+	if !errors.Is(err, ErrIncompleteRead) {
+		t.Fatalf("checkComplete must fail with ErrIncompleteRead, but got %v", err)
+	}
+	if expected := `some partitions were not read through the end timestamp: unfinished partitions ["c"], unread partitions ["d"]`; err.Error() != expected {
+		t.Errorf("checkComplete error = %q, but want %q", err.Error(), expected)
+	}
+}
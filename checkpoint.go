@@ -0,0 +1,257 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// defaultCheckpointInterval is the interval of saving the checkpoint while reading.
+const defaultCheckpointInterval = 10 * time.Second
+
+// checkpoint is the progress of the streams persisted by --checkpoint option.
+type checkpoint struct {
+	Streams map[string]*streamCheckpoint `json:"streams"`
+}
+
+// streamCheckpoint is the progress of a stream.
+type streamCheckpoint struct {
+	// Timestamp is the timestamp to resume from, which is the last timestamp of the slowest partition.
+	// The records before it have been written to the sink.
+	Timestamp time.Time `json:"timestamp"`
+	// Partitions is the last timestamp of the active partitions.
+	Partitions map[string]time.Time `json:"partitions"`
+}
+
+// loadCheckpoint loads the checkpoint file. If the file doesn't exist, it returns an empty checkpoint.
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{Streams: make(map[string]*streamCheckpoint)}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	if c.Streams == nil {
+		c.Streams = make(map[string]*streamCheckpoint)
+	}
+	return c, nil
+}
+
+// resumeTimestamps returns the timestamps to resume the streams from.
+func (c *checkpoint) resumeTimestamps() map[string]time.Time {
+	timestamps := make(map[string]time.Time)
+	for streamID, sc := range c.Streams {
+		if !sc.Timestamp.IsZero() {
+			timestamps[streamID] = sc.Timestamp
+		}
+	}
+	return timestamps
+}
+
+// save writes the checkpoint to a temporary file, and renames it to the path, so that a crash never leaves a
+// partial checkpoint.
+func (c *checkpoint) save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// checkpointSink tracks the progress of the partitions written to the underlying sink, and saves it to the checkpoint
+// file periodically and on Close. The underlying sink is flushed before saving, so the records before the saved
+// timestamps are never lost, while the records after them may be written again after resuming.
+type checkpointSink struct {
+	changestreams.Sink
+	path     string
+	interval time.Duration
+	state    *checkpoint
+	// err is the error of the periodic save, returned from the next Write.
+	err  error
+	done chan struct{}
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+}
+
+func newCheckpointSink(sink changestreams.Sink, path string, interval time.Duration, state *checkpoint) *checkpointSink {
+	// The partitions are read again from the resumed timestamps, with possibly different tokens.
+	for _, sc := range state.Streams {
+		sc.Partitions = make(map[string]time.Time)
+	}
+	return &checkpointSink{
+		Sink:     sink,
+		path:     path,
+		interval: interval,
+		state:    state,
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *checkpointSink) Open(ctx context.Context) error {
+	if err := s.Sink.Open(ctx); err != nil {
+		return err
+	}
+	if s.interval > 0 {
+		s.wg.Add(1)
+		go s.savePeriodically()
+	}
+	return nil
+}
+
+func (s *checkpointSink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.Sink.Write(result); err != nil {
+		return err
+	}
+
+	sc, ok := s.state.Streams[result.StreamID]
+	if !ok {
+		sc = &streamCheckpoint{Partitions: make(map[string]time.Time)}
+		s.state.Streams[result.StreamID] = sc
+	}
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			sc.touchPartition(result.PartitionToken, r.CommitTimestamp)
+		}
+		for _, r := range changeRecord.HeartbeatRecords {
+			sc.touchPartition(result.PartitionToken, r.Timestamp)
+		}
+		for _, r := range changeRecord.ChildPartitionsRecords {
+			// The partition finishes after returning the child partitions, which start from the timestamp.
+			delete(sc.Partitions, result.PartitionToken)
+			for _, child := range r.ChildPartitions {
+				if _, ok := sc.Partitions[child.Token]; !ok {
+					sc.Partitions[child.Token] = r.StartTimestamp
+				}
+			}
+		}
+	}
+	sc.updateTimestamp()
+	return nil
+}
+
+func (sc *streamCheckpoint) touchPartition(token string, ts time.Time) {
+	if token == "" {
+		// The initial query is not a partition.
+		return
+	}
+	if ts.After(sc.Partitions[token]) {
+		sc.Partitions[token] = ts
+	}
+}
+
+// updateTimestamp updates the timestamp to the slowest partition. It's kept if no partitions are active.
+func (sc *streamCheckpoint) updateTimestamp() {
+	var timestamp time.Time
+	for _, ts := range sc.Partitions {
+		if timestamp.IsZero() || ts.Before(timestamp) {
+			timestamp = ts
+		}
+	}
+	if !timestamp.IsZero() {
+		sc.Timestamp = timestamp
+	}
+}
+
+// Flush flushes the underlying sink, and saves the checkpoint.
+func (s *checkpointSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	return s.flush()
+}
+
+func (s *checkpointSink) flush() error {
+	if err := s.Sink.Flush(); err != nil {
+		return err
+	}
+	return s.state.save(s.path)
+}
+
+// Close closes the underlying sink, and saves the checkpoint if it's closed successfully.
+func (s *checkpointSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.Sink.Close(); err != nil {
+		return err
+	}
+	if s.err != nil {
+		return s.err
+	}
+	return s.state.save(s.path)
+}
+
+func (s *checkpointSink) savePeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.err == nil {
+				s.err = s.flush()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
@@ -0,0 +1,130 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCheckpointSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	state, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint error: %v", err)
+	}
+	if diff := cmp.Diff(state.resumeTimestamps(), map[string]time.Time{}); diff != "" {
+		t.Errorf("resumeTimestamps of the missing file has diff = %v", diff)
+	}
+
+	recorder := &recordingSink{}
+	sink := newCheckpointSink(recorder, path, 0, state)
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+
+	childPartitions := func(token, ts string, children ...string) *changestreams.ReadResult {
+		r := &changestreams.ChildPartitionsRecord{StartTimestamp: mustParseTime(t, ts)}
+		for _, child := range children {
+			r.ChildPartitions = append(r.ChildPartitions, &changestreams.ChildPartition{Token: child})
+		}
+		return &changestreams.ReadResult{
+			StreamID:       "s",
+			PartitionToken: token,
+			ChangeRecords:  []*changestreams.ChangeRecord{{ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{r}}},
+		}
+	}
+	heartbeat := func(token, ts string) *changestreams.ReadResult {
+		return &changestreams.ReadResult{
+			StreamID:       "s",
+			PartitionToken: token,
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{HeartbeatRecords: []*changestreams.HeartbeatRecord{{Timestamp: mustParseTime(t, ts)}}},
+			},
+		}
+	}
+	data := newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:05:00Z", "PlayerId"))
+	data.StreamID = "s"
+	data.PartitionToken = "b"
+
+	for _, result := range []*changestreams.ReadResult{
+		childPartitions("", "2022-12-04T18:00:00Z", "a", "b"),
+		heartbeat("a", "2022-12-04T18:03:00Z"),
+		data,
+		// a is split into c and d.
+		childPartitions("a", "2022-12-04T18:04:00Z", "c", "d"),
+		heartbeat("c", "2022-12-04T18:06:00Z"),
+	} {
+		if err := sink.Write(result); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if diff := cmp.Diff(recorder.timestamps, []string{"18:05"}); diff != "" {
+		t.Errorf("written records have diff = %v", diff)
+	}
+
+	saved, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint error: %v", err)
+	}
+	expected := &checkpoint{
+		Streams: map[string]*streamCheckpoint{
+			"s": {
+				// d hasn't returned any records since it started.
+				Timestamp: mustParseTime(t, "2022-12-04T18:04:00Z"),
+				Partitions: map[string]time.Time{
+					"b": mustParseTime(t, "2022-12-04T18:05:00Z"),
+					"c": mustParseTime(t, "2022-12-04T18:06:00Z"),
+					"d": mustParseTime(t, "2022-12-04T18:04:00Z"),
+				},
+			},
+		},
+	}
+	if diff := cmp.Diff(saved, expected); diff != "" {
+		t.Errorf("checkpoint has diff = %v", diff)
+	}
+	if diff := cmp.Diff(saved.resumeTimestamps(), map[string]time.Time{"s": mustParseTime(t, "2022-12-04T18:04:00Z")}); diff != "" {
+		t.Errorf("resumeTimestamps has diff = %v", diff)
+	}
+
+	// The resumed sink forgets the partitions of the previous run, but keeps the timestamp until new ones are read.
+	sink = newCheckpointSink(&recordingSink{}, path, 0, saved)
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	resumed, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint error: %v", err)
+	}
+	if diff := cmp.Diff(resumed.resumeTimestamps(), map[string]time.Time{"s": mustParseTime(t, "2022-12-04T18:04:00Z")}); diff != "" {
+		t.Errorf("resumeTimestamps after resuming has diff = %v", diff)
+	}
+}
@@ -0,0 +1,119 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// cloudPlatformScope is the OAuth scope of the impersonated credentials.
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// clientConfig is the configuration of the Spanner client given by the options.
+type clientConfig struct {
+	// If emulatorHost is set, the client connects to the emulator with plaintext and no credentials.
+	emulatorHost string
+	// If endpoint is set, it overrides the Spanner API endpoint, e.g. a regional endpoint.
+	endpoint string
+	// If credentialsFile is set, the client uses the service account key file instead of the default credentials.
+	credentialsFile string
+	// If impersonateServiceAccount is set, the client impersonates the service account.
+	// It can be a comma separated delegation chain, whose last one is the target service account as with gcloud.
+	impersonateServiceAccount string
+	// If quotaProject is set, quota and billing are attributed to the project.
+	quotaProject string
+	// If grpcPoolSize is set, the client opens the number of gRPC connections instead of the default of the client.
+	grpcPoolSize int
+	// If keepaliveTime is set, the client pings the idle connections at the interval, so that the middleboxes don't drop
+	// them during long tails, and closes them if the pings aren't acknowledged within keepaliveTimeout.
+	keepaliveTime    time.Duration
+	keepaliveTimeout time.Duration
+}
+
+// sessionPoolConfig returns the session pool configuration to read the streams, or the default for the zero values.
+// Each partition query holds a session until it finishes, so maxOpened must not be less than the partitions read
+// concurrently. No sessions are prepared for writes, as the client only reads.
+func sessionPoolConfig(minOpened, maxOpened uint64) spanner.SessionPoolConfig {
+	config := spanner.DefaultSessionPoolConfig
+	config.WriteSessions = 0
+	if minOpened > 0 {
+		config.MinOpened = minOpened
+	}
+	if maxOpened > 0 {
+		config.MaxOpened = maxOpened
+	}
+	if config.MinOpened > config.MaxOpened {
+		config.MinOpened = config.MaxOpened
+	}
+	return config
+}
+
+// clientOptions returns the client options for the configuration.
+func clientOptions(ctx context.Context, config clientConfig) ([]option.ClientOption, error) {
+	var opts []option.ClientOption
+	if config.emulatorHost != "" {
+		opts = append(opts,
+			option.WithEndpoint(config.emulatorHost),
+			option.WithGRPCDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+			option.WithoutAuthentication(),
+		)
+	}
+	if config.endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.endpoint))
+	}
+	var credentialsOpts []option.ClientOption
+	if config.credentialsFile != "" {
+		credentialsOpts = append(credentialsOpts, option.WithCredentialsFile(config.credentialsFile))
+	}
+	if config.impersonateServiceAccount != "" {
+		accounts := strings.Split(config.impersonateServiceAccount, ",")
+		// The credentials file, if any, is used to impersonate the service account.
+		ts, err := impersonate.CredentialsTokenSource(ctx, impersonate.CredentialsConfig{
+			TargetPrincipal: accounts[len(accounts)-1],
+			Delegates:       accounts[:len(accounts)-1],
+			Scopes:          []string{cloudPlatformScope},
+		}, credentialsOpts...)
+		if err != nil {
+			return nil, err
+		}
+		credentialsOpts = []option.ClientOption{option.WithTokenSource(ts)}
+	}
+	opts = append(opts, credentialsOpts...)
+	if config.quotaProject != "" {
+		opts = append(opts, option.WithQuotaProject(config.quotaProject))
+	}
+	if config.grpcPoolSize > 0 {
+		opts = append(opts, option.WithGRPCConnectionPool(config.grpcPoolSize))
+	}
+	if config.keepaliveTime > 0 {
+		opts = append(opts, option.WithGRPCDialOption(grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                config.keepaliveTime,
+			Timeout:             config.keepaliveTimeout,
+			PermitWithoutStream: true,
+		})))
+	}
+	return opts, nil
+}
@@ -0,0 +1,66 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+func TestSessionPoolConfig(t *testing.T) {
+	for _, test := range []struct {
+		desc                     string
+		minOpened, maxOpened     uint64
+		expectedMin, expectedMax uint64
+	}{
+		{"default", 0, 0, spanner.DefaultSessionPoolConfig.MinOpened, spanner.DefaultSessionPoolConfig.MaxOpened},
+		{"both", 10, 1000, 10, 1000},
+		{"max less than the default min", 0, 50, 50, 50},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			config := sessionPoolConfig(test.minOpened, test.maxOpened)
+			if config.MinOpened != test.expectedMin || config.MaxOpened != test.expectedMax {
+				t.Errorf("sessions = %d to %d, expected %d to %d", config.MinOpened, config.MaxOpened, test.expectedMin, test.expectedMax)
+			}
+			if config.WriteSessions != 0 {
+				t.Errorf("no sessions must be prepared for writes, but got %v", config.WriteSessions)
+			}
+		})
+	}
+}
+
+func TestClientOptionsConnections(t *testing.T) {
+	opts, err := clientOptions(context.Background(), clientConfig{})
+	if err != nil {
+		t.Fatalf("clientOptions error: %v", err)
+	}
+	if len(opts) != 0 {
+		t.Errorf("no options must be given by default, but got %d", len(opts))
+	}
+
+	opts, err = clientOptions(context.Background(), clientConfig{grpcPoolSize: 8, keepaliveTime: time.Minute, keepaliveTimeout: 20 * time.Second})
+	if err != nil {
+		t.Fatalf("clientOptions error: %v", err)
+	}
+	// The connection pool and the keepalive dial option.
+	if len(opts) != 2 {
+		t.Errorf("2 options must be given, but got %d", len(opts))
+	}
+}
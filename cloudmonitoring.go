@@ -0,0 +1,218 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	monitoring "cloud.google.com/go/monitoring/apiv3/v2"
+	"cloud.google.com/go/monitoring/apiv3/v2/monitoringpb"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/api/metric"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// cloudMonitoringMetricPrefix is the prefix of the custom metric types.
+	cloudMonitoringMetricPrefix = "custom.googleapis.com/spanner_change_streams_tail/"
+	// defaultCloudMonitoringInterval is the default interval of exporting the metrics.
+	// Cloud Monitoring accepts a point of a time series at most every 5 seconds.
+	defaultCloudMonitoringInterval = time.Minute
+)
+
+// cloudMonitoringStream is the state of a stream exported to Cloud Monitoring.
+type cloudMonitoringStream struct {
+	// partitions is the last timestamp of the active partitions.
+	partitions      map[string]time.Time
+	records         int64
+	reportedRecords int64
+}
+
+// cloudMonitoringExporter exports the watermark lag, the throughput and the active partitions of each stream as Cloud
+// Monitoring custom metrics periodically, so that the alerting of Google Cloud works without running Prometheus.
+type cloudMonitoringExporter struct {
+	client    *monitoring.MetricClient
+	projectID string
+	database  string
+	interval  time.Duration
+	now       func() time.Time
+
+	streams    map[string]*cloudMonitoringStream
+	reportedAt time.Time
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+}
+
+// newCloudMonitoringExporter creates a new exporter writing the metrics to the project. The database labels the
+// metrics, in the form of "instance/database".
+func newCloudMonitoringExporter(ctx context.Context, projectID, database string, interval time.Duration, opts ...option.ClientOption) (*cloudMonitoringExporter, error) {
+	client, err := monitoring.NewMetricClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudMonitoringExporter{
+		client:    client,
+		projectID: projectID,
+		database:  database,
+		interval:  interval,
+		now:       time.Now,
+		streams:   make(map[string]*cloudMonitoringStream),
+	}, nil
+}
+
+// start starts exporting the metrics periodically until Close.
+func (e *cloudMonitoringExporter) start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.mu.Lock()
+	e.reportedAt = e.now()
+	e.mu.Unlock()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := e.export(ctx); err != nil && ctx.Err() == nil {
+					logger.Warn("Failed to export the metrics to Cloud Monitoring", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Close stops exporting the metrics and closes the client.
+func (e *cloudMonitoringExporter) Close() error {
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.wg.Wait()
+	return e.client.Close()
+}
+
+// observe updates the states of the streams by the result.
+func (e *cloudMonitoringExporter) observe(result *changestreams.ReadResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s, ok := e.streams[result.StreamID]
+	if !ok {
+		s = &cloudMonitoringStream{partitions: make(map[string]time.Time)}
+		e.streams[result.StreamID] = s
+	}
+	touch := func(ts time.Time) {
+		if result.PartitionToken != "" && ts.After(s.partitions[result.PartitionToken]) {
+			s.partitions[result.PartitionToken] = ts
+		}
+	}
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			s.records++
+			touch(r.CommitTimestamp)
+		}
+		for _, r := range changeRecord.HeartbeatRecords {
+			touch(r.Timestamp)
+		}
+		if len(changeRecord.ChildPartitionsRecords) > 0 {
+			// The partition finishes after returning the child partitions.
+			delete(s.partitions, result.PartitionToken)
+		}
+	}
+}
+
+func (e *cloudMonitoringExporter) export(ctx context.Context) error {
+	e.mu.Lock()
+	timeSeries := e.timeSeries()
+	e.mu.Unlock()
+
+	if len(timeSeries) == 0 {
+		return nil
+	}
+	return e.client.CreateTimeSeries(ctx, &monitoringpb.CreateTimeSeriesRequest{
+		Name:       fmt.Sprintf("projects/%s", e.projectID),
+		TimeSeries: timeSeries,
+	})
+}
+
+// timeSeries returns the points of the metrics of the streams at now, with the throughput since the last call.
+func (e *cloudMonitoringExporter) timeSeries() []*monitoringpb.TimeSeries {
+	now := e.now()
+	elapsed := now.Sub(e.reportedAt).Seconds()
+	e.reportedAt = now
+
+	streamIDs := make([]string, 0, len(e.streams))
+	for streamID := range e.streams {
+		streamIDs = append(streamIDs, streamID)
+	}
+	sort.Strings(streamIDs)
+
+	var timeSeries []*monitoringpb.TimeSeries
+	for _, streamID := range streamIDs {
+		s := e.streams[streamID]
+
+		var throughput float64
+		if elapsed > 0 {
+			throughput = float64(s.records-s.reportedRecords) / elapsed
+		}
+		s.reportedRecords = s.records
+		var watermark time.Time
+		for _, ts := range s.partitions {
+			if watermark.IsZero() || ts.Before(watermark) {
+				watermark = ts
+			}
+		}
+		var lag float64
+		if !watermark.IsZero() {
+			lag = now.Sub(watermark).Seconds()
+		}
+
+		point := func(name string, value *monitoringpb.TypedValue) *monitoringpb.TimeSeries {
+			return &monitoringpb.TimeSeries{
+				Metric: &metric.Metric{
+					Type:   cloudMonitoringMetricPrefix + name,
+					Labels: map[string]string{"database": e.database, "stream": streamID},
+				},
+				Resource: &monitoredres.MonitoredResource{
+					Type:   "global",
+					Labels: map[string]string{"project_id": e.projectID},
+				},
+				MetricKind: metric.MetricDescriptor_GAUGE,
+				Points: []*monitoringpb.Point{{
+					Interval: &monitoringpb.TimeInterval{EndTime: timestamppb.New(now)},
+					Value:    value,
+				}},
+			}
+		}
+		timeSeries = append(timeSeries,
+			point("watermark_lag", &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: lag}}),
+			point("throughput", &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_DoubleValue{DoubleValue: throughput}}),
+			point("active_partitions", &monitoringpb.TypedValue{Value: &monitoringpb.TypedValue_Int64Value{Int64Value: int64(len(s.partitions))}}),
+		)
+	}
+	return timeSeries
+}
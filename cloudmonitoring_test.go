@@ -0,0 +1,86 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCloudMonitoringExporterTimeSeries(t *testing.T) {
+	now := mustParseTime(t, "2022-12-04T18:00:00Z")
+	e := &cloudMonitoringExporter{
+		projectID:  "myproject",
+		database:   "myinstance/mydb",
+		now:        func() time.Time { return now },
+		streams:    make(map[string]*cloudMonitoringStream),
+		reportedAt: now,
+	}
+
+	data := func(token, ts string) *changestreams.ReadResult {
+		result := newTestReadResult(newTestDataChangeRecord(t, ts, "PlayerId"))
+		result.StreamID = "s"
+		result.PartitionToken = token
+		return result
+	}
+	for _, result := range []*changestreams.ReadResult{
+		data("a", "2022-12-04T18:00:05Z"),
+		data("a", "2022-12-04T18:00:08Z"),
+		data("b", "2022-12-04T18:00:06Z"),
+		data("c", "2022-12-04T18:00:07Z"),
+		{
+			StreamID:       "s",
+			PartitionToken: "c",
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{{}}},
+			},
+		},
+	} {
+		e.observe(result)
+	}
+	now = now.Add(10 * time.Second)
+
+	type point struct {
+		Type   string
+		Labels map[string]string
+		Value  float64
+	}
+	var got []point
+	for _, ts := range e.timeSeries() {
+		if ts.Resource.Labels["project_id"] != "myproject" {
+			t.Errorf("unexpected resource: %v", ts.Resource)
+		}
+		if !ts.Points[0].Interval.EndTime.AsTime().Equal(now) {
+			t.Errorf("unexpected interval: %v", ts.Points[0].Interval)
+		}
+		value := ts.Points[0].Value.GetDoubleValue() + float64(ts.Points[0].Value.GetInt64Value())
+		got = append(got, point{ts.Metric.Type, ts.Metric.Labels, value})
+	}
+	labels := map[string]string{"database": "myinstance/mydb", "stream": "s"}
+	expected := []point{
+		// b is the slowest partition after c finished.
+		{"custom.googleapis.com/spanner_change_streams_tail/watermark_lag", labels, 4},
+		{"custom.googleapis.com/spanner_change_streams_tail/throughput", labels, 0.4},
+		{"custom.googleapis.com/spanner_change_streams_tail/active_partitions", labels, 2},
+	}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("time series have diff = %v", diff)
+	}
+}
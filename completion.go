@@ -0,0 +1,403 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"cloud.google.com/go/spanner"
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	instance "cloud.google.com/go/spanner/admin/instance/apiv1"
+	"cloud.google.com/go/spanner/admin/instance/apiv1/instancepb"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"google.golang.org/api/iterator"
+)
+
+const (
+	commandCompletion = "completion"
+	// commandComplete is a hidden command called by the completion scripts to list the values of an option.
+	commandComplete = "__complete"
+)
+
+const (
+	shellBash = "bash"
+	shellZsh  = "zsh"
+	shellFish = "fish"
+)
+
+// completedFlags are the options whose values are listed by the __complete command.
+var completedFlags = map[string]bool{
+	"instance":           true,
+	"database":           true,
+	"stream":             true,
+	"format":             true,
+	"priority":           true,
+	"redact-mode":        true,
+	"sink":               true,
+	"value-capture-type": true,
+	"viz-format":         true,
+}
+
+// fileFlags are the options whose values are completed as file paths.
+var fileFlags = map[string]bool{
+	"checkpoint":  true,
+	"config":      true,
+	"credentials": true,
+	"output":      true,
+	"output-file": true,
+	"sqlite-path": true,
+	"unix-socket": true,
+	"viz-output":  true,
+}
+
+type completionFlag struct {
+	Long      string
+	Short     []string
+	Value     bool
+	Completed bool
+	File      bool
+}
+
+// names returns the option names with the dashes, e.g. "--project" and "-p".
+func (f completionFlag) names() []string {
+	names := []string{"--" + f.Long}
+	for _, s := range f.Short {
+		names = append(names, "-"+s)
+	}
+	return names
+}
+
+type completionData struct {
+	Program  string
+	Func     string
+	Commands []string
+	Flags    []completionFlag
+	// Option names for the bash and zsh scripts.
+	AllNames, CompletedNames, FileNames, ValueNames []string
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+func newCompletionData(program string, fs *flag.FlagSet) completionData {
+	// Short options share the value with the long options.
+	shorts := make(map[flag.Value][]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) == 1 {
+			shorts[f.Value] = append(shorts[f.Value], f.Name)
+		}
+	})
+
+	d := completionData{
+		Program:  program,
+		Func:     nonIdentifier.ReplaceAllString(program, "_"),
+		Commands: []string{commandServe, commandCreateStream, commandDropStream, commandDescribeStream, commandRecord, commandReplay, commandDiff, commandBench, commandCompletion},
+	}
+	fs.VisitAll(func(f *flag.Flag) {
+		if len(f.Name) == 1 {
+			return
+		}
+		cf := completionFlag{
+			Long:      f.Name,
+			Short:     shorts[f.Value],
+			Value:     !isBoolFlag(f.Value),
+			Completed: completedFlags[f.Name],
+			File:      fileFlags[f.Name],
+		}
+		d.Flags = append(d.Flags, cf)
+		d.AllNames = append(d.AllNames, cf.names()...)
+		switch {
+		case cf.Completed:
+			d.CompletedNames = append(d.CompletedNames, cf.names()...)
+		case cf.File:
+			d.FileNames = append(d.FileNames, cf.names()...)
+		case cf.Value:
+			d.ValueNames = append(d.ValueNames, cf.names()...)
+		}
+	})
+	return d
+}
+
+func isBoolFlag(v flag.Value) bool {
+	b, ok := v.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+var completionTemplates = map[string]*template.Template{
+	shellBash: newCompletionTemplate(`# bash completion for {{.Program}}
+_{{.Func}}() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+    # COMP_WORDS splits --option=value into "--option", "=" and "value".
+    if [[ "$cur" == "=" ]]; then
+        cur=""
+    elif [[ "$prev" == "=" ]]; then
+        prev="${COMP_WORDS[COMP_CWORD-2]}"
+    fi
+    case "$prev" in
+        {{join .CompletedNames "|"}})
+            COMPREPLY=($(compgen -W "$({{.Program}} {{.Complete}} "$prev" "${COMP_WORDS[@]:1:COMP_CWORD-1}" 2>/dev/null)" -- "$cur"))
+            return
+            ;;
+        {{join .FileNames "|"}})
+            COMPREPLY=($(compgen -f -- "$cur"))
+            return
+            ;;
+        {{join .ValueNames "|"}})
+            return
+            ;;
+    esac
+    if [[ $COMP_CWORD -eq 1 && "$cur" != -* ]]; then
+        COMPREPLY=($(compgen -W "{{join .Commands " "}}" -- "$cur"))
+    elif [[ "${COMP_WORDS[1]}" == {{.Completion}} && $COMP_CWORD -eq 2 ]]; then
+        COMPREPLY=($(compgen -W "{{join .Shells " "}}" -- "$cur"))
+    else
+        COMPREPLY=($(compgen -W "{{join .AllNames " "}}" -- "$cur"))
+    fi
+}
+complete -F _{{.Func}} {{.Program}}
+`),
+	shellZsh: newCompletionTemplate(`#compdef {{.Program}}
+
+_{{.Func}}() {
+  local cur=${words[CURRENT]} option=${words[CURRENT-1]}
+  if [[ $cur == -*=* ]]; then
+    option=${cur%%=*}
+    compset -P '*='
+  fi
+  case $option in
+    {{join .CompletedNames "|"}})
+      compadd -- ${(f)"$({{.Program}} {{.Complete}} $option ${words[2,CURRENT-1]} 2>/dev/null)"}
+      return
+      ;;
+    {{join .FileNames "|"}})
+      _files
+      return
+      ;;
+    {{join .ValueNames "|"}})
+      return
+      ;;
+  esac
+  if [[ $cur == -*=* ]]; then
+    return
+  elif (( CURRENT == 2 )) && [[ $cur != -* ]]; then
+    compadd -- {{join .Commands " "}}
+  elif [[ ${words[2]} == {{.Completion}} ]] && (( CURRENT == 3 )); then
+    compadd -- {{join .Shells " "}}
+  else
+    compadd -- {{join .AllNames " "}}
+  fi
+}
+
+compdef _{{.Func}} {{.Program}}
+`),
+	shellFish: newCompletionTemplate(`# fish completion for {{.Program}}
+function __{{.Func}}_complete
+    set -l tokens (commandline -opc)
+    {{.Program}} {{.Complete}} $argv[1] $tokens[2..-1] 2>/dev/null
+end
+
+complete -c {{.Program}} -f
+complete -c {{.Program}} -n __fish_use_subcommand -a '{{join .Commands " "}}'
+complete -c {{.Program}} -n '__fish_seen_subcommand_from {{.Completion}}' -a '{{join .Shells " "}}'
+{{range .Flags -}}
+complete -c {{$.Program}}{{range .Short}} -s {{.}}{{end}} -l {{.Long}}
+{{- if .Completed}} -x -a '(__{{$.Func}}_complete --{{.Long}})'{{else if .File}} -r -F{{else if .Value}} -x{{end}}
+{{end -}}
+`),
+}
+
+func newCompletionTemplate(text string) *template.Template {
+	return template.Must(template.New("").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(text))
+}
+
+// writeCompletionScript writes the completion script of the shell for the options of the flag set.
+func writeCompletionScript(w io.Writer, shell, program string, fs *flag.FlagSet) error {
+	tmpl, ok := completionTemplates[shell]
+	if !ok {
+		return fmt.Errorf("unsupported shell: %s", shell)
+	}
+	return tmpl.Execute(w, struct {
+		completionData
+		Complete   string
+		Completion string
+		Shells     []string
+	}{newCompletionData(program, fs), commandComplete, commandCompletion, []string{shellBash, shellZsh, shellFish}})
+}
+
+// completionArgs converts the words on the command line to the arguments of the flag set,
+// dropping the command, unknown options and options without values, e.g. the one being completed.
+func completionArgs(fs *flag.FlagSet, words []string) []string {
+	var args []string
+	for i := 0; i < len(words); i++ {
+		if !strings.HasPrefix(words[i], "-") {
+			continue
+		}
+		name := strings.TrimLeft(words[i], "-")
+		var value string
+		hasValue := false
+		if parts := strings.SplitN(name, "=", 2); len(parts) == 2 {
+			name, value, hasValue = parts[0], parts[1], true
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			continue
+		}
+		switch {
+		case hasValue:
+		case i+1 < len(words) && words[i+1] == "=":
+			// bash splits --option=value into "--option", "=" and "value".
+			if i+2 < len(words) {
+				value, hasValue = words[i+2], true
+			}
+			i += 2
+		case isBoolFlag(f.Value):
+			value, hasValue = "true", true
+		case i+1 < len(words):
+			value, hasValue = words[i+1], true
+			i++
+		}
+		if hasValue {
+			args = append(args, "--"+name+"="+value)
+		}
+	}
+	return args
+}
+
+// completionConfig is the configuration for listing the values of the options.
+type completionConfig struct {
+	projectID  string
+	instanceID string
+	databaseID string
+	role       string
+	client     clientConfig
+}
+
+// completeValues returns the values of the option.
+// Instances, databases and streams are listed via the Cloud Spanner APIs.
+func completeValues(ctx context.Context, option string, config completionConfig) ([]string, error) {
+	switch strings.TrimLeft(option, "-") {
+	case "format", "f":
+		return []string{formatText, formatJSON, formatLogfmt, formatRaw}, nil
+	case "priority":
+		return []string{priorityLow, priorityMedium, priorityHigh}, nil
+	case "redact-mode":
+		return []string{redactModePlaceholder, redactModeSHA256}, nil
+	case "sink":
+		return changestreams.Sinks(), nil
+	case "value-capture-type":
+		return valueCaptureTypes, nil
+	case "viz-format":
+		return vizFormats, nil
+	case "instance", "i":
+		if config.projectID == "" {
+			return nil, nil
+		}
+		return listInstances(ctx, config)
+	case "database", "d":
+		if config.projectID == "" || config.instanceID == "" {
+			return nil, nil
+		}
+		return listDatabases(ctx, config)
+	case "stream", "s":
+		if config.projectID == "" || config.instanceID == "" || config.databaseID == "" {
+			return nil, nil
+		}
+		return listStreams(ctx, config)
+	default:
+		return nil, nil
+	}
+}
+
+func listInstances(ctx context.Context, config completionConfig) ([]string, error) {
+	opts, err := clientOptions(ctx, config.client)
+	if err != nil {
+		return nil, err
+	}
+	client, err := instance.NewInstanceAdminClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var names []string
+	it := client.ListInstances(ctx, &instancepb.ListInstancesRequest{
+		Parent: fmt.Sprintf("projects/%s", config.projectID),
+	})
+	for {
+		i, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, path.Base(i.Name))
+	}
+}
+
+func listDatabases(ctx context.Context, config completionConfig) ([]string, error) {
+	opts, err := clientOptions(ctx, config.client)
+	if err != nil {
+		return nil, err
+	}
+	client, err := database.NewDatabaseAdminClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var names []string
+	it := client.ListDatabases(ctx, &databasepb.ListDatabasesRequest{
+		Parent: fmt.Sprintf("projects/%s/instances/%s", config.projectID, config.instanceID),
+	})
+	for {
+		d, err := it.Next()
+		if err == iterator.Done {
+			return names, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, path.Base(d.Name))
+	}
+}
+
+func listStreams(ctx context.Context, config completionConfig) ([]string, error) {
+	opts, err := clientOptions(ctx, config.client)
+	if err != nil {
+		return nil, err
+	}
+	dbPath := fmt.Sprintf("projects/%s/instances/%s/databases/%s", config.projectID, config.instanceID, config.databaseID)
+	client, err := spanner.NewClientWithConfig(ctx, dbPath, spanner.ClientConfig{
+		SessionPoolConfig: spanner.SessionPoolConfig{MinOpened: 1},
+		DatabaseRole:      config.role,
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return changestreams.Streams(ctx, client)
+}
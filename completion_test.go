@@ -0,0 +1,122 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestCompletionFlagSet() *flag.FlagSet {
+	var projectID, instanceID, configPath string
+	var verbose bool
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&projectID, "project", "", "")
+	fs.StringVar(&instanceID, "instance", "", "")
+	fs.StringVar(&configPath, "config", "", "")
+	fs.BoolVar(&verbose, "verbose", false, "")
+	fs.StringVar(&projectID, "p", "", "")
+	fs.StringVar(&instanceID, "i", "", "")
+	return fs
+}
+
+func TestCompletionArgs(t *testing.T) {
+	tests := []struct {
+		desc     string
+		words    []string
+		expected []string
+	}{
+		{
+			desc:     "separate values",
+			words:    []string{"serve", "-p", "myproject", "--verbose", "--instance"},
+			expected: []string{"--p=myproject", "--verbose=true"},
+		},
+		{
+			desc:     "inline values",
+			words:    []string{"--project=myproject", "--unknown=x", "-i=myinstance"},
+			expected: []string{"--project=myproject", "--i=myinstance"},
+		},
+		{
+			desc:     "values split by bash",
+			words:    []string{"--project", "=", "myproject", "--instance", "="},
+			expected: []string{"--project=myproject"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got := completionArgs(newTestCompletionFlagSet(), test.words)
+			if diff := cmp.Diff(got, test.expected); diff != "" {
+				t.Errorf("args have diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestWriteCompletionScript(t *testing.T) {
+	tests := []struct {
+		shell    string
+		expected []string
+	}{
+		{
+			shell: shellBash,
+			expected: []string{
+				"complete -F _spanner_change_streams_tail spanner-change-streams-tail",
+				"--instance|-i)",
+				"--config)",
+				"--project|-p)",
+			},
+		},
+		{
+			shell: shellZsh,
+			expected: []string{
+				"compdef _spanner_change_streams_tail spanner-change-streams-tail",
+				"--instance|-i)",
+				"compadd -- --config --instance -i --project -p --verbose",
+			},
+		},
+		{
+			shell: shellFish,
+			expected: []string{
+				"complete -c spanner-change-streams-tail -s i -l instance -x -a '(__spanner_change_streams_tail_complete --instance)'",
+				"complete -c spanner-change-streams-tail -l config -r -F",
+				"complete -c spanner-change-streams-tail -s p -l project -x\n",
+				"complete -c spanner-change-streams-tail -l verbose\n",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.shell, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeCompletionScript(&buf, test.shell, "spanner-change-streams-tail", newTestCompletionFlagSet()); err != nil {
+				t.Fatalf("writeCompletionScript error: %v", err)
+			}
+			for _, s := range test.expected {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("script must contain %q, got:\n%s", s, buf.String())
+				}
+			}
+		})
+	}
+
+	if err := writeCompletionScript(&bytes.Buffer{}, "tcsh", "spanner-change-streams-tail", newTestCompletionFlagSet()); err == nil {
+		t.Errorf("writeCompletionScript must fail for an unsupported shell")
+	}
+}
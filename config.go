@@ -0,0 +1,149 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFileName is the name of the config file searched when --config is not specified.
+const configFileName = "spanner-change-streams-tail.yaml"
+
+// defaultConfigPath returns the first config file found in the current directory or the user config directory,
+// or an empty string if none is found.
+func defaultConfigPath() string {
+	dirs := []string{"."}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, dir)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, configFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadConfigFile sets the flags from the YAML config file, whose keys are the long option names.
+// The flags already set on the command line take precedence over the config file.
+//
+// A list is set as comma separated values, or one by one for the options that can be repeated,
+// and a map is set as key=value pairs, e.g. for --sink-param.
+func loadConfigFile(fs *flag.FlagSet, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config map[string]interface{}
+	if err := yaml.Unmarshal(b, &config); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	// Short options share the value with their long options.
+	var set []flag.Value
+	fs.Visit(func(f *flag.Flag) {
+		set = append(set, f.Value)
+	})
+	isSet := func(v flag.Value) bool {
+		for _, s := range set {
+			if s == v {
+				return true
+			}
+		}
+		return false
+	}
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		f := fs.Lookup(name)
+		if f == nil || name == "config" {
+			return fmt.Errorf("unknown option in config file %s: %s", path, name)
+		}
+		if isSet(f.Value) {
+			continue
+		}
+		values, err := configValues(config[name])
+		if err != nil {
+			return fmt.Errorf("invalid option in config file %s: %s: %w", path, name, err)
+		}
+		if _, ok := f.Value.(*stringsFlag); !ok {
+			values = []string{strings.Join(values, ",")}
+		}
+		for _, v := range values {
+			if err := fs.Set(name, v); err != nil {
+				return fmt.Errorf("invalid option in config file %s: %s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func configValues(v interface{}) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		var values []string
+		for _, e := range v {
+			if _, ok := e.(map[string]interface{}); ok {
+				return nil, errors.New("nested map is not supported")
+			}
+			if _, ok := e.([]interface{}); ok {
+				return nil, errors.New("nested list is not supported")
+			}
+			values = append(values, configValue(e))
+		}
+		return values, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var values []string
+		for _, k := range keys {
+			values = append(values, k+"="+configValue(v[k]))
+		}
+		return values, nil
+	default:
+		return []string{configValue(v)}, nil
+	}
+}
+
+// configValue formats the scalar value. The unquoted timestamps are decoded into time.Time, so they are formatted back
+// in RFC 3339 as written.
+func configValue(v interface{}) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
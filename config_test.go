@@ -0,0 +1,88 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFileName)
+	config := `
+project: myproject
+instance: myinstance
+format: json
+redact: [Players.Email, Players.Phone]
+emit-schema: true
+gcs-max-age: 30m
+start: 2022-05-19T06:49:15Z
+end: 2022-05-19T07:49:15.5+09:00
+sink: file
+sink-param:
+  path: changes.jsonl
+  max_size: 100MB
+`
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	var (
+		projectID, instanceID, format, redact, sink, start, end string
+		emitSchema                                              bool
+		gcsMaxAge                                               time.Duration
+		sinkParams                                              stringsFlag
+	)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&projectID, "project", "", "")
+	fs.StringVar(&instanceID, "instance", "", "")
+	fs.StringVar(&format, "format", formatText, "")
+	fs.StringVar(&redact, "redact", "", "")
+	fs.StringVar(&sink, "sink", "", "")
+	fs.StringVar(&start, "start", "", "")
+	fs.StringVar(&end, "end", "", "")
+	fs.BoolVar(&emitSchema, "emit-schema", false, "")
+	fs.DurationVar(&gcsMaxAge, "gcs-max-age", time.Hour, "")
+	fs.Var(&sinkParams, "sink-param", "")
+	fs.StringVar(&projectID, "p", "", "")
+	fs.StringVar(&format, "f", formatText, "")
+
+	if err := fs.Parse([]string{"-p", "other", "--instance=cli"}); err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if err := loadConfigFile(fs, path); err != nil {
+		t.Fatalf("loadConfigFile error: %v", err)
+	}
+
+	got := []interface{}{projectID, instanceID, format, redact, sink, start, end, emitSchema, gcsMaxAge, []string(sinkParams)}
+	expected := []interface{}{"other", "cli", "json", "Players.Email,Players.Phone", "file", "2022-05-19T06:49:15Z", "2022-05-19T07:49:15.5+09:00", true, 30 * time.Minute, []string{"max_size=100MB", "path=changes.jsonl"}}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("flags have diff = %v", diff)
+	}
+
+	if err := os.WriteFile(path, []byte("unknown: 1\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := loadConfigFile(fs, path); err == nil {
+		t.Errorf("loadConfigFile must fail for an unknown option")
+	}
+}
@@ -0,0 +1,176 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	database "cloud.google.com/go/spanner/admin/database/apiv1"
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"google.golang.org/api/option"
+)
+
+const (
+	commandCreateStream = "create-stream"
+	commandDropStream   = "drop-stream"
+)
+
+var valueCaptureTypes = []string{"OLD_AND_NEW_VALUES", "NEW_ROW", "NEW_VALUES", "NEW_ROW_AND_OLD_VALUES"}
+
+var (
+	// watchPattern matches the table to watch in the form of table or table(column, ...).
+	watchPattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_.]*)\s*(?:\((.*)\))?\s*$`)
+	namePattern  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// createStreamConfig is the configuration of the change stream created by the create-stream command.
+type createStreamConfig struct {
+	forAll bool
+	// watch is the tables to watch in the form of table or table(column, ...).
+	watch            []string
+	valueCaptureType string
+	retentionPeriod  string
+}
+
+// createStreamDDL returns the CREATE CHANGE STREAM statement in the dialect.
+func createStreamDDL(streamID string, config createStreamConfig, dialect databasepb.DatabaseDialect) (string, error) {
+	if !namePattern.MatchString(streamID) {
+		return "", fmt.Errorf("invalid stream name: %s", streamID)
+	}
+	if config.forAll && len(config.watch) > 0 {
+		return "", fmt.Errorf("--for-all and --watch options cannot be specified together")
+	}
+	if !config.forAll && len(config.watch) == 0 {
+		return "", fmt.Errorf("specify --for-all or --watch option")
+	}
+
+	ddl := "CREATE CHANGE STREAM " + streamID
+	if config.forAll {
+		ddl += " FOR ALL"
+	} else {
+		var tables []string
+		for _, w := range config.watch {
+			table, err := parseWatch(w)
+			if err != nil {
+				return "", err
+			}
+			tables = append(tables, table)
+		}
+		ddl += " FOR " + strings.Join(tables, ", ")
+	}
+
+	var options []string
+	if config.valueCaptureType != "" {
+		valid := false
+		for _, t := range valueCaptureTypes {
+			if config.valueCaptureType == t {
+				valid = true
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("invalid value capture type: %s", config.valueCaptureType)
+		}
+		options = append(options, fmt.Sprintf("value_capture_type = '%s'", config.valueCaptureType))
+	}
+	if config.retentionPeriod != "" {
+		if strings.ContainsAny(config.retentionPeriod, `'\`) {
+			return "", fmt.Errorf("invalid retention period: %s", config.retentionPeriod)
+		}
+		options = append(options, fmt.Sprintf("retention_period = '%s'", config.retentionPeriod))
+	}
+	if len(options) > 0 {
+		if dialect == databasepb.DatabaseDialect_POSTGRESQL {
+			ddl += " WITH (" + strings.Join(options, ", ") + ")"
+		} else {
+			ddl += " OPTIONS (" + strings.Join(options, ", ") + ")"
+		}
+	}
+	return ddl, nil
+}
+
+// parseWatch validates the table to watch, and returns it in the DDL syntax.
+func parseWatch(watch string) (string, error) {
+	m := watchPattern.FindStringSubmatch(watch)
+	if m == nil {
+		return "", fmt.Errorf("invalid table to watch %q, must be in the form of table or table(column, ...)", watch)
+	}
+	if !strings.Contains(watch, "(") {
+		return m[1], nil
+	}
+	var columns []string
+	if strings.TrimSpace(m[2]) != "" {
+		for _, c := range strings.Split(m[2], ",") {
+			c = strings.TrimSpace(c)
+			if !namePattern.MatchString(c) {
+				return "", fmt.Errorf("invalid column %q in the table to watch %q", c, watch)
+			}
+			columns = append(columns, c)
+		}
+	}
+	// table() watches only the primary key columns.
+	return m[1] + "(" + strings.Join(columns, ", ") + ")", nil
+}
+
+// dropStreamDDL returns the DROP CHANGE STREAM statement.
+func dropStreamDDL(streamID string) (string, error) {
+	if !namePattern.MatchString(streamID) {
+		return "", fmt.Errorf("invalid stream name: %s", streamID)
+	}
+	return "DROP CHANGE STREAM " + streamID, nil
+}
+
+// runStreamDDL executes the DDL of the create-stream or drop-stream command, and waits for its completion.
+func runStreamDDL(ctx context.Context, command, dbPath, streamID string, config createStreamConfig, opts []option.ClientOption) error {
+	client, err := database.NewDatabaseAdminClient(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var ddl string
+	switch command {
+	case commandCreateStream:
+		db, err := client.GetDatabase(ctx, &databasepb.GetDatabaseRequest{Name: dbPath})
+		if err != nil {
+			return fmt.Errorf("failed to get the database: %w", err)
+		}
+		ddl, err = createStreamDDL(streamID, config, db.DatabaseDialect)
+		if err != nil {
+			return err
+		}
+	case commandDropStream:
+		ddl, err = dropStreamDDL(streamID)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unexpected command: %s", command)
+	}
+
+	logger.Info("Executing the DDL", "ddl", ddl)
+	op, err := client.UpdateDatabaseDdl(ctx, &databasepb.UpdateDatabaseDdlRequest{
+		Database:   dbPath,
+		Statements: []string{ddl},
+	})
+	if err != nil {
+		return err
+	}
+	return op.Wait(ctx)
+}
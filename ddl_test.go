@@ -0,0 +1,92 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+
+	"cloud.google.com/go/spanner/admin/database/apiv1/databasepb"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCreateStreamDDL(t *testing.T) {
+	tests := []struct {
+		desc     string
+		config   createStreamConfig
+		dialect  databasepb.DatabaseDialect
+		expected string
+	}{
+		{
+			desc:     "for all",
+			config:   createStreamConfig{forAll: true},
+			dialect:  databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL,
+			expected: "CREATE CHANGE STREAM mystream FOR ALL",
+		},
+		{
+			desc:     "tables and columns",
+			config:   createStreamConfig{watch: []string{"Players", "Teams(Name, Score)", "Scores()"}},
+			dialect:  databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL,
+			expected: "CREATE CHANGE STREAM mystream FOR Players, Teams(Name, Score), Scores()",
+		},
+		{
+			desc:     "options",
+			config:   createStreamConfig{forAll: true, valueCaptureType: "NEW_ROW", retentionPeriod: "7d"},
+			dialect:  databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL,
+			expected: "CREATE CHANGE STREAM mystream FOR ALL OPTIONS (value_capture_type = 'NEW_ROW', retention_period = '7d')",
+		},
+		{
+			desc:     "PostgreSQL options",
+			config:   createStreamConfig{watch: []string{"players"}, retentionPeriod: "36h"},
+			dialect:  databasepb.DatabaseDialect_POSTGRESQL,
+			expected: "CREATE CHANGE STREAM mystream FOR players WITH (retention_period = '36h')",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			got, err := createStreamDDL("mystream", test.config, test.dialect)
+			if err != nil {
+				t.Fatalf("createStreamDDL error: %v", err)
+			}
+			if diff := cmp.Diff(got, test.expected); diff != "" {
+				t.Errorf("DDL has diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestCreateStreamDDLInvalid(t *testing.T) {
+	tests := []struct {
+		desc     string
+		streamID string
+		config   createStreamConfig
+	}{
+		{desc: "no tables", streamID: "mystream", config: createStreamConfig{}},
+		{desc: "for all and tables", streamID: "mystream", config: createStreamConfig{forAll: true, watch: []string{"Players"}}},
+		{desc: "invalid stream", streamID: "my-stream", config: createStreamConfig{forAll: true}},
+		{desc: "invalid table", streamID: "mystream", config: createStreamConfig{watch: []string{"Players; DROP TABLE Players"}}},
+		{desc: "invalid column", streamID: "mystream", config: createStreamConfig{watch: []string{"Players(Name Score)"}}},
+		{desc: "invalid value capture type", streamID: "mystream", config: createStreamConfig{forAll: true, valueCaptureType: "ALL"}},
+		{desc: "invalid retention period", streamID: "mystream", config: createStreamConfig{forAll: true, retentionPeriod: "7d'"}},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if _, err := createStreamDDL(test.streamID, test.config, databasepb.DatabaseDialect_GOOGLE_STANDARD_SQL); err == nil {
+				t.Errorf("createStreamDDL must fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// deadLetterFile appends the dead letters to a file as JSON lines.
+type deadLetterFile struct {
+	file *os.File
+	mu   sync.Mutex
+}
+
+func openDeadLetterFile(path string) (*deadLetterFile, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &deadLetterFile{file: f}, nil
+}
+
+// write writes the dead letter. It's called by changestreams.DeadLetterSink.
+func (f *deadLetterFile) write(d *changestreams.DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return json.NewEncoder(f.file).Encode(struct {
+		StreamID       string                          `json:"stream_id"`
+		PartitionToken string                          `json:"partition_token"`
+		Error          string                          `json:"error"`
+		Record         *changestreams.DataChangeRecord `json:"record"`
+	}{d.StreamID, d.PartitionToken, d.Err.Error(), d.Record})
+}
+
+func (f *deadLetterFile) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,56 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDeadLetterFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	f, err := openDeadLetterFile(path)
+	if err != nil {
+		t.Fatalf("openDeadLetterFile error: %v", err)
+	}
+	if err := f.write(&changestreams.DeadLetter{
+		StreamID:       "s",
+		PartitionToken: "a",
+		Record:         newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"),
+		Err:            errors.New("bad record"),
+	}); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected := `{"stream_id":"s","partition_token":"a","error":"bad record","record":{"commit_timestamp":"2022-12-04T18:00:00Z","record_sequence":"","server_transaction_id":"","is_last_record_in_transaction_in_partition":false,"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1}],"mods":[{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}],"mod_type":"INSERT","value_capture_type":"","number_of_records_in_transaction":0,"number_of_partitions_in_transaction":0,"transaction_tag":"","is_system_transaction":false}}
+`
+	if diff := cmp.Diff(string(got), expected); diff != "" {
+		t.Errorf("dead letter file has diff = %v", diff)
+	}
+}
@@ -0,0 +1,180 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// debugVarsName is the name of the debug variables published by expvar.
+const debugVarsName = "changestreams"
+
+// partitionVar is the state of a partition in the debug variables.
+type partitionVar struct {
+	Stream        string    `json:"stream"`
+	Token         string    `json:"token"`
+	LastTimestamp time.Time `json:"last_timestamp"`
+	Records       int64     `json:"records"`
+}
+
+// debugVars are the internal counters and the states of the partitions, published by expvar for the operators to
+// inspect a running tail.
+type debugVars struct {
+	vars               *expvar.Map
+	results            expvar.Int
+	records            expvar.Int
+	heartbeats         expvar.Int
+	childPartitions    expvar.Int
+	finishedPartitions expvar.Int
+
+	// partitions are the active partitions by the stream IDs and the tokens.
+	partitions map[partitionKey]*partitionVar
+	// tables are the statistics of the tables by the stream IDs and the table names.
+	tables map[string]map[string]*tableStats
+	mu     sync.Mutex
+}
+
+func newDebugVars() *debugVars {
+	v := &debugVars{
+		vars:       new(expvar.Map).Init(),
+		partitions: make(map[partitionKey]*partitionVar),
+		tables:     make(map[string]map[string]*tableStats),
+	}
+	v.vars.Set("results", &v.results)
+	v.vars.Set("data_change_records", &v.records)
+	v.vars.Set("heartbeat_records", &v.heartbeats)
+	v.vars.Set("child_partitions_records", &v.childPartitions)
+	v.vars.Set("finished_partitions", &v.finishedPartitions)
+	v.vars.Set("partitions", expvar.Func(v.partitionStates))
+	v.vars.Set("tables", expvar.Func(v.tableStats))
+	return v
+}
+
+// observe updates the variables by the result.
+func (v *debugVars) observe(result *changestreams.ReadResult) {
+	v.results.Add(1)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, changeRecord := range result.ChangeRecords {
+		v.records.Add(int64(len(changeRecord.DataChangeRecords)))
+		v.heartbeats.Add(int64(len(changeRecord.HeartbeatRecords)))
+		v.childPartitions.Add(int64(len(changeRecord.ChildPartitionsRecords)))
+
+		for _, r := range changeRecord.DataChangeRecords {
+			p := v.partition(result)
+			p.Records++
+			v.table(result.StreamID, r.TableName).add(r)
+			if r.CommitTimestamp.After(p.LastTimestamp) {
+				p.LastTimestamp = r.CommitTimestamp
+			}
+		}
+		for _, r := range changeRecord.HeartbeatRecords {
+			p := v.partition(result)
+			if r.Timestamp.After(p.LastTimestamp) {
+				p.LastTimestamp = r.Timestamp
+			}
+		}
+		if len(changeRecord.ChildPartitionsRecords) > 0 && result.PartitionToken != "" {
+			// The partition finishes after returning the child partitions.
+			delete(v.partitions, partitionKey{streamID: result.StreamID, token: result.PartitionToken})
+			v.finishedPartitions.Add(1)
+		}
+	}
+}
+
+func (v *debugVars) partition(result *changestreams.ReadResult) *partitionVar {
+	key := partitionKey{streamID: result.StreamID, token: result.PartitionToken}
+	p, ok := v.partitions[key]
+	if !ok {
+		p = &partitionVar{Stream: result.StreamID, Token: result.PartitionToken}
+		v.partitions[key] = p
+	}
+	return p
+}
+
+func (v *debugVars) table(streamID, table string) *tableStats {
+	if v.tables[streamID] == nil {
+		v.tables[streamID] = make(map[string]*tableStats)
+	}
+	s, ok := v.tables[streamID][table]
+	if !ok {
+		s = &tableStats{}
+		v.tables[streamID][table] = s
+	}
+	return s
+}
+
+// tableStats returns a copy of the statistics of the tables.
+func (v *debugVars) tableStats() interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	stats := make(map[string]map[string]tableStats, len(v.tables))
+	for streamID, tables := range v.tables {
+		stats[streamID] = make(map[string]tableStats, len(tables))
+		for table, s := range tables {
+			stats[streamID][table] = *s
+		}
+	}
+	return stats
+}
+
+// partitionStates returns a copy of the states of the active partitions.
+func (v *debugVars) partitionStates() interface{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	states := make([]partitionVar, 0, len(v.partitions))
+	for _, p := range v.partitions {
+		states = append(states, *p)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Stream != states[j].Stream {
+			return states[i].Stream < states[j].Stream
+		}
+		return states[i].Token < states[j].Token
+	})
+	return states
+}
+
+// serveDebugVars publishes the variables, and serves them on /debug/vars of the address in the background.
+// It returns the function to stop the server. It must be called at most once.
+func serveDebugVars(addr string, v *debugVars) (func(), error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	expvar.Publish(debugVarsName, v.vars)
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	server := &http.Server{Handler: mux}
+	go server.Serve(lis)
+	logger.Info("Serving the debug variables", "url", fmt.Sprintf("http://%s/debug/vars", lis.Addr()))
+	return func() {
+		server.Shutdown(context.Background())
+	}, nil
+}
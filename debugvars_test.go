@@ -0,0 +1,104 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDebugVars(t *testing.T) {
+	v := newDebugVars()
+
+	data := func(stream, token, ts string) *changestreams.ReadResult {
+		result := newTestReadResult(newTestDataChangeRecord(t, ts, "PlayerId"))
+		result.StreamID = stream
+		result.PartitionToken = token
+		return result
+	}
+	for _, result := range []*changestreams.ReadResult{
+		data("s", "a", "2022-12-04T18:00:00Z"),
+		data("s", "a", "2022-12-04T18:00:10Z"),
+		data("s", "b", "2022-12-04T18:00:20Z"),
+		data("t", "a", "2022-12-04T18:00:05Z"),
+		{
+			StreamID:       "s",
+			PartitionToken: "b",
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{HeartbeatRecords: []*changestreams.HeartbeatRecord{{Timestamp: mustParseTime(t, "2022-12-04T18:00:30Z")}}},
+			},
+		},
+		{
+			StreamID:       "s",
+			PartitionToken: "a",
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{{}}},
+			},
+		},
+	} {
+		v.observe(result)
+	}
+
+	var got bytes.Buffer
+	if err := json.Indent(&got, []byte(v.vars.String()), "", "  "); err != nil {
+		t.Fatalf("failed to indent: %v", err)
+	}
+	expected := `{
+  "child_partitions_records": 1,
+  "data_change_records": 4,
+  "finished_partitions": 1,
+  "heartbeat_records": 1,
+  "partitions": [
+    {
+      "stream": "s",
+      "token": "b",
+      "last_timestamp": "2022-12-04T18:00:30Z",
+      "records": 1
+    },
+    {
+      "stream": "t",
+      "token": "a",
+      "last_timestamp": "2022-12-04T18:00:05Z",
+      "records": 1
+    }
+  ],
+  "results": 6,
+  "tables": {
+    "s": {
+      "Players": {
+        "records": 3,
+        "bytes": 213,
+        "last_commit_timestamp": "2022-12-04T18:00:20Z"
+      }
+    },
+    "t": {
+      "Players": {
+        "records": 1,
+        "bytes": 71,
+        "last_commit_timestamp": "2022-12-04T18:00:05Z"
+      }
+    }
+  }
+}`
+	if diff := cmp.Diff(got.String(), expected); diff != "" {
+		t.Errorf("debug variables have diff = %v", diff)
+	}
+}
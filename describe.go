@@ -0,0 +1,59 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+const commandDescribeStream = "describe-stream"
+
+// writeStreamDescription writes the description of the change stream in JSON or the human readable format.
+func writeStreamDescription(w io.Writer, d *changestreams.StreamDescription, format string) error {
+	if format == formatJSON {
+		return json.NewEncoder(w).Encode(d)
+	}
+
+	_, err := fmt.Fprintf(w, `Stream:             %s
+Tables:             %s
+Value capture type: %s
+Retention period:   %s
+Partitions:         %d
+`, d.Name, watchedTables(d), d.ValueCaptureType, d.RetentionPeriod, d.Partitions)
+	return err
+}
+
+// watchedTables returns the tables watched by the change stream, with the columns if not all of them are watched.
+func watchedTables(d *changestreams.StreamDescription) string {
+	if d.All {
+		return "ALL"
+	}
+	var watched []string
+	for _, t := range d.Tables {
+		if t.AllColumns {
+			watched = append(watched, t.Name)
+		} else {
+			watched = append(watched, fmt.Sprintf("%s(%s)", t.Name, strings.Join(t.Columns, ", ")))
+		}
+	}
+	return strings.Join(watched, ", ")
+}
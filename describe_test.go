@@ -0,0 +1,70 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWriteStreamDescription(t *testing.T) {
+	d := &changestreams.StreamDescription{
+		Name: "mystream",
+		Tables: []changestreams.WatchedTable{
+			{Name: "Players", AllColumns: true},
+			{Name: "Teams", Columns: []string{"Name", "Score"}},
+			{Name: "Scores"},
+		},
+		ValueCaptureType: "NEW_ROW",
+		RetentionPeriod:  "7d",
+		Partitions:       3,
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: formatText,
+			expected: `Stream:             mystream
+Tables:             Players, Teams(Name, Score), Scores()
+Value capture type: NEW_ROW
+Retention period:   7d
+Partitions:         3
+`,
+		},
+		{
+			format: formatJSON,
+			expected: `{"name":"mystream","all":false,"tables":[{"name":"Players","all_columns":true,"columns":null},{"name":"Teams","all_columns":false,"columns":["Name","Score"]},{"name":"Scores","all_columns":false,"columns":null}],"value_capture_type":"NEW_ROW","retention_period":"7d","partitions":3}
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeStreamDescription(&buf, d, test.format); err != nil {
+				t.Fatalf("writeStreamDescription error: %v", err)
+			}
+			if diff := cmp.Diff(buf.String(), test.expected); diff != "" {
+				t.Errorf("output has diff = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+const commandDiff = "diff"
+
+const (
+	modTypeInsert = "INSERT"
+	modTypeUpdate = "UPDATE"
+	modTypeDelete = "DELETE"
+)
+
+// rowChange is the net change of a row over a time window.
+type rowChange struct {
+	TableName string                 `json:"table_name"`
+	Keys      map[string]interface{} `json:"keys"`
+	ModType   string                 `json:"mod_type"`
+	OldValues map[string]interface{} `json:"old_values"`
+	NewValues map[string]interface{} `json:"new_values"`
+}
+
+// netChangeCollector collects the data change records to compute the net change of each row.
+type netChangeCollector struct {
+	spool recordSpool
+	mu    sync.Mutex
+}
+
+func (c *netChangeCollector) Read(result *changestreams.ReadResult) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			if err := c.spool.add(r); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close removes the records spilled to the temporary files.
+func (c *netChangeCollector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.spool.close()
+}
+
+// netChanges returns the net changes by table name and keys, applying the mods of each row in the commit order.
+// e.g. INSERT followed by UPDATE is an INSERT of the last values, and INSERT followed by DELETE is no change.
+// UPDATE restoring the old values is no change, if the old values are captured.
+func (c *netChangeCollector) netChanges() (map[string]*rowChange, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changes := make(map[string]*rowChange)
+	err := c.spool.each(func(r *changestreams.DataChangeRecord) error {
+		for _, mod := range r.Mods {
+			change := &rowChange{TableName: r.TableName, ModType: r.ModType}
+			var err error
+			if change.Keys, err = jsonObject(mod.Keys); err != nil {
+				return err
+			}
+			if change.OldValues, err = jsonObject(mod.OldValues); err != nil {
+				return err
+			}
+			if change.NewValues, err = jsonObject(mod.NewValues); err != nil {
+				return err
+			}
+			key, err := rowChangeKey(change)
+			if err != nil {
+				return err
+			}
+			if prev, ok := changes[key]; ok {
+				change = mergeRowChanges(prev, change)
+			}
+			if change == nil {
+				delete(changes, key)
+			} else {
+				changes[key] = change
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for key, change := range changes {
+		if change.ModType != modTypeUpdate || len(change.OldValues) == 0 {
+			continue
+		}
+		for column, v := range change.NewValues {
+			if old, ok := change.OldValues[column]; ok && reflect.DeepEqual(old, v) {
+				delete(change.OldValues, column)
+				delete(change.NewValues, column)
+			}
+		}
+		if len(change.NewValues) == 0 {
+			delete(changes, key)
+		}
+	}
+	return changes, nil
+}
+
+// mergeRowChanges returns the net change of the row changed by prev and then next, or nil if there is no change.
+func mergeRowChanges(prev, next *rowChange) *rowChange {
+	switch {
+	case prev.ModType == modTypeInsert && next.ModType == modTypeUpdate:
+		return &rowChange{TableName: prev.TableName, Keys: prev.Keys, ModType: modTypeInsert,
+			OldValues: prev.OldValues, NewValues: mergeValues(prev.NewValues, next.NewValues)}
+	case prev.ModType == modTypeInsert && next.ModType == modTypeDelete:
+		return nil
+	case prev.ModType == modTypeUpdate && next.ModType == modTypeUpdate:
+		return &rowChange{TableName: prev.TableName, Keys: prev.Keys, ModType: modTypeUpdate,
+			OldValues: mergeValues(next.OldValues, prev.OldValues), NewValues: mergeValues(prev.NewValues, next.NewValues)}
+	case prev.ModType == modTypeUpdate && next.ModType == modTypeDelete:
+		return &rowChange{TableName: prev.TableName, Keys: prev.Keys, ModType: modTypeDelete,
+			OldValues: mergeValues(next.OldValues, prev.OldValues), NewValues: next.NewValues}
+	case prev.ModType == modTypeDelete && next.ModType == modTypeInsert:
+		// The row is replaced.
+		return &rowChange{TableName: prev.TableName, Keys: prev.Keys, ModType: modTypeUpdate,
+			OldValues: prev.OldValues, NewValues: next.NewValues}
+	default:
+		// Unexpected sequence, e.g. the mods before the window are missing.
+		return next
+	}
+}
+
+// mergeValues returns the values overwritten by the newer values.
+func mergeValues(values, newer map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(values)+len(newer))
+	for k, v := range values {
+		merged[k] = v
+	}
+	for k, v := range newer {
+		merged[k] = v
+	}
+	return merged
+}
+
+func jsonObject(v spanner.NullJSON) (map[string]interface{}, error) {
+	if !v.Valid || v.Value == nil {
+		return map[string]interface{}{}, nil
+	}
+	m, ok := v.Value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected JSON value: %v", v)
+	}
+	return mergeValues(m, nil), nil
+}
+
+func rowChangeKey(change *rowChange) (string, error) {
+	keys, err := json.Marshal(change.Keys)
+	if err != nil {
+		return "", err
+	}
+	return change.TableName + " " + string(keys), nil
+}
+
+// sortedRowChangeKeys returns the keys of the changes in the order of the table names and the keys.
+func sortedRowChangeKeys(changes ...map[string]*rowChange) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, c := range changes {
+		for key := range c {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// writeNetChanges writes the net changes of the rows.
+func writeNetChanges(w io.Writer, changes map[string]*rowChange, format string) error {
+	for _, key := range sortedRowChangeKeys(changes) {
+		if err := writeRowChange(w, "", changes[key], format); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeWindowDiff writes the rows whose net changes differ between the first and second windows.
+// In the text format, the changes in the first window are prefixed with "<" and the second with ">" as diff(1).
+func writeWindowDiff(w io.Writer, first, second map[string]*rowChange, format string) error {
+	for _, key := range sortedRowChangeKeys(first, second) {
+		a, b := first[key], second[key]
+		if reflect.DeepEqual(a, b) {
+			continue
+		}
+		if a != nil {
+			if err := writeRowChange(w, "first", a, format); err != nil {
+				return err
+			}
+		}
+		if b != nil {
+			if err := writeRowChange(w, "second", b, format); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeRowChange(w io.Writer, window string, change *rowChange, format string) error {
+	if format == formatJSON {
+		if window == "" {
+			return json.NewEncoder(w).Encode(change)
+		}
+		return json.NewEncoder(w).Encode(struct {
+			Window string `json:"window"`
+			*rowChange
+		}{window, change})
+	}
+
+	var prefix string
+	switch window {
+	case "first":
+		prefix = "< "
+	case "second":
+		prefix = "> "
+	}
+	keys, err := json.Marshal(change.Keys)
+	if err != nil {
+		return err
+	}
+	oldValues, err := json.Marshal(change.OldValues)
+	if err != nil {
+		return err
+	}
+	newValues, err := json.Marshal(change.NewValues)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s%s | %s | %s | %s -> %s\n", prefix, change.ModType, change.TableName, keys, oldValues, newValues)
+	return err
+}
+
+// diffConfig is the configuration for the diff command.
+type diffConfig struct {
+	// If compareStartTimestamp and compareEndTimestamp are set, the net changes in the window are compared with
+	// the ones in the window of the reader configuration.
+	compareStartTimestamp time.Time
+	compareEndTimestamp   time.Time
+	format                string
+	// maxMemory is the maximum size of the records buffered in memory per window, or 0 for no limit.
+	maxMemory int64
+}
+
+// runDiff reads the window of the reader configuration, and writes the net changes of the rows in it, or the
+// differences from the net changes in the window to compare.
+func runDiff(ctx context.Context, w io.Writer, client *spanner.Client, streamIDs []string, config changestreams.Config, diff diffConfig) error {
+	first, err := readNetChanges(ctx, client, streamIDs, config, diff.maxMemory)
+	if err != nil {
+		return err
+	}
+	if diff.compareStartTimestamp.IsZero() {
+		return writeNetChanges(w, first, diff.format)
+	}
+
+	config.StartTimestamp = diff.compareStartTimestamp
+	config.EndTimestamp = diff.compareEndTimestamp
+	second, err := readNetChanges(ctx, client, streamIDs, config, diff.maxMemory)
+	if err != nil {
+		return err
+	}
+	return writeWindowDiff(w, first, second, diff.format)
+}
+
+func readNetChanges(ctx context.Context, client *spanner.Client, streamIDs []string, config changestreams.Config, maxMemory int64) (map[string]*rowChange, error) {
+	readers, err := newStreamReaders(ctx, client, streamIDs, config, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer readers.Close()
+
+	collector := &netChangeCollector{spool: recordSpool{maxMemory: maxMemory}}
+	defer collector.Close()
+	if err := readers.Read(ctx, collector.Read); err != nil {
+		return nil, err
+	}
+	return collector.netChanges()
+}
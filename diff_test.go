@@ -0,0 +1,139 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestModRecord(t *testing.T, commitTimestamp, modType, playerID string, oldValues, newValues map[string]interface{}) *changestreams.ReadResult {
+	return newTestReadResult(&changestreams.DataChangeRecord{
+		CommitTimestamp: mustParseTime(t, commitTimestamp),
+		TableName:       "Players",
+		ModType:         modType,
+		Mods: []*changestreams.Mod{
+			{
+				Keys:      spanner.NullJSON{Value: map[string]interface{}{"PlayerId": playerID}, Valid: true},
+				OldValues: spanner.NullJSON{Value: oldValues, Valid: true},
+				NewValues: spanner.NullJSON{Value: newValues, Valid: true},
+			},
+		},
+	})
+}
+
+func TestNetChanges(t *testing.T) {
+	var collector netChangeCollector
+	results := []*changestreams.ReadResult{
+		// Inserted and updated.
+		newTestModRecord(t, "2022-12-04T18:00:00Z", "INSERT", "1", nil, map[string]interface{}{"Name": "a", "Score": "1"}),
+		newTestModRecord(t, "2022-12-04T18:00:01Z", "UPDATE", "1", map[string]interface{}{"Score": "1"}, map[string]interface{}{"Score": "2"}),
+		// Inserted and deleted.
+		newTestModRecord(t, "2022-12-04T18:00:00Z", "INSERT", "2", nil, map[string]interface{}{"Name": "b"}),
+		newTestModRecord(t, "2022-12-04T18:00:02Z", "DELETE", "2", map[string]interface{}{"Name": "b"}, nil),
+		// Updated twice, the second update is read first.
+		newTestModRecord(t, "2022-12-04T18:00:03Z", "UPDATE", "3", map[string]interface{}{"Score": "2"}, map[string]interface{}{"Score": "3"}),
+		newTestModRecord(t, "2022-12-04T18:00:00Z", "UPDATE", "3", map[string]interface{}{"Name": "c", "Score": "1"}, map[string]interface{}{"Name": "d", "Score": "2"}),
+		// Updated and restored.
+		newTestModRecord(t, "2022-12-04T18:00:00Z", "UPDATE", "4", map[string]interface{}{"Name": "e"}, map[string]interface{}{"Name": "f"}),
+		newTestModRecord(t, "2022-12-04T18:00:01Z", "UPDATE", "4", map[string]interface{}{"Name": "f"}, map[string]interface{}{"Name": "e"}),
+		// Deleted and inserted again.
+		newTestModRecord(t, "2022-12-04T18:00:00Z", "DELETE", "5", map[string]interface{}{"Name": "g"}, nil),
+		newTestModRecord(t, "2022-12-04T18:00:01Z", "INSERT", "5", nil, map[string]interface{}{"Name": "h"}),
+	}
+	for _, result := range results {
+		if err := collector.Read(result); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+
+	changes, err := collector.netChanges()
+	if err != nil {
+		t.Fatalf("netChanges error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeNetChanges(&buf, changes, formatText); err != nil {
+		t.Fatalf("writeNetChanges error: %v", err)
+	}
+	expected := `INSERT | Players | {"PlayerId":"1"} | {} -> {"Name":"a","Score":"2"}
+UPDATE | Players | {"PlayerId":"3"} | {"Name":"c","Score":"1"} -> {"Name":"d","Score":"3"}
+UPDATE | Players | {"PlayerId":"5"} | {"Name":"g"} -> {"Name":"h"}
+`
+	if diff := cmp.Diff(buf.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+}
+
+func TestWriteWindowDiff(t *testing.T) {
+	newChanges := func(results ...*changestreams.ReadResult) map[string]*rowChange {
+		var collector netChangeCollector
+		for _, result := range results {
+			if err := collector.Read(result); err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+		}
+		changes, err := collector.netChanges()
+		if err != nil {
+			t.Fatalf("netChanges error: %v", err)
+		}
+		return changes
+	}
+	first := newChanges(
+		newTestModRecord(t, "2022-12-04T18:00:00Z", "UPDATE", "1", map[string]interface{}{"Name": "a"}, map[string]interface{}{"Name": "b"}),
+		newTestModRecord(t, "2022-12-04T18:00:00Z", "UPDATE", "2", map[string]interface{}{"Name": "c"}, map[string]interface{}{"Name": "d"}),
+	)
+	second := newChanges(
+		newTestModRecord(t, "2022-12-05T18:00:00Z", "UPDATE", "1", map[string]interface{}{"Name": "a"}, map[string]interface{}{"Name": "b"}),
+		newTestModRecord(t, "2022-12-05T18:00:00Z", "UPDATE", "2", map[string]interface{}{"Name": "c"}, map[string]interface{}{"Name": "e"}),
+		newTestModRecord(t, "2022-12-05T18:00:00Z", "DELETE", "3", map[string]interface{}{"Name": "f"}, nil),
+	)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: formatText,
+			expected: `< UPDATE | Players | {"PlayerId":"2"} | {"Name":"c"} -> {"Name":"d"}
+> UPDATE | Players | {"PlayerId":"2"} | {"Name":"c"} -> {"Name":"e"}
+> DELETE | Players | {"PlayerId":"3"} | {"Name":"f"} -> {}
+`,
+		},
+		{
+			format: formatJSON,
+			expected: `{"window":"first","table_name":"Players","keys":{"PlayerId":"2"},"mod_type":"UPDATE","old_values":{"Name":"c"},"new_values":{"Name":"d"}}
+{"window":"second","table_name":"Players","keys":{"PlayerId":"2"},"mod_type":"UPDATE","old_values":{"Name":"c"},"new_values":{"Name":"e"}}
+{"window":"second","table_name":"Players","keys":{"PlayerId":"3"},"mod_type":"DELETE","old_values":{"Name":"f"},"new_values":{}}
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeWindowDiff(&buf, first, second, test.format); err != nil {
+				t.Fatalf("writeWindowDiff error: %v", err)
+			}
+			if diff := cmp.Diff(buf.String(), test.expected); diff != "" {
+				t.Errorf("output has diff = %v", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// ElasticsearchSink keeps Elasticsearch (or OpenSearch) indices in sync with the tables.
+//
+// Each row is indexed as a document whose ID is the JSON array of its primary key values.
+// Inserts replace the document, updates are merged into the document, and deletes remove the document.
+// Each table is indexed into the index named indexPrefix + lower-cased table name.
+type ElasticsearchSink struct {
+	ctx         context.Context
+	url         string
+	indexPrefix string
+	client      *http.Client
+}
+
+// NewElasticsearchSink creates a new ElasticsearchSink. Credentials can be included in the URL as user info.
+func NewElasticsearchSink(ctx context.Context, url, indexPrefix string) *ElasticsearchSink {
+	return &ElasticsearchSink{
+		ctx:         ctx,
+		url:         strings.TrimSuffix(url, "/"),
+		indexPrefix: indexPrefix,
+		client:      &http.Client{Timeout: time.Minute},
+	}
+}
+
+func (s *ElasticsearchSink) Read(result *changestreams.ReadResult) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			for _, mod := range r.Mods {
+				if err := s.writeActions(enc, r, mod); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if body.Len() == 0 {
+		return nil
+	}
+	return s.bulk(&body)
+}
+
+func (s *ElasticsearchSink) writeActions(enc *json.Encoder, r *changestreams.DataChangeRecord, mod *changestreams.Mod) error {
+	id, err := elasticsearchDocumentID(r, mod)
+	if err != nil {
+		return err
+	}
+	meta := map[string]string{
+		"_index": s.indexPrefix + strings.ToLower(r.TableName),
+		"_id":    id,
+	}
+
+	switch r.ModType {
+	case "DELETE":
+		return enc.Encode(map[string]interface{}{"delete": meta})
+	case "INSERT":
+		if err := enc.Encode(map[string]interface{}{"index": meta}); err != nil {
+			return err
+		}
+		return enc.Encode(elasticsearchDocument(mod))
+	case "UPDATE":
+		if err := enc.Encode(map[string]interface{}{"update": meta}); err != nil {
+			return err
+		}
+		return enc.Encode(map[string]interface{}{
+			"doc":           elasticsearchDocument(mod),
+			"doc_as_upsert": true,
+		})
+	default:
+		return fmt.Errorf("unexpected mod type: %s", r.ModType)
+	}
+}
+
+func (s *ElasticsearchSink) bulk(body *bytes.Buffer) error {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.url+"/_bulk", body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("bulk request returned status %s", resp.Status)
+	}
+
+	var bulkResp struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&bulkResp); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if !bulkResp.Errors {
+		return nil
+	}
+	for _, item := range bulkResp.Items {
+		for action, result := range item {
+			// Deleting a document that doesn't exist is not an error.
+			if action == "delete" && result.Status == http.StatusNotFound {
+				continue
+			}
+			if result.Status >= 300 {
+				return fmt.Errorf("bulk %s failed with status %d: %s", action, result.Status, result.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// elasticsearchDocumentID returns the JSON array of the primary key values in the ordinal order.
+func elasticsearchDocumentID(r *changestreams.DataChangeRecord, mod *changestreams.Mod) (string, error) {
+	keys, _ := mod.Keys.Value.(map[string]interface{})
+
+	var primaryKeys []*changestreams.ColumnType
+	for _, columnType := range r.ColumnTypes {
+		if columnType.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, columnType)
+		}
+	}
+	sort.Slice(primaryKeys, func(i, j int) bool {
+		return primaryKeys[i].OrdinalPosition < primaryKeys[j].OrdinalPosition
+	})
+
+	values := make([]interface{}, 0, len(primaryKeys))
+	for _, pk := range primaryKeys {
+		values = append(values, keys[pk.Name])
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// elasticsearchDocument merges the keys and the new values of the mod.
+func elasticsearchDocument(mod *changestreams.Mod) map[string]interface{} {
+	doc := make(map[string]interface{})
+	for _, v := range []interface{}{mod.Keys.Value, mod.NewValues.Value} {
+		if values, ok := v.(map[string]interface{}); ok {
+			for name, value := range values {
+				doc[name] = value
+			}
+		}
+	}
+	return doc
+}
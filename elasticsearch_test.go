@@ -0,0 +1,97 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestElasticsearchSink(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		io.WriteString(w, `{"errors":true,"items":[{"index":{"status":201}},{"update":{"status":200}},{"delete":{"status":404}}]}`)
+	}))
+	defer server.Close()
+
+	insert := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId", "Name")
+	update := newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId", "Name")
+	update.ModType = "UPDATE"
+	update.Mods[0].NewValues = spanner.NullJSON{Value: map[string]interface{}{"Name": "bar"}, Valid: true}
+	del := newTestDataChangeRecord(t, "2022-12-04T18:00:02Z", "PlayerId", "Name")
+	del.ModType = "DELETE"
+
+	sink := NewElasticsearchSink(context.Background(), server.URL+"/", "spanner-")
+	if err := sink.Read(newTestReadResult(insert, update, del)); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+
+	expected := `{"index":{"_id":"[\"1\"]","_index":"spanner-players"}}
+{"Name":"foo","PlayerId":"1"}
+{"update":{"_id":"[\"1\"]","_index":"spanner-players"}}
+{"doc":{"Name":"bar","PlayerId":"1"},"doc_as_upsert":true}
+{"delete":{"_id":"[\"1\"]","_index":"spanner-players"}}
+`
+	if diff := cmp.Diff(body, expected); diff != "" {
+		t.Errorf("bulk body has diff = %v", diff)
+	}
+}
+
+func TestElasticsearchSinkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}}]}`)
+	}))
+	defer server.Close()
+
+	sink := NewElasticsearchSink(context.Background(), server.URL, "")
+	err := sink.Read(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")))
+	if err == nil {
+		t.Errorf("Read should return an error")
+	}
+}
+
+func TestElasticsearchDocumentID(t *testing.T) {
+	record := &changestreams.DataChangeRecord{
+		ColumnTypes: []*changestreams.ColumnType{
+			{Name: "Name", OrdinalPosition: 3},
+			{Name: "SingerId", IsPrimaryKey: true, OrdinalPosition: 2},
+			{Name: "AlbumId", IsPrimaryKey: true, OrdinalPosition: 1},
+		},
+	}
+	mod := &changestreams.Mod{
+		Keys: spanner.NullJSON{Value: map[string]interface{}{"SingerId": "1", "AlbumId": "2"}, Valid: true},
+	}
+	got, err := elasticsearchDocumentID(record, mod)
+	if err != nil {
+		t.Fatalf("elasticsearchDocumentID error: %v", err)
+	}
+	if expected := `["2","1"]`; got != expected {
+		t.Errorf("elasticsearchDocumentID = %s, want %s", got, expected)
+	}
+}
@@ -0,0 +1,44 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+// emulatorOptions are the options limited by the Cloud Spanner emulator.
+type emulatorOptions struct {
+	role                string
+	queryStats          bool
+	visualizePartitions bool
+	command             string
+}
+
+// emulatorWarnings returns the warnings of the options which the emulator doesn't support or behaves differently
+// from Cloud Spanner for, so that they don't end up in confusing query errors or misleading results.
+func emulatorWarnings(o emulatorOptions) []string {
+	var warnings []string
+	if o.role != "" {
+		warnings = append(warnings, "The emulator doesn't support fine-grained access control, --role option is ignored")
+	}
+	if o.queryStats {
+		warnings = append(warnings, "The emulator doesn't report the execution statistics of the queries, --query-stats option may log no statistics")
+	}
+	if o.visualizePartitions || o.command == commandDescribeStream {
+		warnings = append(warnings, "The partitions of the emulator don't split and merge like Cloud Spanner by the load, so they don't tell the partitions of production")
+	}
+	if o.command == commandBench {
+		warnings = append(warnings, "The throughput and the latency of the emulator don't tell the ones of Cloud Spanner")
+	}
+	return warnings
+}
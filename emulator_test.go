@@ -0,0 +1,39 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEmulatorWarnings(t *testing.T) {
+	if warnings := emulatorWarnings(emulatorOptions{}); len(warnings) != 0 {
+		t.Errorf("the default options must have no warnings, but got %v", warnings)
+	}
+
+	got := emulatorWarnings(emulatorOptions{role: "reader", queryStats: true, command: commandDescribeStream})
+	expected := []string{
+		"The emulator doesn't support fine-grained access control, --role option is ignored",
+		"The emulator doesn't report the execution statistics of the queries, --query-stats option may log no statistics",
+		"The partitions of the emulator don't split and merge like Cloud Spanner by the load, so they don't tell the partitions of production",
+	}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("warnings have diff = %v", diff)
+	}
+}
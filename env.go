@@ -0,0 +1,76 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// envPrefix is the prefix of the environment variables for the options, e.g. CS_TAIL_FORMAT for --format.
+const envPrefix = "CS_TAIL_"
+
+// envAliases are the environment variables shared with other Cloud Spanner tools, used if the CS_TAIL_ ones are not set.
+var envAliases = map[string]string{
+	"project":       "SPANNER_PROJECT",
+	"instance":      "SPANNER_INSTANCE",
+	"database":      "SPANNER_DATABASE",
+	"emulator-host": "SPANNER_EMULATOR_HOST",
+}
+
+// envName returns the environment variable name for the option.
+func envName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadEnv sets the flags from the environment variables, except for the flags already set on the command line.
+func loadEnv(fs *flag.FlagSet, lookupEnv func(key string) (string, bool)) error {
+	// Short options share the value with their long options.
+	var set []flag.Value
+	fs.Visit(func(f *flag.Flag) {
+		set = append(set, f.Value)
+	})
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || len(f.Name) == 1 {
+			return
+		}
+		for _, s := range set {
+			if s == f.Value {
+				return
+			}
+		}
+
+		key := envName(f.Name)
+		value, ok := lookupEnv(key)
+		if !ok {
+			if alias, found := envAliases[f.Name]; found {
+				key = alias
+				value, ok = lookupEnv(key)
+			}
+		}
+		if !ok {
+			return
+		}
+		if setErr := fs.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid environment variable %s: %w", key, setErr)
+		}
+	})
+	return err
+}
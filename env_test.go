@@ -0,0 +1,76 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadEnv(t *testing.T) {
+	env := map[string]string{
+		"CS_TAIL_PROJECT":      "tail-project",
+		"SPANNER_PROJECT":      "spanner-project",
+		"SPANNER_INSTANCE":     "spanner-instance",
+		"CS_TAIL_DATABASE":     "ignored",
+		"CS_TAIL_STREAM":       "mystream",
+		"CS_TAIL_EMIT_SCHEMA":  "true",
+		"CS_TAIL_GCS_MAX_AGE":  "30m",
+		"CS_TAIL_UNKNOWN_FLAG": "x",
+	}
+	lookupEnv := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+
+	var (
+		projectID, instanceID, databaseID, streamID string
+		emitSchema                                  bool
+		gcsMaxAge                                   time.Duration
+	)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.StringVar(&projectID, "project", "", "")
+	fs.StringVar(&instanceID, "instance", "", "")
+	fs.StringVar(&databaseID, "database", "", "")
+	fs.StringVar(&streamID, "stream", "", "")
+	fs.BoolVar(&emitSchema, "emit-schema", false, "")
+	fs.DurationVar(&gcsMaxAge, "gcs-max-age", time.Hour, "")
+	fs.StringVar(&databaseID, "d", "", "")
+
+	if err := fs.Parse([]string{"-d", "cli"}); err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	if err := loadEnv(fs, lookupEnv); err != nil {
+		t.Fatalf("loadEnv error: %v", err)
+	}
+
+	got := []interface{}{projectID, instanceID, databaseID, streamID, emitSchema, gcsMaxAge}
+	expected := []interface{}{"tail-project", "spanner-instance", "cli", "mystream", true, 30 * time.Minute}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("flags have diff = %v", diff)
+	}
+
+	env["CS_TAIL_GCS_MAX_AGE"] = "invalid"
+	fs = flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.DurationVar(&gcsMaxAge, "gcs-max-age", time.Hour, "")
+	if err := loadEnv(fs, lookupEnv); err == nil {
+		t.Errorf("loadEnv must fail for an invalid value")
+	}
+}
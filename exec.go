@@ -0,0 +1,135 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ExecWriter writes to the standard input of a command run by the shell.
+//
+// When the command exits, it is restarted with exponential backoff on the next Write, and the data that failed to be
+// written is written again to the restarted command. The standard output and error of the command are passed through,
+// and the restarts are logged.
+type ExecWriter struct {
+	command        string
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	backoff        time.Duration
+	stdout         io.Writer
+	stderr         io.Writer
+	logger         *slog.Logger
+	cmd            *exec.Cmd
+	stdin          io.WriteCloser
+	done           chan struct{}
+	mu             sync.Mutex
+}
+
+// NewExecWriter starts the command and creates a new ExecWriter.
+func NewExecWriter(command string, initialBackoff, maxBackoff time.Duration) (*ExecWriter, error) {
+	if initialBackoff <= 0 {
+		initialBackoff = time.Second
+	}
+	if maxBackoff < initialBackoff {
+		maxBackoff = initialBackoff
+	}
+	w := &ExecWriter{
+		command:        command,
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+		backoff:        initialBackoff,
+		stdout:         os.Stdout,
+		stderr:         os.Stderr,
+		logger:         logger,
+		done:           make(chan struct{}),
+	}
+	if err := w.start(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *ExecWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for {
+		if w.stdin == nil {
+			if err := w.restart(); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := w.stdin.Write(p); err == nil {
+			w.backoff = w.initialBackoff
+			return len(p), nil
+		}
+		err := w.cmd.Wait()
+		w.logger.Info("Command exited, restarting", "command", w.command, "error", err, "backoff", w.backoff)
+		w.stdin = nil
+	}
+}
+
+// Close closes the standard input of the command and waits for it to exit.
+func (w *ExecWriter) Close() error {
+	close(w.done)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stdin == nil {
+		return nil
+	}
+	w.stdin.Close()
+	return w.cmd.Wait()
+}
+
+func (w *ExecWriter) start() error {
+	cmd := exec.Command("/bin/sh", "-c", w.command)
+	cmd.Stdout = w.stdout
+	cmd.Stderr = w.stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	w.cmd = cmd
+	w.stdin = stdin
+	return nil
+}
+
+func (w *ExecWriter) restart() error {
+	timer := time.NewTimer(w.backoff)
+	defer timer.Stop()
+	select {
+	case <-w.done:
+		return fmt.Errorf("writer is closed")
+	case <-timer.C:
+	}
+
+	w.backoff *= 2
+	if w.backoff > w.maxBackoff {
+		w.backoff = w.maxBackoff
+	}
+	return w.start()
+}
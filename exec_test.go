@@ -0,0 +1,61 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExecWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	// The command exits after reading each line, so that it is restarted for every write.
+	w, err := NewExecWriter("head -n 1 >> "+path, time.Millisecond, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("NewExecWriter error: %v", err)
+	}
+	var logs bytes.Buffer
+	w.logger = slog.New(slog.NewTextHandler(&logs, nil))
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		// Waits for the command to exit.
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if diff := cmp.Diff(string(got), "aaaa\nbbbb\ncccc\n"); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+	if !bytes.Contains(logs.Bytes(), []byte("Command exited, restarting")) {
+		t.Errorf("restarts must be logged, but got %q", logs.String())
+	}
+}
@@ -0,0 +1,53 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"errors"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"google.golang.org/grpc/codes"
+)
+
+// Exit codes, so that scripts can branch on the class of the failure.
+const (
+	// exitCodeOK is returned when the end timestamp is reached or the tail is interrupted.
+	exitCodeOK = 0
+	// exitCodeError is returned on the other failures, e.g. of the sinks.
+	exitCodeError          = 1
+	exitCodeUsage          = 2
+	exitCodePermission     = 3
+	exitCodeStreamNotFound = 4
+	exitCodeReadFailure    = 5
+)
+
+// errorExitCode returns the exit code for the class of the error, or defaultCode if the error is not classified.
+func errorExitCode(err error, defaultCode int) int {
+	if errors.Is(err, changestreams.ErrStreamNotFound) {
+		return exitCodeStreamNotFound
+	}
+	if errors.Is(err, changestreams.ErrStartBeforeRetention) {
+		return exitCodeUsage
+	}
+	switch spanner.ErrCode(err) {
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return exitCodePermission
+	default:
+		return defaultCode
+	}
+}
@@ -0,0 +1,83 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorExitCode(t *testing.T) {
+	tests := []struct {
+		desc string
+		err  error
+		want int
+	}{
+		{
+			desc: "stream not found",
+			err:  fmt.Errorf("failed: %w", changestreams.ErrStreamNotFound),
+			want: exitCodeStreamNotFound,
+		},
+		{
+			desc: "start before retention",
+			err:  fmt.Errorf("failed: %w", changestreams.ErrStartBeforeRetention),
+			want: exitCodeUsage,
+		},
+		{
+			desc: "database not found",
+			err:  status.Error(codes.NotFound, "Database not found"),
+			want: exitCodeReadFailure,
+		},
+		{
+			desc: "permission denied",
+			err:  status.Error(codes.PermissionDenied, "permission denied"),
+			want: exitCodePermission,
+		},
+		{
+			desc: "unauthenticated",
+			err:  status.Error(codes.Unauthenticated, "unauthenticated"),
+			want: exitCodePermission,
+		},
+		{
+			desc: "permission denied in a partition",
+			err:  &changestreams.PartitionError{StreamID: "s", PartitionToken: "a", Err: status.Error(codes.PermissionDenied, "permission denied")},
+			want: exitCodePermission,
+		},
+		{
+			desc: "unavailable",
+			err:  status.Error(codes.Unavailable, "unavailable"),
+			want: exitCodeReadFailure,
+		},
+		{
+			desc: "other error",
+			err:  errors.New("error"),
+			want: exitCodeReadFailure,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			if got := errorExitCode(test.err, exitCodeReadFailure); got != test.want {
+				t.Errorf("errorExitCode() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
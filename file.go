@@ -0,0 +1,146 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileWriter writes to a local file, rotating it by size or age.
+//
+// Rotated files are renamed after the time they were opened (e.g. changes.jsonl to changes-20220519T064915.093823000Z.jsonl),
+// and optionally compressed with gzip in the background. The last file is rotated on Close as well.
+type FileWriter struct {
+	*rotatingWriter
+	path     string
+	compress bool
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	err      error
+}
+
+// NewFileWriter creates a new FileWriter. If the file already exists (e.g. left by a crashed process),
+// it is rotated before writing.
+func NewFileWriter(path string, maxSize int64, maxAge time.Duration, compress bool) (*FileWriter, error) {
+	w := &FileWriter{
+		path:     path,
+		compress: compress,
+	}
+	if info, err := os.Stat(path); err == nil {
+		if err := w.rotate(info.ModTime()); err != nil {
+			return nil, err
+		}
+	}
+	w.rotatingWriter = newRotatingWriter(w.open, maxSize, maxAge)
+	return w, nil
+}
+
+// Close closes and rotates the current file, and waits for the background compression.
+func (w *FileWriter) Close() error {
+	err := w.rotatingWriter.Close()
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err == nil {
+		err = w.err
+	}
+	return err
+}
+
+func (w *FileWriter) open(t time.Time) (io.WriteCloser, error) {
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &rotatedFile{File: f, openedAt: t, writer: w}, nil
+}
+
+func (w *FileWriter) rotate(openedAt time.Time) error {
+	rotatedPath := rotatedFilePath(w.path, openedAt)
+	if err := os.Rename(w.path, rotatedPath); err != nil {
+		return err
+	}
+	if !w.compress {
+		return nil
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if err := gzipFile(rotatedPath); err != nil {
+			w.mu.Lock()
+			if w.err == nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// rotatedFile is a file that is rotated when closed.
+type rotatedFile struct {
+	*os.File
+	openedAt time.Time
+	writer   *FileWriter
+}
+
+func (f *rotatedFile) Close() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	return f.writer.rotate(f.openedAt)
+}
+
+func rotatedFilePath(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + t.UTC().Format("20060102T150405.000000000Z") + ext
+}
+
+// gzipFile compresses the file into path.gz and removes the original file.
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
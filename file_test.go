@@ -0,0 +1,101 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFileWriter(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		compress bool
+		pattern  string
+	}{
+		{desc: "plain", compress: false, pattern: "changes-*.jsonl"},
+		{desc: "gzip", compress: true, pattern: "changes-*.jsonl.gz"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "changes.jsonl")
+			// Left by a previous run.
+			if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			w, err := NewFileWriter(path, 10, 0, test.compress)
+			if err != nil {
+				t.Fatalf("NewFileWriter error: %v", err)
+			}
+			for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n"} {
+				if _, err := w.Write([]byte(line)); err != nil {
+					t.Fatalf("Write error: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close error: %v", err)
+			}
+
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%s must be rotated on close", path)
+			}
+			files, err := filepath.Glob(filepath.Join(dir, test.pattern))
+			if err != nil {
+				t.Fatalf("Glob error: %v", err)
+			}
+			sort.Strings(files)
+
+			var got []string
+			for _, file := range files {
+				got = append(got, readTestFile(t, file, test.compress))
+			}
+			expected := []string{"old\n", "aaaa\nbbbb\n", "cccc\n"}
+			if diff := cmp.Diff(got, expected); diff != "" {
+				t.Errorf("rotated files have diff = %v", diff)
+			}
+		})
+	}
+}
+
+func readTestFile(t *testing.T, path string, compressed bool) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if compressed {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	return string(b)
+}
@@ -0,0 +1,99 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// GCSWriter writes to Cloud Storage objects, rotating them by size or interval
+// into time partitioned paths (gs://bucket/prefix/yyyy/mm/dd/hh/).
+type GCSWriter struct {
+	*rotatingWriter
+	client *storage.Client
+}
+
+// NewGCSWriter creates a new GCSWriter. gcsPath must be in the form of gs://bucket[/prefix].
+// ext is appended to the object names (e.g. ".jsonl").
+func NewGCSWriter(ctx context.Context, gcsPath, ext string, maxSize int64, maxAge time.Duration, opts ...option.ClientOption) (*GCSWriter, error) {
+	bucket, prefix, err := parseGCSPath(gcsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	b := client.Bucket(bucket)
+	open := func(t time.Time) (io.WriteCloser, error) {
+		w := b.Object(gcsObjectName(prefix, ext, t)).NewWriter(ctx)
+		w.ContentType = contentType(ext)
+		return w, nil
+	}
+	return &GCSWriter{
+		rotatingWriter: newRotatingWriter(open, maxSize, maxAge),
+		client:         client,
+	}, nil
+}
+
+// Close finalizes the current object and closes the underlying client.
+func (w *GCSWriter) Close() error {
+	err := w.rotatingWriter.Close()
+	if closeErr := w.client.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func gcsObjectName(prefix, ext string, t time.Time) string {
+	t = t.UTC()
+	return path.Join(prefix, t.Format("2006/01/02/15"), t.Format("20060102T150405.000000000Z")+ext)
+}
+
+func parseGCSPath(gcsPath string) (bucket, prefix string, err error) {
+	if !strings.HasPrefix(gcsPath, "gs://") {
+		return "", "", fmt.Errorf("invalid GCS path %q, must be in the form of gs://bucket[/prefix]", gcsPath)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(gcsPath, "gs://"), "/", 2)
+	if parts[0] == "" {
+		return "", "", fmt.Errorf("invalid GCS path %q, must be in the form of gs://bucket[/prefix]", gcsPath)
+	}
+	bucket = parts[0]
+	if len(parts) == 2 {
+		prefix = strings.Trim(parts[1], "/")
+	}
+	return bucket, prefix, nil
+}
+
+func contentType(ext string) string {
+	switch ext {
+	case ".jsonl":
+		return "application/x-ndjson"
+	default:
+		return "text/plain"
+	}
+}
@@ -0,0 +1,59 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+)
+
+func TestParseGCSPath(t *testing.T) {
+	for _, test := range []struct {
+		gcsPath        string
+		expectedBucket string
+		expectedPrefix string
+		wantErr        bool
+	}{
+		{gcsPath: "gs://bucket", expectedBucket: "bucket"},
+		{gcsPath: "gs://bucket/", expectedBucket: "bucket"},
+		{gcsPath: "gs://bucket/a/b/", expectedBucket: "bucket", expectedPrefix: "a/b"},
+		{gcsPath: "gs://", wantErr: true},
+		{gcsPath: "bucket/a", wantErr: true},
+	} {
+		bucket, prefix, err := parseGCSPath(test.gcsPath)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseGCSPath(%q) should return an error", test.gcsPath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseGCSPath(%q) error: %v", test.gcsPath, err)
+			continue
+		}
+		if bucket != test.expectedBucket || prefix != test.expectedPrefix {
+			t.Errorf("parseGCSPath(%q) = (%q, %q), want (%q, %q)", test.gcsPath, bucket, prefix, test.expectedBucket, test.expectedPrefix)
+		}
+	}
+}
+
+func TestGCSObjectName(t *testing.T) {
+	got := gcsObjectName("changes", ".jsonl", mustParseTime(t, "2022-12-04T18:30:00Z"))
+	expected := "changes/2022/12/04/18/20221204T183000.000000000Z.jsonl"
+	if got != expected {
+		t.Errorf("gcsObjectName = %q, want %q", got, expected)
+	}
+}
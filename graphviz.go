@@ -17,9 +17,13 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -30,17 +34,48 @@ const (
 	rootPartitionToken = "root"
 )
 
+// Formats of the partition visualization.
+const (
+	vizFormatDOT     = "dot"
+	vizFormatMermaid = "mermaid"
+	vizFormatJSON    = "json"
+	vizFormatGantt   = "gantt"
+	vizFormatHTML    = "html"
+	vizFormatCSV     = "csv"
+)
+
+var vizFormats = []string{vizFormatDOT, vizFormatMermaid, vizFormatJSON, vizFormatGantt, vizFormatHTML, vizFormatCSV}
+
 type Partition struct {
 	Token          string
 	StartTimestamp time.Time
+	// EndTimestamp is when the partition finished, i.e. the start timestamp of its children.
+	// It's a zero value if the partition didn't finish in the time range.
+	EndTimestamp   time.Time
 	RecordSequence string
 	Parents        []*Partition
+	// DataChangeRecords and HeartbeatRecords are the numbers of the records read from the partition.
+	DataChangeRecords int64
+	HeartbeatRecords  int64
+	// LastTimestamp is the last commit timestamp or heartbeat timestamp read from the partition.
+	LastTimestamp time.Time
 }
 
+// Statuses of the partitions in the watermark overlay.
+const (
+	partitionStatusOK        = "ok"
+	partitionStatusLagged    = "lagged"
+	partitionStatusNoRecords = "no_records"
+)
+
 type PartitionVisualizer struct {
 	partitions map[string]*Partition
-	mu         sync.Mutex
-	out        io.Writer
+	// If windowEnd is set, each partition is annotated with the lag of its last timestamp from the window end, and
+	// the partitions lagged more than lagThreshold or without any records are highlighted.
+	windowEnd    time.Time
+	lagThreshold time.Duration
+	mu           sync.Mutex
+	out          io.Writer
 }
 
 func NewPartitionVisualizer(out io.Writer) *PartitionVisualizer {
@@ -58,7 +93,25 @@ func (v *PartitionVisualizer) Read(result *changestreams.ReadResult) error {
 	defer v.mu.Unlock()
 
 	for _, changeRecord := range result.ChangeRecords {
+		if result.PartitionToken != "" && (len(changeRecord.DataChangeRecords) > 0 || len(changeRecord.HeartbeatRecords) > 0) {
+			partition := v.partition(result.PartitionToken)
+			partition.DataChangeRecords += int64(len(changeRecord.DataChangeRecords))
+			partition.HeartbeatRecords += int64(len(changeRecord.HeartbeatRecords))
+			for _, r := range changeRecord.DataChangeRecords {
+				if r.CommitTimestamp.After(partition.LastTimestamp) {
+					partition.LastTimestamp = r.CommitTimestamp
+				}
+			}
+			for _, r := range changeRecord.HeartbeatRecords {
+				if r.Timestamp.After(partition.LastTimestamp) {
+					partition.LastTimestamp = r.Timestamp
+				}
+			}
+		}
 		for _, partitionRecord := range changeRecord.ChildPartitionsRecords {
+			if result.PartitionToken != "" {
+				v.finishPartition(result.PartitionToken, partitionRecord.StartTimestamp)
+			}
 			for _, childPartition := range partitionRecord.ChildPartitions {
 				token := childPartition.Token
 				if _, ok := v.partitions[token]; ok {
@@ -94,6 +147,50 @@ func (v *PartitionVisualizer) Read(result *changestreams.ReadResult) error {
 	return nil
 }
 
+// partition returns the partition of the token, adding it if it's not known yet.
+func (v *PartitionVisualizer) partition(token string) *Partition {
+	partition, ok := v.partitions[token]
+	if !ok {
+		partition = &Partition{Token: token}
+		v.partitions[token] = partition
+	}
+	return partition
+}
+
+// finishPartition records the end timestamp of the partition returning the child partitions.
+func (v *PartitionVisualizer) finishPartition(token string, ts time.Time) {
+	partition := v.partition(token)
+	if partition.EndTimestamp.IsZero() || ts.Before(partition.EndTimestamp) {
+		partition.EndTimestamp = ts
+	}
+}
+
+// watermark returns the lag of the last timestamp of the partition from the window end, or from its end if it finished
+// earlier, and its status. ok is false if the overlay is disabled or the start of the partition is unknown.
+func (v *PartitionVisualizer) watermark(partition *Partition) (lag time.Duration, status string, ok bool) {
+	if v.windowEnd.IsZero() || partition.StartTimestamp.IsZero() {
+		return 0, "", false
+	}
+	end := v.windowEnd
+	if !partition.EndTimestamp.IsZero() && partition.EndTimestamp.Before(end) {
+		end = partition.EndTimestamp
+	}
+	if partition.LastTimestamp.IsZero() {
+		return end.Sub(partition.StartTimestamp), partitionStatusNoRecords, true
+	}
+	lag = end.Sub(partition.LastTimestamp)
+	if lag > v.lagThreshold {
+		return lag, partitionStatusLagged, true
+	}
+	return lag, partitionStatusOK, true
+}
+
+// vizColors are the colors to highlight the partitions by status.
+var vizColors = map[string]string{
+	partitionStatusLagged:    "orange",
+	partitionStatusNoRecords: "lightgray",
+}
+
 func (v *PartitionVisualizer) Draw() {
 	fmt.Fprintf(v.out, "digraph {\n")
 	fmt.Fprintf(v.out, "  node [shape=record];\n")
@@ -103,7 +200,17 @@ func (v *PartitionVisualizer) Draw() {
 		if !partition.StartTimestamp.IsZero() {
 			timestamp = partition.StartTimestamp.Format(time.RFC3339)
 		}
-		fmt.Fprintf(v.out, `  "%s" [label="{token|start_timestamp|record_sequence}|{{%s}|{%s}|{%s}}"];`, partition.Token, partition.Token, timestamp, partition.RecordSequence)
+		names := "token|start_timestamp|record_sequence|data_change_records|heartbeat_records"
+		values := fmt.Sprintf("{%s}|{%s}|{%s}|{%d}|{%d}", partition.Token, timestamp, partition.RecordSequence, partition.DataChangeRecords, partition.HeartbeatRecords)
+		var style string
+		if lag, status, ok := v.watermark(partition); ok {
+			names += "|last_timestamp|lag"
+			values += fmt.Sprintf("|{%s}|{%v}", formatTimestamp(partition.LastTimestamp), lag)
+			if color, ok := vizColors[status]; ok {
+				style = fmt.Sprintf(`, style=filled, fillcolor="%s"`, color)
+			}
+		}
+		fmt.Fprintf(v.out, `  "%s" [label="{%s}|{%s}"%s];`, partition.Token, names, values, style)
 		fmt.Fprintln(v.out, "")
 	}
 	for _, partition := range partitions {
@@ -115,6 +222,283 @@ func (v *PartitionVisualizer) Draw() {
 	fmt.Fprintf(v.out, "}\n")
 }
 
+// DrawMermaid draws the partitions as a Mermaid flowchart, which is rendered in Markdown of GitHub and GitLab.
+// The nodes have sequential IDs, as the tokens may contain the characters of the Mermaid syntax.
+func (v *PartitionVisualizer) DrawMermaid() {
+	fmt.Fprintf(v.out, "flowchart TD\n")
+	partitions := sortPartitions(v.partitions)
+	ids := make(map[*Partition]string, len(partitions))
+	for i, partition := range partitions {
+		ids[partition] = fmt.Sprintf("p%d", i)
+		var timestamp string
+		if !partition.StartTimestamp.IsZero() {
+			timestamp = partition.StartTimestamp.Format(time.RFC3339)
+		}
+		label := partition.Token
+		if timestamp != "" || partition.RecordSequence != "" {
+			label += "<br/>" + timestamp + "<br/>" + partition.RecordSequence
+		}
+		if partition.DataChangeRecords > 0 || partition.HeartbeatRecords > 0 {
+			label += fmt.Sprintf("<br/>records: %d, heartbeats: %d", partition.DataChangeRecords, partition.HeartbeatRecords)
+		}
+		var color string
+		if lag, status, ok := v.watermark(partition); ok {
+			label += fmt.Sprintf("<br/>last: %s, lag: %v", formatTimestamp(partition.LastTimestamp), lag)
+			color = vizColors[status]
+		}
+		fmt.Fprintf(v.out, "  %s[\"%s\"]\n", ids[partition], label)
+		if color != "" {
+			fmt.Fprintf(v.out, "  style %s fill:%s\n", ids[partition], color)
+		}
+	}
+	for _, partition := range partitions {
+		for _, parent := range partition.Parents {
+			fmt.Fprintf(v.out, "  %s --> %s\n", ids[parent], ids[partition])
+		}
+	}
+}
+
+// partitionGraph is the partitions in JSON format.
+type partitionGraph struct {
+	Nodes []partitionNode `json:"nodes"`
+	Edges []partitionEdge `json:"edges"`
+}
+
+type partitionNode struct {
+	Token             string     `json:"token"`
+	StartTimestamp    *time.Time `json:"start_timestamp,omitempty"`
+	EndTimestamp      *time.Time `json:"end_timestamp,omitempty"`
+	RecordSequence    string     `json:"record_sequence,omitempty"`
+	DataChangeRecords int64      `json:"data_change_records"`
+	HeartbeatRecords  int64      `json:"heartbeat_records"`
+	LastTimestamp     *time.Time `json:"last_timestamp,omitempty"`
+	LagSeconds        *float64   `json:"lag_seconds,omitempty"`
+	Status            string     `json:"status,omitempty"`
+}
+
+type partitionEdge struct {
+	Parent string `json:"parent"`
+	Child  string `json:"child"`
+}
+
+// DrawJSON writes the partitions as a JSON graph of the nodes and the edges from the parents to the children, to be
+// analyzed by other tools.
+func (v *PartitionVisualizer) DrawJSON() error {
+	graph := partitionGraph{Nodes: []partitionNode{}, Edges: []partitionEdge{}}
+	partitions := sortPartitions(v.partitions)
+	for _, partition := range partitions {
+		node := partitionNode{
+			Token:             partition.Token,
+			RecordSequence:    partition.RecordSequence,
+			DataChangeRecords: partition.DataChangeRecords,
+			HeartbeatRecords:  partition.HeartbeatRecords,
+		}
+		if !partition.StartTimestamp.IsZero() {
+			node.StartTimestamp = &partition.StartTimestamp
+		}
+		if !partition.EndTimestamp.IsZero() {
+			node.EndTimestamp = &partition.EndTimestamp
+		}
+		if !partition.LastTimestamp.IsZero() {
+			node.LastTimestamp = &partition.LastTimestamp
+		}
+		if lag, status, ok := v.watermark(partition); ok {
+			node.LagSeconds = lagSeconds(&lag)
+			node.Status = status
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for _, partition := range partitions {
+		for _, parent := range partition.Parents {
+			graph.Edges = append(graph.Edges, partitionEdge{Parent: parent.Token, Child: partition.Token})
+		}
+	}
+	return json.NewEncoder(v.out).Encode(graph)
+}
+
+// DrawCSV writes the metadata of the partitions as CSV with a header, for offline analysis e.g. in notebooks. The parent
+// tokens are separated by spaces, and the unknown timestamps are empty.
+func (v *PartitionVisualizer) DrawCSV() error {
+	w := csv.NewWriter(v.out)
+	if err := w.Write([]string{"token", "parents", "start_timestamp", "record_sequence", "end_timestamp", "data_change_records", "heartbeat_records", "last_timestamp"}); err != nil {
+		return err
+	}
+	for _, partition := range sortPartitions(v.partitions) {
+		parents := make([]string, 0, len(partition.Parents))
+		for _, parent := range partition.Parents {
+			parents = append(parents, parent.Token)
+		}
+		record := []string{
+			partition.Token,
+			strings.Join(parents, " "),
+			formatTimestamp(partition.StartTimestamp),
+			partition.RecordSequence,
+			formatTimestamp(partition.EndTimestamp),
+			strconv.FormatInt(partition.DataChangeRecords, 10),
+			strconv.FormatInt(partition.HeartbeatRecords, 10),
+			formatTimestamp(partition.LastTimestamp),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// DrawGantt draws the partitions as a Mermaid Gantt chart, with a bar of each partition from its start to its end, to
+// show the timing of the splits and the merges and the parallelism. The partitions not finished in the time range end at
+// the last known timestamp, and the ones whose start is unknown, e.g. the root, are omitted.
+func (v *PartitionVisualizer) DrawGantt() {
+	const layout = "2006-01-02T15:04:05"
+	last := v.lastTimestamp()
+
+	partitions := sortPartitions(v.partitions)
+	sort.SliceStable(partitions, func(i, j int) bool {
+		return partitions[i].StartTimestamp.Before(partitions[j].StartTimestamp)
+	})
+	fmt.Fprintf(v.out, "gantt\n")
+	fmt.Fprintf(v.out, "  dateFormat YYYY-MM-DDTHH:mm:ss\n")
+	fmt.Fprintf(v.out, "  axisFormat %%H:%%M\n")
+	fmt.Fprintf(v.out, "  section Partitions\n")
+	for i, partition := range partitions {
+		if partition.StartTimestamp.IsZero() {
+			continue
+		}
+		end := partition.EndTimestamp
+		if end.IsZero() {
+			end = last
+		}
+		// The lagged partitions are highlighted as critical.
+		var tags string
+		if _, status, ok := v.watermark(partition); ok && status != partitionStatusOK {
+			tags = "crit, "
+		}
+		fmt.Fprintf(v.out, "  %s :%sp%d, %s, %s\n", partition.Token, tags, i,
+			partition.StartTimestamp.UTC().Format(layout), end.UTC().Format(layout))
+	}
+}
+
+// lastTimestamp returns the last known start or end timestamp of the partitions.
+func (v *PartitionVisualizer) lastTimestamp() time.Time {
+	var last time.Time
+	for _, partition := range v.partitions {
+		for _, ts := range []time.Time{partition.StartTimestamp, partition.EndTimestamp} {
+			if ts.After(last) {
+				last = ts
+			}
+		}
+	}
+	return last
+}
+
+// partitionSummary is the statistics of the splits and the merges of the partitions.
+type partitionSummary struct {
+	// Partitions is the number of the partitions whose start is known.
+	Partitions int
+	// Splits is the number of the partitions split into multiple children, and Merges is the number of the partitions
+	// merged from multiple parents.
+	Splits int
+	Merges int
+	// AvgLifetime is the average time from the start to the end of the finished partitions.
+	AvgLifetime time.Duration
+	// MaxConcurrent is the maximum number of the partitions alive at the same time.
+	MaxConcurrent int
+}
+
+// Summary returns the statistics of the splits and the merges. The partitions not finished end at the last known
+// timestamp, as in DrawGantt.
+func (v *PartitionVisualizer) Summary() partitionSummary {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	var summary partitionSummary
+	children := make(map[*Partition]int)
+	for _, partition := range v.partitions {
+		for _, parent := range partition.Parents {
+			children[parent]++
+		}
+		if len(partition.Parents) > 1 {
+			summary.Merges++
+		}
+	}
+	for parent, n := range children {
+		// The initial partitions are not split from the root.
+		if parent.Token != rootPartitionToken && n > 1 {
+			summary.Splits++
+		}
+	}
+
+	type event struct {
+		ts    time.Time
+		delta int
+	}
+	var events []event
+	var lifetime time.Duration
+	var finished int
+	last := v.lastTimestamp()
+	for _, partition := range v.partitions {
+		if partition.StartTimestamp.IsZero() {
+			continue
+		}
+		summary.Partitions++
+		end := partition.EndTimestamp
+		if end.IsZero() {
+			end = last
+		} else {
+			lifetime += end.Sub(partition.StartTimestamp)
+			finished++
+		}
+		events = append(events, event{partition.StartTimestamp, 1}, event{end, -1})
+	}
+	if finished > 0 {
+		summary.AvgLifetime = lifetime / time.Duration(finished)
+	}
+
+	// The parents end when the children start, so the ends come first at the same timestamp.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].ts.Equal(events[j].ts) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].ts.Before(events[j].ts)
+	})
+	var concurrent int
+	for _, e := range events {
+		concurrent += e.delta
+		summary.MaxConcurrent = max(summary.MaxConcurrent, concurrent)
+	}
+	return summary
+}
+
+// Render draws the partitions in the format.
+func (v *PartitionVisualizer) Render(format string) error {
+	switch format {
+	case vizFormatDOT:
+		v.Draw()
+	case vizFormatMermaid:
+		v.DrawMermaid()
+	case vizFormatJSON:
+		return v.DrawJSON()
+	case vizFormatGantt:
+		v.DrawGantt()
+	case vizFormatHTML:
+		return v.DrawHTML()
+	case vizFormatCSV:
+		return v.DrawCSV()
+	default:
+		return fmt.Errorf("invalid visualization format: %s", format)
+	}
+	return nil
+}
+
+// formatTimestamp formats the timestamp in RFC 3339, or returns an empty string if it's unknown.
+func formatTimestamp(ts time.Time) string {
+	if ts.IsZero() {
+		return ""
+	}
+	return ts.Format(time.RFC3339Nano)
+}
+
 func sortPartitions(partitionsMap map[string]*Partition) []*Partition {
 	var partitions []*Partition
 	for _, p := range partitionsMap {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"bytes"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,139 +22,323 @@ func TestPartitionVisualizer(t *testing.T) {
 			readResults: []*changestreams.ReadResult{},
 			expected: `digraph {
   node [shape=record];
-  "root" [label="{token|start_timestamp|record_sequence}|{{root}|{}|{}}"];
+  "root" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records}|{{root}|{}|{}|{0}|{0}}"];
 }
 `,
 		},
 		{
-			desc: "simple split/join results",
-			readResults: []*changestreams.ReadResult{
+			desc:        "simple split/join results",
+			readResults: newTestPartitionResults(t),
+			expected: `digraph {
+  node [shape=record];
+  "a" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records}|{{a}|{2022-12-04T18:00:00Z}|{00000001}|{0}|{0}}"];
+  "b" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records}|{{b}|{2022-12-04T19:00:00Z}|{00000001}|{0}|{0}}"];
+  "c" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records}|{{c}|{2022-12-04T19:00:00Z}|{00000002}|{0}|{0}}"];
+  "d" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records}|{{d}|{2022-12-04T20:00:00Z}|{00000001}|{2}|{1}}"];
+  "root" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records}|{{root}|{}|{}|{0}|{0}}"];
+  "root" -> "a"
+  "a" -> "b"
+  "a" -> "c"
+  "b" -> "d"
+  "c" -> "d"
+}
+`,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			visualizer := NewPartitionVisualizer(&out)
+			for _, r := range test.readResults {
+				visualizer.Read(r)
+			}
+			visualizer.Draw()
+
+			if diff := cmp.Diff(out.String(), test.expected); diff != "" {
+				t.Errorf("visualizer has diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestPartitionVisualizerWatermark(t *testing.T) {
+	var out bytes.Buffer
+	visualizer := NewPartitionVisualizer(&out)
+	visualizer.windowEnd = mustParseTime(t, "2022-12-04T20:00:10Z")
+	visualizer.lagThreshold = 5 * time.Second
+	for _, r := range newTestPartitionResults(t) {
+		visualizer.Read(r)
+	}
+	visualizer.Draw()
+
+	// a, b and c didn't produce any records before their ends, and d lagged 7s behind the window end.
+	expected := `digraph {
+  node [shape=record];
+  "a" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records|last_timestamp|lag}|{{a}|{2022-12-04T18:00:00Z}|{00000001}|{0}|{0}|{}|{1h0m0s}}", style=filled, fillcolor="lightgray"];
+  "b" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records|last_timestamp|lag}|{{b}|{2022-12-04T19:00:00Z}|{00000001}|{0}|{0}|{}|{1h0m0s}}", style=filled, fillcolor="lightgray"];
+  "c" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records|last_timestamp|lag}|{{c}|{2022-12-04T19:00:00Z}|{00000002}|{0}|{0}|{}|{1h0m0s}}", style=filled, fillcolor="lightgray"];
+  "d" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records|last_timestamp|lag}|{{d}|{2022-12-04T20:00:00Z}|{00000001}|{2}|{1}|{2022-12-04T20:00:03Z}|{7s}}", style=filled, fillcolor="orange"];
+  "root" [label="{token|start_timestamp|record_sequence|data_change_records|heartbeat_records}|{{root}|{}|{}|{0}|{0}}"];
+  "root" -> "a"
+  "a" -> "b"
+  "a" -> "c"
+  "b" -> "d"
+  "c" -> "d"
+}
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("visualizer has diff = %v", diff)
+	}
+}
+
+func TestPartitionVisualizerMermaid(t *testing.T) {
+	var out bytes.Buffer
+	visualizer := NewPartitionVisualizer(&out)
+	for _, r := range newTestPartitionResults(t) {
+		visualizer.Read(r)
+	}
+	if err := visualizer.Render(vizFormatMermaid); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	expected := `flowchart TD
+  p0["a<br/>2022-12-04T18:00:00Z<br/>00000001"]
+  p1["b<br/>2022-12-04T19:00:00Z<br/>00000001"]
+  p2["c<br/>2022-12-04T19:00:00Z<br/>00000002"]
+  p3["d<br/>2022-12-04T20:00:00Z<br/>00000001<br/>records: 2, heartbeats: 1"]
+  p4["root"]
+  p4 --> p0
+  p0 --> p1
+  p0 --> p2
+  p1 --> p3
+  p2 --> p3
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("visualizer has diff = %v", diff)
+	}
+}
+
+func TestPartitionVisualizerJSON(t *testing.T) {
+	var out bytes.Buffer
+	visualizer := NewPartitionVisualizer(&out)
+	for _, r := range newTestPartitionResults(t) {
+		visualizer.Read(r)
+	}
+	if err := visualizer.Render(vizFormatJSON); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	expected := `{"nodes":[{"token":"a","start_timestamp":"2022-12-04T18:00:00Z","end_timestamp":"2022-12-04T19:00:00Z","record_sequence":"00000001","data_change_records":0,"heartbeat_records":0},{"token":"b","start_timestamp":"2022-12-04T19:00:00Z","end_timestamp":"2022-12-04T20:00:00Z","record_sequence":"00000001","data_change_records":0,"heartbeat_records":0},{"token":"c","start_timestamp":"2022-12-04T19:00:00Z","end_timestamp":"2022-12-04T20:00:00Z","record_sequence":"00000002","data_change_records":0,"heartbeat_records":0},{"token":"d","start_timestamp":"2022-12-04T20:00:00Z","record_sequence":"00000001","data_change_records":2,"heartbeat_records":1,"last_timestamp":"2022-12-04T20:00:03Z"},{"token":"root","data_change_records":0,"heartbeat_records":0}],"edges":[{"parent":"root","child":"a"},{"parent":"a","child":"b"},{"parent":"a","child":"c"},{"parent":"b","child":"d"},{"parent":"c","child":"d"}]}
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("visualizer has diff = %v", diff)
+	}
+}
+
+func TestPartitionVisualizerGantt(t *testing.T) {
+	var out bytes.Buffer
+	visualizer := NewPartitionVisualizer(&out)
+	for _, r := range newTestPartitionResults(t) {
+		visualizer.Read(r)
+	}
+	if err := visualizer.Render(vizFormatGantt); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	// d is not finished, so it ends at the last known timestamp.
+	expected := `gantt
+  dateFormat YYYY-MM-DDTHH:mm:ss
+  axisFormat %H:%M
+  section Partitions
+  a :p1, 2022-12-04T18:00:00, 2022-12-04T19:00:00
+  b :p2, 2022-12-04T19:00:00, 2022-12-04T20:00:00
+  c :p3, 2022-12-04T19:00:00, 2022-12-04T20:00:00
+  d :p4, 2022-12-04T20:00:00, 2022-12-04T20:00:00
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("visualizer has diff = %v", diff)
+	}
+}
+
+// newTestPartitionResults returns the results of the partitions split from a to b and c, and merged into d.
+// d returns two data change records and a heartbeat record.
+func newTestPartitionResults(t *testing.T) []*changestreams.ReadResult {
+	return []*changestreams.ReadResult{
+		{
+			PartitionToken: "",
+			ChangeRecords: []*changestreams.ChangeRecord{
 				{
-					PartitionToken: "",
-					ChangeRecords: []*changestreams.ChangeRecord{
+					ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
 						{
-							ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
+							StartTimestamp: mustParseTime(t, "2022-12-04T18:00:00Z"),
+							RecordSequence: "00000001",
+							ChildPartitions: []*changestreams.ChildPartition{
 								{
-									StartTimestamp: mustParseTime(t, "2022-12-04T18:00:00Z"),
-									RecordSequence: "00000001",
-									ChildPartitions: []*changestreams.ChildPartition{
-										{
-											Token:                 "a",
-											ParentPartitionTokens: []string{},
-										},
-									},
+									Token:                 "a",
+									ParentPartitionTokens: []string{},
 								},
 							},
 						},
 					},
 				},
+			},
+		},
+		{
+			PartitionToken: "a",
+			ChangeRecords: []*changestreams.ChangeRecord{
 				{
-					PartitionToken: "a",
-					ChangeRecords: []*changestreams.ChangeRecord{
+					ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
 						{
-							ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
+							StartTimestamp: mustParseTime(t, "2022-12-04T19:00:00Z"),
+							RecordSequence: "00000001",
+							ChildPartitions: []*changestreams.ChildPartition{
 								{
-									StartTimestamp: mustParseTime(t, "2022-12-04T19:00:00Z"),
-									RecordSequence: "00000001",
-									ChildPartitions: []*changestreams.ChildPartition{
-										{
-											Token:                 "b",
-											ParentPartitionTokens: []string{"a"},
-										},
-									},
+									Token:                 "b",
+									ParentPartitionTokens: []string{"a"},
 								},
+							},
+						},
+						{
+							StartTimestamp: mustParseTime(t, "2022-12-04T19:00:00Z"),
+							RecordSequence: "00000002",
+							ChildPartitions: []*changestreams.ChildPartition{
 								{
-									StartTimestamp: mustParseTime(t, "2022-12-04T19:00:00Z"),
-									RecordSequence: "00000002",
-									ChildPartitions: []*changestreams.ChildPartition{
-										{
-											Token:                 "c",
-											ParentPartitionTokens: []string{"a"},
-										},
-									},
+									Token:                 "c",
+									ParentPartitionTokens: []string{"a"},
 								},
 							},
 						},
 					},
 				},
+			},
+		},
+		{
+			PartitionToken: "b",
+			ChangeRecords: []*changestreams.ChangeRecord{
 				{
-					PartitionToken: "b",
-					ChangeRecords: []*changestreams.ChangeRecord{
+					ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
 						{
-							ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
+							StartTimestamp: mustParseTime(t, "2022-12-04T20:00:00Z"),
+							RecordSequence: "00000001",
+							ChildPartitions: []*changestreams.ChildPartition{
 								{
-									StartTimestamp: mustParseTime(t, "2022-12-04T20:00:00Z"),
-									RecordSequence: "00000001",
-									ChildPartitions: []*changestreams.ChildPartition{
-										{
-											Token:                 "d",
-											ParentPartitionTokens: []string{"b", "c"},
-										},
-									},
+									Token:                 "d",
+									ParentPartitionTokens: []string{"b", "c"},
 								},
 							},
 						},
 					},
 				},
+			},
+		},
+		{
+			PartitionToken: "c",
+			ChangeRecords: []*changestreams.ChangeRecord{
 				{
-					PartitionToken: "c",
-					ChangeRecords: []*changestreams.ChangeRecord{
+					ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
 						{
-							ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{
+							StartTimestamp: mustParseTime(t, "2022-12-04T20:00:00Z"),
+							RecordSequence: "00000001",
+							ChildPartitions: []*changestreams.ChildPartition{
 								{
-									StartTimestamp: mustParseTime(t, "2022-12-04T20:00:00Z"),
-									RecordSequence: "00000001",
-									ChildPartitions: []*changestreams.ChildPartition{
-										{
-											Token:                 "d",
-											ParentPartitionTokens: []string{"b", "c"},
-										},
-									},
+									Token:                 "d",
+									ParentPartitionTokens: []string{"b", "c"},
 								},
 							},
 						},
 					},
 				},
+			},
+		},
+		{
+			PartitionToken: "d",
+			ChangeRecords: []*changestreams.ChangeRecord{
 				{
-					PartitionToken: "d",
-					ChangeRecords:  []*changestreams.ChangeRecord{},
+					DataChangeRecords: []*changestreams.DataChangeRecord{
+						newTestDataChangeRecord(t, "2022-12-04T20:00:01Z", "PlayerId"),
+						newTestDataChangeRecord(t, "2022-12-04T20:00:02Z", "PlayerId"),
+					},
+					HeartbeatRecords: []*changestreams.HeartbeatRecord{
+						{Timestamp: mustParseTime(t, "2022-12-04T20:00:03Z")},
+					},
 				},
 			},
-			expected: `digraph {
-  node [shape=record];
-  "a" [label="{token|start_timestamp|record_sequence}|{{a}|{2022-12-04T18:00:00Z}|{00000001}}"];
-  "b" [label="{token|start_timestamp|record_sequence}|{{b}|{2022-12-04T19:00:00Z}|{00000001}}"];
-  "c" [label="{token|start_timestamp|record_sequence}|{{c}|{2022-12-04T19:00:00Z}|{00000002}}"];
-  "d" [label="{token|start_timestamp|record_sequence}|{{d}|{2022-12-04T20:00:00Z}|{00000001}}"];
-  "root" [label="{token|start_timestamp|record_sequence}|{{root}|{}|{}}"];
-  "root" -> "a"
-  "a" -> "b"
-  "a" -> "c"
-  "b" -> "d"
-  "c" -> "d"
-}
-`,
 		},
-	} {
-		t.Run(test.desc, func(t *testing.T) {
-			var out bytes.Buffer
-			visualizer := NewPartitionVisualizer(&out)
-			for _, r := range test.readResults {
-				visualizer.Read(r)
-			}
-			visualizer.Draw()
-
-			if diff := cmp.Diff(out.String(), test.expected); diff != "" {
-				t.Errorf("visualizer has diff = %v", diff)
-			}
-		})
 	}
 }
 
-func mustParseTime(t *testing.T, s string) time.Time {
+func mustParseTime(t testing.TB, s string) time.Time {
 	parsed, err := time.ParseInLocation(time.RFC3339, s, time.UTC)
 	if err != nil {
 		t.Fatalf("failed to parse time: %v", err)
 	}
 	return parsed
 }
+
+func TestPartitionVisualizerHTML(t *testing.T) {
+	var out bytes.Buffer
+	visualizer := NewPartitionVisualizer(&out)
+	for _, r := range newTestPartitionResults(t) {
+		visualizer.Read(r)
+	}
+	if err := visualizer.Render(vizFormatHTML); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	got := out.String()
+	for _, expected := range []string{
+		`<svg id="graph" viewBox="0 0 536 400"`,
+		// root is in the first row, and d is in the last row below b and c.
+		`<rect x="16" y="16" width="240" height="56" rx="4"/><text x="16" y="16" dx="8" dy="22">root</text>`,
+		`<rect x="16" y="328" width="240" height="56" rx="4"/><text x="16" y="328" dx="8" dy="22">d</text><text x="16" y="328" dx="8" dy="42">records: 2, heartbeats: 1</text>`,
+		"<title>token: a\nstart_timestamp: 2022-12-04T18:00:00Z\nend_timestamp: 2022-12-04T19:00:00Z\nrecord_sequence: 00000001\ndata_change_records: 0\nheartbeat_records: 0</title>",
+		// From b to d.
+		`<line x1="136" y1="280" x2="136" y2="328"/>`,
+	} {
+		if !strings.Contains(got, expected) {
+			t.Errorf("HTML report must contain %q, but got:\n%s", expected, got)
+		}
+	}
+	if n := strings.Count(got, "<line "); n != 5 {
+		t.Errorf("HTML report has %d edges, want 5", n)
+	}
+}
+
+func TestPartitionVisualizerSummary(t *testing.T) {
+	visualizer := NewPartitionVisualizer(io.Discard)
+	for _, r := range newTestPartitionResults(t) {
+		visualizer.Read(r)
+	}
+
+	expected := partitionSummary{
+		Partitions:    4,
+		Splits:        1,
+		Merges:        1,
+		AvgLifetime:   time.Hour,
+		MaxConcurrent: 2,
+	}
+	if diff := cmp.Diff(visualizer.Summary(), expected); diff != "" {
+		t.Errorf("summary has diff = %v", diff)
+	}
+}
+
+func TestPartitionVisualizerCSV(t *testing.T) {
+	var out bytes.Buffer
+	visualizer := NewPartitionVisualizer(&out)
+	for _, r := range newTestPartitionResults(t) {
+		visualizer.Read(r)
+	}
+	if err := visualizer.Render(vizFormatCSV); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	expected := `token,parents,start_timestamp,record_sequence,end_timestamp,data_change_records,heartbeat_records,last_timestamp
+a,root,2022-12-04T18:00:00Z,00000001,2022-12-04T19:00:00Z,0,0,
+b,a,2022-12-04T19:00:00Z,00000001,2022-12-04T20:00:00Z,0,0,
+c,a,2022-12-04T19:00:00Z,00000002,2022-12-04T20:00:00Z,0,0,
+d,b c,2022-12-04T20:00:00Z,00000001,,2,1,2022-12-04T20:00:03Z
+root,,,,,0,0,
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("visualizer has diff = %v", diff)
+	}
+}
@@ -0,0 +1,111 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/tailpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// tailServer implements the Tail gRPC service on top of Broadcaster.
+type tailServer struct {
+	tailpb.UnimplementedTailServer
+	broadcaster *Broadcaster
+}
+
+func (s *tailServer) Tail(req *tailpb.TailRequest, stream tailpb.Tail_TailServer) error {
+	sub := s.broadcaster.Subscribe(newRecordFilter(req.GetTables(), req.GetModTypes()))
+	defer s.broadcaster.Unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case r, ok := <-sub.records:
+			if !ok {
+				if s.broadcaster.Overflowed(sub) {
+					return status.Error(codes.ResourceExhausted, "client is too slow to receive the records")
+				}
+				return nil
+			}
+			record, err := toRecordProto(r.streamID, r.partitionToken, r.record)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to convert the record: %v", err)
+			}
+			if err := stream.Send(record); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toRecordProto(streamID, partitionToken string, r *changestreams.DataChangeRecord) (*tailpb.Record, error) {
+	record := &tailpb.Record{
+		StreamId:                             streamID,
+		PartitionToken:                       partitionToken,
+		CommitTimestamp:                      timestamppb.New(r.CommitTimestamp),
+		RecordSequence:                       r.RecordSequence,
+		ServerTransactionId:                  r.ServerTransactionID,
+		IsLastRecordInTransactionInPartition: r.IsLastRecordInTransactionInPartition,
+		TableName:                            r.TableName,
+		ModType:                              r.ModType,
+		ValueCaptureType:                     r.ValueCaptureType,
+		NumberOfRecordsInTransaction:         r.NumberOfRecordsInTransaction,
+		NumberOfPartitionsInTransaction:      r.NumberOfPartitionsInTransaction,
+		TransactionTag:                       r.TransactionTag,
+		IsSystemTransaction:                  r.IsSystemTransaction,
+	}
+	for _, columnType := range r.ColumnTypes {
+		typ, err := structpb.NewValue(columnType.Type.Value)
+		if err != nil {
+			return nil, err
+		}
+		record.ColumnTypes = append(record.ColumnTypes, &tailpb.ColumnType{
+			Name:            columnType.Name,
+			Type:            typ,
+			IsPrimaryKey:    columnType.IsPrimaryKey,
+			OrdinalPosition: columnType.OrdinalPosition,
+		})
+	}
+	for _, mod := range r.Mods {
+		m := &tailpb.Mod{}
+		for _, v := range []struct {
+			value interface{}
+			dst   **structpb.Struct
+		}{
+			{mod.Keys.Value, &m.Keys},
+			{mod.NewValues.Value, &m.NewValues},
+			{mod.OldValues.Value, &m.OldValues},
+		} {
+			values, ok := v.value.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			s, err := structpb.NewStruct(values)
+			if err != nil {
+				return nil, err
+			}
+			*v.dst = s
+		}
+		record.Mods = append(record.Mods, m)
+	}
+	return record, nil
+}
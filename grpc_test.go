@@ -0,0 +1,164 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/tailpb"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func startTestTailServer(t *testing.T, broadcaster *Broadcaster) tailpb.TailClient {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer()
+	tailpb.RegisterTailServer(server, &tailServer{broadcaster: broadcaster})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return tailpb.NewTailClient(conn)
+}
+
+func waitForSubscribers(t *testing.T, broadcaster *Broadcaster, n int) {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		broadcaster.mu.Lock()
+		count := len(broadcaster.subscriptions)
+		broadcaster.mu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d subscribers", n)
+}
+
+func TestTailServer(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	broadcaster := NewBroadcaster()
+	client := startTestTailServer(t, broadcaster)
+
+	stream, err := client.Tail(ctx, &tailpb.TailRequest{ModTypes: []string{"DELETE"}})
+	if err != nil {
+		t.Fatalf("Tail error: %v", err)
+	}
+	waitForSubscribers(t, broadcaster, 1)
+
+	insert := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+	del := newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId")
+	del.ModType = "DELETE"
+	if err := broadcaster.Read(newTestReadResult(insert, del)); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	broadcaster.Close()
+
+	var got []string
+	for {
+		record, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv error: %v", err)
+		}
+		got = append(got, record.GetModType()+" "+record.GetCommitTimestamp().AsTime().Format(time.RFC3339)+" "+record.GetMods()[0].GetKeys().GetFields()["PlayerId"].GetStringValue())
+	}
+	expected := []string{"DELETE 2022-12-04T18:00:01Z 1"}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("records have diff = %v", diff)
+	}
+}
+
+func TestTailServerStreams(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	broadcaster := NewBroadcaster()
+	client := startTestTailServer(t, broadcaster)
+
+	stream, err := client.Tail(ctx, &tailpb.TailRequest{})
+	if err != nil {
+		t.Fatalf("Tail error: %v", err)
+	}
+	waitForSubscribers(t, broadcaster, 1)
+
+	for _, streamID := range []string{"stream1", "stream2"} {
+		result := newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))
+		result.StreamID = streamID
+		if err := broadcaster.Read(result); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+	broadcaster.Close()
+
+	var got []string
+	for {
+		record, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv error: %v", err)
+		}
+		got = append(got, record.GetStreamId()+" "+record.GetPartitionToken())
+	}
+	expected := []string{"stream1 a", "stream2 a"}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("records have diff = %v", diff)
+	}
+}
+
+func TestBroadcasterOverflow(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	sub := broadcaster.Subscribe(newRecordFilter(nil, nil))
+
+	record := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		if err := broadcaster.Read(newTestReadResult(record)); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+	if !broadcaster.Overflowed(sub) {
+		t.Errorf("subscription must be overflowed")
+	}
+	count := 0
+	for range sub.records {
+		count++
+	}
+	if count != subscriptionBufferSize {
+		t.Errorf("got %d records, want %d", count, subscriptionBufferSize)
+	}
+}
@@ -0,0 +1,153 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// defaultHealthStallTimeout is the default time the watermark may stay still before the tail is reported unhealthy.
+const defaultHealthStallTimeout = 5 * time.Minute
+
+// healthChecker reports whether the partitions are being read and the watermark is advancing, so that an orchestrator
+// such as Kubernetes can restart a wedged tail. The watermark is the last timestamp of the slowest partition, which
+// advances by the heartbeat records even while no data change records arrive.
+type healthChecker struct {
+	stallTimeout time.Duration
+	now          func() time.Time
+
+	// partitions is the last timestamp of the active partitions.
+	partitions map[partitionKey]time.Time
+	watermark  time.Time
+	// advancedAt is when the watermark advanced last, or when the checker was created.
+	advancedAt time.Time
+	mu         sync.Mutex
+}
+
+func newHealthChecker(stallTimeout time.Duration) *healthChecker {
+	return &healthChecker{
+		stallTimeout: stallTimeout,
+		now:          time.Now,
+		partitions:   make(map[partitionKey]time.Time),
+		advancedAt:   time.Now(),
+	}
+}
+
+// observe updates the partitions and the watermark by the result.
+func (h *healthChecker) observe(result *changestreams.ReadResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := partitionKey{streamID: result.StreamID, token: result.PartitionToken}
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			h.touchPartition(key, r.CommitTimestamp)
+		}
+		for _, r := range changeRecord.HeartbeatRecords {
+			h.touchPartition(key, r.Timestamp)
+		}
+		if len(changeRecord.ChildPartitionsRecords) > 0 {
+			// The partition finishes after returning the child partitions.
+			delete(h.partitions, key)
+		}
+	}
+
+	var watermark time.Time
+	for _, ts := range h.partitions {
+		if watermark.IsZero() || ts.Before(watermark) {
+			watermark = ts
+		}
+	}
+	if watermark.After(h.watermark) {
+		h.watermark = watermark
+		h.advancedAt = h.now()
+	}
+}
+
+func (h *healthChecker) touchPartition(key partitionKey, ts time.Time) {
+	if key.token == "" {
+		// The initial query is not a partition.
+		return
+	}
+	if ts.After(h.partitions[key]) {
+		h.partitions[key] = ts
+	}
+}
+
+// healthy returns an error if the watermark has not advanced for the stall timeout.
+func (h *healthChecker) healthy() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if stalled := h.now().Sub(h.advancedAt); stalled > h.stallTimeout {
+		return fmt.Errorf("the watermark has not advanced for %v", stalled.Round(time.Second))
+	}
+	return nil
+}
+
+// ready returns an error if no partitions are being read.
+func (h *healthChecker) ready() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.partitions) == 0 {
+		return errors.New("no partitions are being read")
+	}
+	return nil
+}
+
+// handler returns the handler of /healthz and /readyz, which respond 503 Service Unavailable with the reason on failure.
+func (h *healthChecker) handler() http.Handler {
+	check := func(f func() error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if err := f(); err != nil {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprintln(w, err)
+				return
+			}
+			fmt.Fprintln(w, "ok")
+		}
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", check(h.healthy))
+	mux.Handle("/readyz", check(h.ready))
+	return mux
+}
+
+// serveHealth serves the health checks on /healthz and /readyz of the address in the background, and returns the
+// function to stop it.
+func serveHealth(addr string, h *healthChecker) (func(), error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	server := &http.Server{Handler: h.handler()}
+	go server.Serve(lis)
+	logger.Info("Serving the health checks", "url", fmt.Sprintf("http://%s/healthz", lis.Addr()))
+	return func() {
+		server.Shutdown(context.Background())
+	}, nil
+}
@@ -0,0 +1,78 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+func TestHealthChecker(t *testing.T) {
+	now := mustParseTime(t, "2022-12-04T18:00:00Z")
+	h := newHealthChecker(time.Minute)
+	h.now = func() time.Time { return now }
+	h.advancedAt = now
+	server := httptest.NewServer(h.handler())
+	defer server.Close()
+
+	heartbeat := func(token, ts string) *changestreams.ReadResult {
+		return &changestreams.ReadResult{
+			StreamID:       "s",
+			PartitionToken: token,
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{HeartbeatRecords: []*changestreams.HeartbeatRecord{{Timestamp: mustParseTime(t, ts)}}},
+			},
+		}
+	}
+	check := func(path string, wantStatus int, wantBody string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("failed to get: %v", err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("failed to read: %v", err)
+		}
+		if resp.StatusCode != wantStatus || string(body) != wantBody {
+			t.Errorf("%s = %d %q, want %d %q", path, resp.StatusCode, body, wantStatus, wantBody)
+		}
+	}
+
+	// Healthy but not ready until the partitions are read.
+	check("/healthz", http.StatusOK, "ok\n")
+	check("/readyz", http.StatusServiceUnavailable, "no partitions are being read\n")
+
+	h.observe(heartbeat("a", "2022-12-04T18:00:00Z"))
+	h.observe(heartbeat("b", "2022-12-04T18:00:00Z"))
+	check("/readyz", http.StatusOK, "ok\n")
+
+	// The watermark is held back by the slowest partition b.
+	now = now.Add(50 * time.Second)
+	h.observe(heartbeat("a", "2022-12-04T18:00:50Z"))
+	now = now.Add(20 * time.Second)
+	check("/healthz", http.StatusServiceUnavailable, "the watermark has not advanced for 1m10s\n")
+
+	h.observe(heartbeat("b", "2022-12-04T18:01:10Z"))
+	check("/healthz", http.StatusOK, "ok\n")
+}
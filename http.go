@@ -0,0 +1,139 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// sseKeepAliveInterval is the interval of the comment lines sent to keep idle connections open through proxies.
+const sseKeepAliveInterval = 15 * time.Second
+
+// newHTTPHandler returns the handler serving the data change records as JSON
+// over Server-Sent Events (/events) and WebSocket (/ws).
+//
+// Records can be filtered with the "table" and "mod_type" query parameters,
+// which can be repeated or comma separated.
+func newHTTPHandler(broadcaster *Broadcaster) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		serveSSE(w, r, broadcaster)
+	})
+	mux.Handle("/ws", websocket.Handler(func(conn *websocket.Conn) {
+		serveWebSocket(conn, broadcaster)
+	}))
+	return mux
+}
+
+func serveSSE(w http.ResponseWriter, r *http.Request, broadcaster *Broadcaster) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := broadcaster.Subscribe(httpRecordFilter(r))
+	defer broadcaster.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case record, ok := <-sub.records:
+			if !ok {
+				if broadcaster.Overflowed(sub) {
+					fmt.Fprint(w, "event: error\ndata: client is too slow to receive the records\n\n")
+					flusher.Flush()
+				}
+				return
+			}
+			b, err := json.Marshal(taggedRecord{StreamID: record.streamID, DataChangeRecord: record.record})
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", b)
+			flusher.Flush()
+		}
+	}
+}
+
+func serveWebSocket(conn *websocket.Conn, broadcaster *Broadcaster) {
+	defer conn.Close()
+
+	sub := broadcaster.Subscribe(httpRecordFilter(conn.Request()))
+	defer broadcaster.Unsubscribe(sub)
+
+	// Detect the client closing the connection. Messages from the client are ignored.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var msg []byte
+		for {
+			if err := websocket.Message.Receive(conn, &msg); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case record, ok := <-sub.records:
+			if !ok {
+				return
+			}
+			if err := websocket.JSON.Send(conn, taggedRecord{StreamID: record.streamID, DataChangeRecord: record.record}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func httpRecordFilter(r *http.Request) *recordFilter {
+	query := r.URL.Query()
+	return newRecordFilter(splitQueryValues(query["table"]), splitQueryValues(query["mod_type"]))
+}
+
+func splitQueryValues(values []string) []string {
+	var result []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s != "" {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/net/websocket"
+)
+
+func TestHTTPHandlerSSE(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	server := httptest.NewServer(newHTTPHandler(broadcaster))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/events?table=Players&mod_type=UPDATE,DELETE")
+	if err != nil {
+		t.Fatalf("failed to get: %v", err)
+	}
+	defer resp.Body.Close()
+	if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	waitForSubscribers(t, broadcaster, 1)
+
+	insert := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+	del := newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId")
+	del.ModType = "DELETE"
+	result := newTestReadResult(insert, del)
+	result.StreamID = "stream1"
+	if err := broadcaster.Read(result); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	broadcaster.Close()
+
+	var got []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "data: ") {
+			got = append(got, line)
+		}
+	}
+	if len(got) != 1 || !strings.Contains(got[0], `"stream_id":"stream1"`) || !strings.Contains(got[0], `"commit_timestamp":"2022-12-04T18:00:01Z"`) {
+		t.Errorf("unexpected events: %v", got)
+	}
+}
+
+func TestHTTPHandlerWebSocket(t *testing.T) {
+	broadcaster := NewBroadcaster()
+	server := httptest.NewServer(newHTTPHandler(broadcaster))
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?mod_type=INSERT"
+	conn, err := websocket.Dial(wsURL, "", server.URL)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	waitForSubscribers(t, broadcaster, 1)
+
+	insert := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+	del := newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId")
+	del.ModType = "DELETE"
+	result := newTestReadResult(del, insert)
+	result.StreamID = "stream2"
+	if err := broadcaster.Read(result); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+
+	var got struct {
+		StreamID string `json:"stream_id"`
+		changestreams.DataChangeRecord
+	}
+	if err := websocket.JSON.Receive(conn, &got); err != nil {
+		t.Fatalf("failed to receive: %v", err)
+	}
+	if diff := cmp.Diff([]string{got.StreamID, got.ModType, got.TableName}, []string{"stream2", "INSERT", "Players"}); diff != "" {
+		t.Errorf("record has diff = %v", diff)
+	}
+}
+
+func TestSplitQueryValues(t *testing.T) {
+	got := splitQueryValues([]string{"a,b", "c", ""})
+	if diff := cmp.Diff(got, []string{"a", "b", "c"}); diff != "" {
+		t.Errorf("splitQueryValues has diff = %v", diff)
+	}
+}
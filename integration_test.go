@@ -19,8 +19,10 @@ package main
 import (
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -330,3 +332,217 @@ func TestReader(t *testing.T) {
 		})
 	}
 }
+
+func TestStreamsForTable(t *testing.T) {
+	if skipIntegrateTest {
+		t.Skip("integration tests skipped")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutPerTest)
+	defer cancel()
+
+	setupResult, err := setup(ctx, t)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer func() {
+		if err := setupResult.tearDown(); err != nil {
+			t.Fatalf("failed to tear down: %v", err)
+		}
+	}()
+
+	streams, err := changestreams.StreamsForTable(ctx, setupResult.client, setupResult.tableID)
+	if err != nil {
+		t.Fatalf("StreamsForTable error: %v", err)
+	}
+	var found bool
+	for _, stream := range streams {
+		if stream == setupResult.streamID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("stream %q is not found in %v", setupResult.streamID, streams)
+	}
+}
+
+func TestStreams(t *testing.T) {
+	if skipIntegrateTest {
+		t.Skip("integration tests skipped")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutPerTest)
+	defer cancel()
+
+	setupResult, err := setup(ctx, t)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer func() {
+		if err := setupResult.tearDown(); err != nil {
+			t.Fatalf("failed to tear down: %v", err)
+		}
+	}()
+
+	streams, err := changestreams.Streams(ctx, setupResult.client)
+	if err != nil {
+		t.Fatalf("Streams error: %v", err)
+	}
+	var found bool
+	for _, stream := range streams {
+		if stream == setupResult.streamID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("stream %q is not found in %v", setupResult.streamID, streams)
+	}
+}
+
+func TestTables(t *testing.T) {
+	if skipIntegrateTest {
+		t.Skip("integration tests skipped")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutPerTest)
+	defer cancel()
+
+	setupResult, err := setup(ctx, t)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer func() {
+		if err := setupResult.tearDown(); err != nil {
+			t.Fatalf("failed to tear down: %v", err)
+		}
+	}()
+
+	tables, err := changestreams.Tables(ctx, setupResult.client)
+	if err != nil {
+		t.Fatalf("Tables error: %v", err)
+	}
+	var found bool
+	for _, table := range tables {
+		if table == setupResult.tableID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("table %q is not found in %v", setupResult.tableID, tables)
+	}
+}
+
+func TestDescribeStream(t *testing.T) {
+	if skipIntegrateTest {
+		t.Skip("integration tests skipped")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutPerTest)
+	defer cancel()
+
+	setupResult, err := setup(ctx, t)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer func() {
+		if err := setupResult.tearDown(); err != nil {
+			t.Fatalf("failed to tear down: %v", err)
+		}
+	}()
+
+	d, err := changestreams.DescribeStream(ctx, setupResult.client, setupResult.streamID)
+	if err != nil {
+		t.Fatalf("DescribeStream error: %v", err)
+	}
+	expected := &changestreams.StreamDescription{
+		Name:             setupResult.streamID,
+		Tables:           []changestreams.WatchedTable{{Name: setupResult.tableID, AllColumns: true}},
+		ValueCaptureType: changestreams.DefaultValueCaptureType,
+		RetentionPeriod:  changestreams.DefaultRetentionPeriod,
+	}
+	if d.Partitions == 0 {
+		t.Errorf("partitions must be counted")
+	}
+	d.Partitions = 0
+	if diff := cmp.Diff(d, expected); diff != "" {
+		t.Errorf("description has diff = %v", diff)
+	}
+}
+
+func TestNewReaderStreamNotFound(t *testing.T) {
+	if skipIntegrateTest {
+		t.Skip("integration tests skipped")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutPerTest)
+	defer cancel()
+
+	setupResult, err := setup(ctx, t)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer func() {
+		if err := setupResult.tearDown(); err != nil {
+			t.Fatalf("failed to tear down: %v", err)
+		}
+	}()
+
+	_, err = changestreams.NewReaderWithClient(ctx, setupResult.client, setupResult.streamID+"_typo", changestreams.Config{})
+	if !errors.Is(err, changestreams.ErrStreamNotFound) {
+		t.Fatalf("NewReaderWithClient of a missing stream must fail with ErrStreamNotFound, but got %v", err)
+	}
+	if !strings.Contains(err.Error(), setupResult.streamID) {
+		t.Errorf("error must list the existing streams, but got %v", err)
+	}
+}
+
+func TestReaderMaxConcurrentPartitions(t *testing.T) {
+	if skipIntegrateTest {
+		t.Skip("integration tests skipped")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeoutPerTest)
+	defer cancel()
+
+	setupResult, err := setup(ctx, t)
+	if err != nil {
+		t.Fatalf("failed to setup: %v", err)
+	}
+	defer func() {
+		if err := setupResult.tearDown(); err != nil {
+			t.Fatalf("failed to tear down: %v", err)
+		}
+	}()
+
+	start := time.Now()
+	if _, err := setupResult.client.Apply(ctx, []*spanner.Mutation{
+		spanner.Insert(setupResult.tableID, []string{"id", "active"}, []interface{}{1, true}),
+	}); err != nil {
+		t.Fatalf("failed to add test data: %v", err)
+	}
+
+	reader, err := changestreams.NewReaderWithClient(ctx, setupResult.client, setupResult.streamID, changestreams.Config{
+		StartTimestamp:          start,
+		EndTimestamp:            time.Now().Add(time.Second),
+		MaxConcurrentPartitions: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to create a reader: %v", err)
+	}
+	defer reader.Close()
+
+	var modTypes []string
+	if err := reader.Read(ctx, func(result *changestreams.ReadResult) error {
+		for _, changeRecord := range result.ChangeRecords {
+			for _, r := range changeRecord.DataChangeRecords {
+				modTypes = append(modTypes, r.ModType)
+			}
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if diff := cmp.Diff(modTypes, []string{"INSERT"}); diff != "" {
+		t.Errorf("mod types have diff = %v", diff)
+	}
+}
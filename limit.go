@@ -0,0 +1,77 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// limitSink stops reading after the limited number of data change records are written to the underlying sink.
+type limitSink struct {
+	changestreams.Sink
+	remaining int
+	mu        sync.Mutex
+}
+
+func newLimitSink(sink changestreams.Sink, limit int) *limitSink {
+	return &limitSink{Sink: sink, remaining: limit}
+}
+
+func (s *limitSink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.remaining <= 0 {
+		return changestreams.ErrStop
+	}
+	limited := result
+	if result.RawChangeRecord != nil {
+		// The raw change record can't be truncated, so the limit is applied to the whole result, which may exceed it.
+		s.remaining -= len(changestreams.DataChangeRecordsOf([]*changestreams.ReadResult{result}))
+	} else {
+		limited = &changestreams.ReadResult{StreamID: result.StreamID, PartitionToken: result.PartitionToken}
+		complete := true
+		for _, changeRecord := range result.ChangeRecords {
+			if s.remaining == 0 {
+				complete = false
+				break
+			}
+			if len(changeRecord.DataChangeRecords) > s.remaining {
+				truncated := *changeRecord
+				truncated.DataChangeRecords = changeRecord.DataChangeRecords[:s.remaining]
+				changeRecord = &truncated
+				complete = false
+			}
+			s.remaining -= len(changeRecord.DataChangeRecords)
+			limited.ChangeRecords = append(limited.ChangeRecords, changeRecord)
+		}
+		// The row is of the whole result, so it's passed only if the result is not truncated.
+		if complete {
+			limited.Row = result.Row
+		}
+	}
+
+	if err := s.Sink.Write(limited); err != nil {
+		return err
+	}
+	if s.remaining <= 0 {
+		return changestreams.ErrStop
+	}
+	return nil
+}
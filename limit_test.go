@@ -0,0 +1,105 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+type recordingSink struct {
+	timestamps []string
+	results    []*changestreams.ReadResult
+}
+
+func (s *recordingSink) Open(ctx context.Context) error { return nil }
+func (s *recordingSink) Flush() error                   { return nil }
+func (s *recordingSink) Close() error                   { return nil }
+
+func (s *recordingSink) Write(result *changestreams.ReadResult) error {
+	s.results = append(s.results, result)
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			s.timestamps = append(s.timestamps, r.CommitTimestamp.Format("15:04"))
+		}
+	}
+	return nil
+}
+
+func TestLimitSink(t *testing.T) {
+	recorder := &recordingSink{}
+	sink := newLimitSink(recorder, 3)
+
+	if err := sink.Write(newTestReadResult(
+		newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"),
+		newTestDataChangeRecord(t, "2022-12-04T18:01:00Z", "PlayerId"),
+	)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := sink.Write(newTestReadResult(
+		newTestDataChangeRecord(t, "2022-12-04T18:02:00Z", "PlayerId"),
+		newTestDataChangeRecord(t, "2022-12-04T18:03:00Z", "PlayerId"),
+	)); !errors.Is(err, changestreams.ErrStop) {
+		t.Fatalf("Write must return ErrStop when reaching the limit, got %v", err)
+	}
+	if err := sink.Write(newTestReadResult(
+		newTestDataChangeRecord(t, "2022-12-04T18:04:00Z", "PlayerId"),
+	)); !errors.Is(err, changestreams.ErrStop) {
+		t.Fatalf("Write must return ErrStop after the limit, got %v", err)
+	}
+
+	if diff := cmp.Diff(recorder.timestamps, []string{"18:00", "18:01", "18:02"}); diff != "" {
+		t.Errorf("written records have diff = %v", diff)
+	}
+}
+
+func TestLimitSinkRawChangeRecord(t *testing.T) {
+	recorder := &recordingSink{}
+	sink := newLimitSink(recorder, 2)
+
+	result := newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))
+	result.RawChangeRecord = []byte(`[{"data_change_record":[{}]}]`)
+	if err := sink.Write(result); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	crossing := newTestReadResult(
+		newTestDataChangeRecord(t, "2022-12-04T18:01:00Z", "PlayerId"),
+		newTestDataChangeRecord(t, "2022-12-04T18:02:00Z", "PlayerId"),
+	)
+	crossing.RawChangeRecord = []byte(`[{"data_change_record":[{},{}]}]`)
+	if err := sink.Write(crossing); !errors.Is(err, changestreams.ErrStop) {
+		t.Fatalf("Write must return ErrStop when reaching the limit, got %v", err)
+	}
+
+	// The result crossing the limit is written as a whole, since its raw change record can't be truncated.
+	written := recorder.results
+	if len(written) != 2 {
+		t.Fatalf("written results = %d, want 2", len(written))
+	}
+	for i, r := range []*changestreams.ReadResult{result, crossing} {
+		if got := string(written[i].RawChangeRecord); got != string(r.RawChangeRecord) {
+			t.Errorf("raw change record of result %d = %s, want %s", i, got, r.RawChangeRecord)
+		}
+		if got, want := len(changestreams.DataChangeRecordsOf(written[i:i+1])), len(changestreams.DataChangeRecordsOf([]*changestreams.ReadResult{r})); got != want {
+			t.Errorf("data change records of result %d = %d, want %d", i, got, want)
+		}
+	}
+}
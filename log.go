@@ -17,24 +17,53 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
 )
 
 const (
-	formatText = "text"
-	formatJSON = "json"
+	formatText   = "text"
+	formatJSON   = "json"
+	formatLogfmt = "logfmt"
+	// formatRaw writes the change record column of each row as is, which is read without decoding the mods.
+	formatRaw = "raw"
 )
 
+// formatExtension returns the file extension for the output format.
+func formatExtension(format string, verbose bool) string {
+	if verbose {
+		return ".jsonl"
+	}
+	switch format {
+	case formatJSON, formatRaw:
+		return ".jsonl"
+	case formatLogfmt:
+		return ".log"
+	default:
+		return ".txt"
+	}
+}
+
 type Logger struct {
 	out     io.Writer
 	format  string
 	verbose bool
-	mu      sync.Mutex
+	// If schemas is set, a schema record is emitted before the data change records of each table.
+	schemas *schemaTracker
+	// If streamTag is set, each data change record is tagged with the stream ID, e.g. when reading multiple streams.
+	streamTag bool
+	// If annotateLag is set, each data change record is annotated with the lag from the commit timestamp to now.
+	annotateLag bool
+	now         func() time.Time
+	mu          sync.Mutex
 }
 
 func (l *Logger) Read(result *changestreams.ReadResult) error {
@@ -42,23 +71,58 @@ func (l *Logger) Read(result *changestreams.ReadResult) error {
 	defer l.mu.Unlock()
 
 	if l.verbose {
-		return json.NewEncoder(l.out).Encode(result)
+		for _, changeRecord := range result.ChangeRecords {
+			for _, r := range changeRecord.DataChangeRecords {
+				if err := l.updateSchema(r, formatJSON); err != nil {
+					return err
+				}
+			}
+		}
+		return encodeJSON(l.out, result)
+	}
+	if l.format == formatRaw {
+		return l.writeRaw(result)
 	}
 
 	// Only prints the data change records.
 	for _, changeRecord := range result.ChangeRecords {
 		for _, r := range changeRecord.DataChangeRecords {
+			if err := l.updateSchema(r, l.format); err != nil {
+				return err
+			}
+			var streamID string
+			if l.streamTag {
+				streamID = result.StreamID
+			}
+			var lag *time.Duration
+			if l.annotateLag {
+				d := l.lag(r)
+				lag = &d
+			}
 			switch l.format {
 			case formatJSON:
-				if err := json.NewEncoder(l.out).Encode(r); err != nil {
+				var v interface{} = r
+				if streamID != "" || lag != nil {
+					v = taggedRecord{StreamID: streamID, LagSeconds: lagSeconds(lag), DataChangeRecord: r}
+				}
+				if err := encodeJSON(l.out, v); err != nil {
 					return err
 				}
 			case formatText:
-				modsJSON, err := json.Marshal(r.Mods)
-				if err != nil {
+				if err := writeTextRecord(l.out, streamID, lag, r); err != nil {
+					return err
+				}
+			case formatLogfmt:
+				var tags []logfmtField
+				if streamID != "" {
+					tags = append(tags, logfmtField{"stream_id", streamID})
+				}
+				if lag != nil {
+					tags = append(tags, logfmtField{"lag_seconds", strconv.FormatFloat(lag.Seconds(), 'f', -1, 64)})
+				}
+				if err := writeLogfmtRecord(l.out, tags, r); err != nil {
 					return err
 				}
-				fmt.Fprintf(l.out, "%s | %s | %s | %s\n", r.CommitTimestamp, r.ModType, r.TableName, modsJSON)
 			default:
 				return fmt.Errorf("invalid format: %s", l.format)
 			}
@@ -67,3 +131,149 @@ func (l *Logger) Read(result *changestreams.ReadResult) error {
 
 	return nil
 }
+
+// writeRaw writes the raw change record of the row followed by a newline, if it has data change records.
+func (l *Logger) writeRaw(result *changestreams.ReadResult) error {
+	if !hasDataChangeRecords(result) {
+		return nil
+	}
+	if result.RawChangeRecord == nil {
+		return errors.New("raw change record is missing, the records must be read with raw JSON")
+	}
+	e := getJSONEncoder()
+	defer putJSONEncoder(e)
+	e.buf.Write(result.RawChangeRecord)
+	e.buf.WriteByte('\n')
+	_, err := l.out.Write(e.buf.Bytes())
+	return err
+}
+
+func hasDataChangeRecords(result *changestreams.ReadResult) bool {
+	for _, changeRecord := range result.ChangeRecords {
+		if len(changeRecord.DataChangeRecords) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonEncoder is a JSON encoder writing into its own buffer. The encoders are pooled, as allocating them per record is
+// a bottleneck at tens of thousands of records per second.
+type jsonEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// maxPooledBufferSize is the maximum size of the buffers returned to the pool, not to retain the large ones.
+const maxPooledBufferSize = 64 << 10
+
+var jsonEncoderPool = sync.Pool{
+	New: func() interface{} {
+		e := &jsonEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		return e
+	},
+}
+
+func getJSONEncoder() *jsonEncoder {
+	e := jsonEncoderPool.Get().(*jsonEncoder)
+	e.buf.Reset()
+	return e
+}
+
+func putJSONEncoder(e *jsonEncoder) {
+	if e.buf.Cap() <= maxPooledBufferSize {
+		jsonEncoderPool.Put(e)
+	}
+}
+
+// encodeJSON writes v in JSON followed by a newline to out with a single write.
+func encodeJSON(out io.Writer, v interface{}) error {
+	e := getJSONEncoder()
+	defer putJSONEncoder(e)
+	if err := e.enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := out.Write(e.buf.Bytes())
+	return err
+}
+
+// writeTextRecord writes the data change record in the text format with a single write.
+func writeTextRecord(out io.Writer, streamID string, lag *time.Duration, r *changestreams.DataChangeRecord) error {
+	e := getJSONEncoder()
+	defer putJSONEncoder(e)
+	e.buf.WriteString(r.CommitTimestamp.String())
+	if streamID != "" {
+		e.buf.WriteString(" | " + streamID)
+	}
+	e.buf.WriteString(" | " + r.ModType + " | " + r.TableName + " | ")
+	if err := e.enc.Encode(r.Mods); err != nil {
+		return err
+	}
+	// Trims the newline of the encoder.
+	e.buf.Truncate(e.buf.Len() - 1)
+	if lag != nil {
+		e.buf.WriteString(" | lag=" + lag.String())
+	}
+	e.buf.WriteByte('\n')
+	_, err := out.Write(e.buf.Bytes())
+	return err
+}
+
+// taggedRecord is the data change record in JSON format with the tags.
+type taggedRecord struct {
+	StreamID   string   `json:"stream_id,omitempty"`
+	LagSeconds *float64 `json:"lag_seconds,omitempty"`
+	*changestreams.DataChangeRecord
+}
+
+// lag returns the lag from the commit timestamp of the record to now.
+func (l *Logger) lag(r *changestreams.DataChangeRecord) time.Duration {
+	now := time.Now
+	if l.now != nil {
+		now = l.now
+	}
+	return now().Sub(r.CommitTimestamp)
+}
+
+func lagSeconds(lag *time.Duration) *float64 {
+	if lag == nil {
+		return nil
+	}
+	seconds := lag.Seconds()
+	return &seconds
+}
+
+func (l *Logger) updateSchema(r *changestreams.DataChangeRecord, format string) error {
+	if l.schemas == nil {
+		return nil
+	}
+	schema, err := l.schemas.update(r)
+	if err != nil {
+		return err
+	}
+	if schema == nil {
+		return nil
+	}
+	return l.writeSchema(schema, r.CommitTimestamp, format)
+}
+
+func (l *Logger) writeSchema(schema *SchemaRecord, commitTimestamp time.Time, format string) error {
+	switch format {
+	case formatJSON:
+		return encodeJSON(l.out, struct {
+			SchemaRecord *SchemaRecord `json:"schema_record"`
+		}{schema})
+	case formatText:
+		columnTypesJSON, err := json.Marshal(schema.ColumnTypes)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(l.out, "%s | SCHEMA | %s | %s\n", commitTimestamp, schema.TableName, columnTypesJSON)
+		return err
+	case formatLogfmt:
+		return writeLogfmtSchema(l.out, schema, commitTimestamp)
+	default:
+		return fmt.Errorf("invalid format: %s", format)
+	}
+}
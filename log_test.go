@@ -0,0 +1,286 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func newTestReadResult(records ...*changestreams.DataChangeRecord) *changestreams.ReadResult {
+	return &changestreams.ReadResult{
+		PartitionToken: "a",
+		ChangeRecords: []*changestreams.ChangeRecord{
+			{
+				DataChangeRecords: records,
+			},
+		},
+	}
+}
+
+func newTestDataChangeRecord(t testing.TB, commitTimestamp string, columnTypes ...string) *changestreams.DataChangeRecord {
+	var types []*changestreams.ColumnType
+	for i, name := range columnTypes {
+		types = append(types, &changestreams.ColumnType{
+			Name: name,
+			Type: spanner.NullJSON{
+				Value: map[string]interface{}{"code": "STRING"},
+				Valid: true,
+			},
+			IsPrimaryKey:    i == 0,
+			OrdinalPosition: int64(i + 1),
+		})
+	}
+	return &changestreams.DataChangeRecord{
+		CommitTimestamp: mustParseTime(t, commitTimestamp),
+		TableName:       "Players",
+		ColumnTypes:     types,
+		Mods: []*changestreams.Mod{
+			{
+				Keys:      spanner.NullJSON{Value: map[string]interface{}{"PlayerId": "1"}, Valid: true},
+				NewValues: spanner.NullJSON{Value: map[string]interface{}{"Name": "foo"}, Valid: true},
+				OldValues: spanner.NullJSON{Value: map[string]interface{}{}, Valid: true},
+			},
+		},
+		ModType: "INSERT",
+	}
+}
+
+func TestLoggerEmitSchema(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		format   string
+		expected string
+	}{
+		{
+			desc:   "text",
+			format: formatText,
+			expected: `2022-12-04 18:00:00 +0000 UTC | SCHEMA | Players | [{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1}]
+2022-12-04 18:00:00 +0000 UTC | INSERT | Players | [{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}]
+2022-12-04 18:00:01 +0000 UTC | INSERT | Players | [{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}]
+2022-12-04 18:00:02 +0000 UTC | SCHEMA | Players | [{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1},{"name":"Name","type":{"code":"STRING"},"is_primary_key":false,"ordinal_position":2}]
+2022-12-04 18:00:02 +0000 UTC | INSERT | Players | [{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}]
+`,
+		},
+		{
+			desc:   "json",
+			format: formatJSON,
+			expected: `{"schema_record":{"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1}]}}
+{"commit_timestamp":"2022-12-04T18:00:00Z","record_sequence":"","server_transaction_id":"","is_last_record_in_transaction_in_partition":false,"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1}],"mods":[{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}],"mod_type":"INSERT","value_capture_type":"","number_of_records_in_transaction":0,"number_of_partitions_in_transaction":0,"transaction_tag":"","is_system_transaction":false}
+{"commit_timestamp":"2022-12-04T18:00:01Z","record_sequence":"","server_transaction_id":"","is_last_record_in_transaction_in_partition":false,"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1}],"mods":[{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}],"mod_type":"INSERT","value_capture_type":"","number_of_records_in_transaction":0,"number_of_partitions_in_transaction":0,"transaction_tag":"","is_system_transaction":false}
+{"schema_record":{"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1},{"name":"Name","type":{"code":"STRING"},"is_primary_key":false,"ordinal_position":2}]}}
+{"commit_timestamp":"2022-12-04T18:00:02Z","record_sequence":"","server_transaction_id":"","is_last_record_in_transaction_in_partition":false,"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1},{"name":"Name","type":{"code":"STRING"},"is_primary_key":false,"ordinal_position":2}],"mods":[{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}],"mod_type":"INSERT","value_capture_type":"","number_of_records_in_transaction":0,"number_of_partitions_in_transaction":0,"transaction_tag":"","is_system_transaction":false}
+`,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := &Logger{
+				out:     &out,
+				format:  test.format,
+				schemas: newSchemaTracker(),
+			}
+			for _, r := range []*changestreams.ReadResult{
+				newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")),
+				newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId")),
+				newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:02Z", "PlayerId", "Name")),
+			} {
+				if err := logger.Read(r); err != nil {
+					t.Fatalf("Read error: %v", err)
+				}
+			}
+
+			if diff := cmp.Diff(out.String(), test.expected); diff != "" {
+				t.Errorf("logger has diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestLoggerLogfmt(t *testing.T) {
+	record := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId", "Name")
+	record.ServerTransactionID = "NjQxOTE0MDE0MzM1MDQ4NTQ5NQ=="
+	record.Mods = append(record.Mods, &changestreams.Mod{
+		Keys:      spanner.NullJSON{Value: map[string]interface{}{"PlayerId": "2"}, Valid: true},
+		NewValues: spanner.NullJSON{Value: map[string]interface{}{"Name": "foo bar", "Active": true}, Valid: true},
+		OldValues: spanner.NullJSON{Value: map[string]interface{}{"Name": ""}, Valid: true},
+	})
+
+	var out bytes.Buffer
+	logger := &Logger{
+		out:     &out,
+		format:  formatLogfmt,
+		schemas: newSchemaTracker(),
+	}
+	if err := logger.Read(newTestReadResult(record)); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+
+	expected := `commit_timestamp=2022-12-04T18:00:00Z mod_type=SCHEMA table_name=Players column.PlayerId=STRING column.Name=STRING
+commit_timestamp=2022-12-04T18:00:00Z mod_type=INSERT table_name=Players record_sequence="" server_transaction_id="NjQxOTE0MDE0MzM1MDQ4NTQ5NQ==" key.PlayerId=1 new.Name=foo
+commit_timestamp=2022-12-04T18:00:00Z mod_type=INSERT table_name=Players record_sequence="" server_transaction_id="NjQxOTE0MDE0MzM1MDQ4NTQ5NQ==" key.PlayerId=2 new.Active=true new.Name="foo bar" old.Name=""
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("logger has diff = %v", diff)
+	}
+}
+
+func TestLoggerStreamTag(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		format   string
+		expected string
+	}{
+		{
+			desc:     "text",
+			format:   formatText,
+			expected: "2022-12-04 18:00:00 +0000 UTC | mystream | INSERT | Players | [{\"keys\":{\"PlayerId\":\"1\"},\"new_values\":{\"Name\":\"foo\"},\"old_values\":{}}]\n",
+		},
+		{
+			desc:     "json",
+			format:   formatJSON,
+			expected: `{"stream_id":"mystream","commit_timestamp":"2022-12-04T18:00:00Z","record_sequence":"","server_transaction_id":"","is_last_record_in_transaction_in_partition":false,"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1}],"mods":[{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}],"mod_type":"INSERT","value_capture_type":"","number_of_records_in_transaction":0,"number_of_partitions_in_transaction":0,"transaction_tag":"","is_system_transaction":false}` + "\n",
+		},
+		{
+			desc:     "logfmt",
+			format:   formatLogfmt,
+			expected: `commit_timestamp=2022-12-04T18:00:00Z stream_id=mystream mod_type=INSERT table_name=Players record_sequence="" server_transaction_id="" key.PlayerId=1 new.Name=foo` + "\n",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := &Logger{
+				out:       &out,
+				format:    test.format,
+				streamTag: true,
+			}
+			result := newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))
+			result.StreamID = "mystream"
+			if err := logger.Read(result); err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+
+			if diff := cmp.Diff(out.String(), test.expected); diff != "" {
+				t.Errorf("logger has diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestLoggerAnnotateLag(t *testing.T) {
+	for _, test := range []struct {
+		desc     string
+		format   string
+		expected string
+	}{
+		{
+			desc:     "text",
+			format:   formatText,
+			expected: "2022-12-04 18:00:00 +0000 UTC | INSERT | Players | [{\"keys\":{\"PlayerId\":\"1\"},\"new_values\":{\"Name\":\"foo\"},\"old_values\":{}}] | lag=1.5s\n",
+		},
+		{
+			desc:     "json",
+			format:   formatJSON,
+			expected: `{"lag_seconds":1.5,"commit_timestamp":"2022-12-04T18:00:00Z","record_sequence":"","server_transaction_id":"","is_last_record_in_transaction_in_partition":false,"table_name":"Players","column_types":[{"name":"PlayerId","type":{"code":"STRING"},"is_primary_key":true,"ordinal_position":1}],"mods":[{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}],"mod_type":"INSERT","value_capture_type":"","number_of_records_in_transaction":0,"number_of_partitions_in_transaction":0,"transaction_tag":"","is_system_transaction":false}` + "\n",
+		},
+		{
+			desc:     "logfmt",
+			format:   formatLogfmt,
+			expected: `commit_timestamp=2022-12-04T18:00:00Z lag_seconds=1.5 mod_type=INSERT table_name=Players record_sequence="" server_transaction_id="" key.PlayerId=1 new.Name=foo` + "\n",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			logger := &Logger{
+				out:         &out,
+				format:      test.format,
+				annotateLag: true,
+				now:         func() time.Time { return mustParseTime(t, "2022-12-04T18:00:01.5Z") },
+			}
+			if err := logger.Read(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))); err != nil {
+				t.Fatalf("Read error: %v", err)
+			}
+
+			if diff := cmp.Diff(out.String(), test.expected); diff != "" {
+				t.Errorf("logger has diff = %v", diff)
+			}
+		})
+	}
+}
+
+func TestLoggerRaw(t *testing.T) {
+	var out bytes.Buffer
+	logger := &Logger{out: &out, format: formatRaw}
+
+	result := newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z"))
+	result.RawChangeRecord = []byte(`[{"data_change_record":[{"table_name":"Players"}],"heartbeat_record":[],"child_partitions_record":[]}]`)
+	heartbeat := &changestreams.ReadResult{
+		PartitionToken:  "a",
+		ChangeRecords:   []*changestreams.ChangeRecord{{HeartbeatRecords: []*changestreams.HeartbeatRecord{{}}}},
+		RawChangeRecord: []byte(`[{"data_change_record":[],"heartbeat_record":[{"timestamp":"2022-12-04T18:00:10Z"}],"child_partitions_record":[]}]`),
+	}
+	for _, r := range []*changestreams.ReadResult{result, heartbeat} {
+		if err := logger.Read(r); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+
+	// Only the rows of the data change records are written.
+	expected := `[{"data_change_record":[{"table_name":"Players"}],"heartbeat_record":[],"child_partitions_record":[]}]` + "\n"
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("logger has diff = %v", diff)
+	}
+
+	if err := logger.Read(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z"))); err == nil {
+		t.Errorf("Read must fail without the raw change record")
+	}
+}
+
+func BenchmarkLogger(b *testing.B) {
+	var records []*changestreams.DataChangeRecord
+	for i := 0; i < 100; i++ {
+		records = append(records, newTestDataChangeRecord(b, "2022-12-04T18:00:00Z", "PlayerId", "Name"))
+	}
+	result := newTestReadResult(records...)
+
+	for _, format := range []string{formatText, formatJSON, formatLogfmt} {
+		b.Run(format, func(b *testing.B) {
+			logger := &Logger{out: io.Discard, format: format}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := logger.Read(result); err != nil {
+					b.Fatalf("Read error: %v", err)
+				}
+			}
+		})
+	}
+	b.Run("verbose", func(b *testing.B) {
+		logger := &Logger{out: io.Discard, verbose: true}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := logger.Read(result); err != nil {
+				b.Fatalf("Read error: %v", err)
+			}
+		}
+	})
+}
@@ -0,0 +1,148 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+type logfmtField struct {
+	key   string
+	value string
+}
+
+// writeLogfmtRecord writes the data change record as logfmt lines, one line per mod.
+// Keys, new values and old values are flattened into "key.", "new." and "old." prefixed fields.
+// The tags such as stream_id are written after commit_timestamp.
+func writeLogfmtRecord(out io.Writer, tags []logfmtField, r *changestreams.DataChangeRecord) error {
+	for _, mod := range r.Mods {
+		fields := []logfmtField{
+			{"commit_timestamp", r.CommitTimestamp.Format(time.RFC3339Nano)},
+		}
+		fields = append(fields, tags...)
+		fields = append(fields, []logfmtField{
+			{"mod_type", r.ModType},
+			{"table_name", r.TableName},
+			{"record_sequence", r.RecordSequence},
+			{"server_transaction_id", r.ServerTransactionID},
+		}...)
+		for _, values := range []struct {
+			prefix string
+			json   spanner.NullJSON
+		}{
+			{"key.", mod.Keys},
+			{"new.", mod.NewValues},
+			{"old.", mod.OldValues},
+		} {
+			f, err := logfmtValues(values.prefix, values.json)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, f...)
+		}
+		if err := writeLogfmt(out, fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLogfmtSchema writes the schema record as a logfmt line with a "column." prefixed field per column.
+func writeLogfmtSchema(out io.Writer, schema *SchemaRecord, commitTimestamp time.Time) error {
+	fields := []logfmtField{
+		{"commit_timestamp", commitTimestamp.Format(time.RFC3339Nano)},
+		{"mod_type", "SCHEMA"},
+		{"table_name", schema.TableName},
+	}
+	for _, columnType := range schema.ColumnTypes {
+		var typ string
+		if m, ok := columnType.Type.Value.(map[string]interface{}); ok && len(m) == 1 {
+			typ, _ = m["code"].(string)
+		}
+		if typ == "" {
+			b, err := columnType.Type.MarshalJSON()
+			if err != nil {
+				return err
+			}
+			typ = string(b)
+		}
+		fields = append(fields, logfmtField{"column." + columnType.Name, typ})
+	}
+	return writeLogfmt(out, fields)
+}
+
+func logfmtValues(prefix string, v spanner.NullJSON) ([]logfmtField, error) {
+	values, ok := v.Value.(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var fields []logfmtField
+	for _, name := range names {
+		var value string
+		switch x := values[name].(type) {
+		case string:
+			value = x
+		default:
+			b, err := json.Marshal(x)
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		}
+		fields = append(fields, logfmtField{prefix + name, value})
+	}
+	return fields, nil
+}
+
+func writeLogfmt(out io.Writer, fields []logfmtField) error {
+	var sb strings.Builder
+	for i, f := range fields {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(f.key)
+		sb.WriteByte('=')
+		sb.WriteString(logfmtQuote(f.value))
+	}
+	sb.WriteByte('\n')
+	_, err := io.WriteString(out, sb.String())
+	return err
+}
+
+func logfmtQuote(s string) string {
+	if s == "" {
+		return `""`
+	}
+	if strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, func(r rune) bool { return r < ' ' || r == 0x7f }) >= 0 {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// removeTime removes the time from the logs to compare them.
+func removeTime(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.TimeKey && len(groups) == 0 {
+		return slog.Attr{}
+	}
+	return a
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		desc     string
+		level    string
+		format   string
+		expected string
+		wantErr  bool
+	}{
+		{
+			desc:     "text",
+			level:    logLevelInfo,
+			format:   logFormatText,
+			expected: `level=INFO msg="Reading the stream" stream=mystream`,
+		},
+		{
+			desc:     "json",
+			level:    logLevelDebug,
+			format:   logFormatJSON,
+			expected: `"level":"INFO","msg":"Reading the stream","stream":"mystream"}`,
+		},
+		{
+			desc:   "filtered by level",
+			level:  logLevelWarn,
+			format: logFormatText,
+		},
+		{
+			desc:    "invalid level",
+			level:   "trace",
+			format:  logFormatText,
+			wantErr: true,
+		},
+		{
+			desc:    "invalid format",
+			level:   logLevelInfo,
+			format:  "logfmt",
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			var out bytes.Buffer
+			l, err := newLogger(&out, test.level, test.format)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("newLogger must fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newLogger error: %v", err)
+			}
+			l.Info("Reading the stream", "stream", "mystream")
+			got := strings.TrimSpace(out.String())
+			if test.expected == "" {
+				if got != "" {
+					t.Errorf("log must be filtered, but got %q", got)
+				}
+				return
+			}
+			if !strings.HasSuffix(got, test.expected) {
+				t.Errorf("log = %q, want suffix %q", got, test.expected)
+			}
+		})
+	}
+}
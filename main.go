@@ -18,145 +18,1207 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"cloud.google.com/go/spanner"
+	sppb "cloud.google.com/go/spanner/apiv1/spannerpb"
 	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/term"
+)
+
+const (
+	redactModePlaceholder = "placeholder"
+	redactModeSHA256      = "sha256"
+)
+
+const (
+	priorityLow    = "low"
+	priorityMedium = "medium"
+	priorityHigh   = "high"
 )
 
 func usage() {
 	command := os.Args[0]
 	fmt.Printf(`Usage:
-  %s [OPTIONS]
+  %s [COMMAND] [OPTIONS]
+
+Commands:
+  serve                        Serve the data change records to multiple gRPC, Server-Sent Events or WebSocket clients
+  create-stream                Create the change stream with the DDL, e.g. for debugging
+  drop-stream                  Drop the change stream with the DDL
+  describe-stream              Print the watched tables, options and current partition count of the change stream
+  record FILE                  Capture the complete results to the file for replay, compressed with gzip if it ends with .gz
+  replay FILE                  Read the results captured by record or --verbose from the file instead of the streams
+  diff                         Print the net change of each row in the window, or the differences from another window
+  bench                        Write synthetic transactions into a table and measure the throughput and latency of tailing
+  completion SHELL             Print the completion script of the shell [bash|zsh|fish]
 
 Options:
+      --config=                YAML file of the options (default: spanner-change-streams-tail.yaml in the current or user config directory)
   -p, --project=  (required)   GCP Project ID
   -i, --instance= (required)   Cloud Spanner Instance ID
   -d, --database= (required)   Cloud Spanner Database ID
-  -s, --stream=   (required)   Cloud Spanner Change Stream ID
-  -f, --format=                Output format [text|json] (default: text)
-      --start=                 Start timestamp with RFC3339 format (default: current timestamp)
+  -s, --stream=   (required)   Cloud Spanner Change Stream ID (can be repeated or comma separated)
+      --table=                 Find the change stream watching the table, instead of specifying --stream
+      --show-coverage          Log the tables and the columns watched by each stream before reading
+  -f, --format=                Output format [text|json|logfmt|raw] (default: text)
+  -o, --output=                Write the records to the file instead of the standard output
+  -q, --quiet                  Suppress the informational messages on the standard error, the same as --log-level=warn
+      --log-level=             Level of the messages on the standard error [debug|info|warn|error] (default: info)
+      --log-format=            Format of the messages on the standard error [text|json] (default: text)
+      --progress-interval=     Interval of the progress line on the terminal while no changes arrive, or 0 to disable it (default: 10s)
+      --summary-interval=      Interval of the throughput summary logged to the standard error (default: none)
+      --verify-sequence        Verify the record sequences of each transaction, and log the gaps and the records out of order
+      --start=                 Start timestamp with RFC3339 format (default: current timestamp of the database)
+      --since=                 Start reading from the duration ago, e.g. 30m or 2h (cannot be used with --start)
+      --clamp-start            Read from the earliest readable timestamp with a warning if the start is older than the
+                               retention period of the stream, instead of failing
+      --checkpoint=            File to save the progress of the streams to, and to resume them from instead of --start
+      --audit-log=             File to append the retries of the partition queries and the resumptions of the streams to
+      --dead-letter=           File to append the records failed to be written to the sink to, instead of failing
+      --dead-letter-max-error-rate=
+                               Maximum ratio of the records to --dead-letter before failing, or 0 for no limit (default: 0)
+      --notify-new-tables      Print notices when tables are created and their records appear, e.g. in the streams FOR ALL tables
+      --notify-schema-changes  Print notices when columns are added to the tables or their types change while tailing
+      --enrich-rows            Read the full row of each change at its commit timestamp, and add it to the mods as full_row
+      --reconstruct-old-values=
+                               Number of the rows whose last values are kept to fill the old values of NEW_VALUES and
+                               NEW_ROW streams (default: 0, disabled)
+      --metrics-addr=          Address of the HTTP server for the Prometheus metrics (/metrics), e.g. :9090 (default: none)
+      --cloud-monitoring       Export the lag, the throughput and the active partitions as Cloud Monitoring custom metrics
+      --cloud-monitoring-interval=
+                               Interval of exporting the metrics to Cloud Monitoring (default: 1m)
+      --query-stats            Collect the execution statistics of the partition queries, logged when each query finishes
+      --debug-addr=            Address of the HTTP server for the expvar debug variables (/debug/vars), e.g. localhost:6060 (default: none)
+      --pprof-addr=            Address of the HTTP server for the runtime profiles (/debug/pprof/), e.g. localhost:6060 (default: none)
+      --health-addr=           Address of the HTTP server for the health checks (/healthz and /readyz), e.g. :8080 (default: none)
+      --health-stall-timeout=  Time the watermark may stay still before /healthz fails (default: 5m)
+      --trace-exporter=        Export OpenTelemetry spans of the queries and the records [stdout|otlp] (default: none)
       --end=                   End timestamp with RFC3339 format (default: none)
+      --duration=              End reading after the duration from the start, e.g. 10m (cannot be used with --end)
+      --limit=                 Exit after the number of data change records are written (default: none)
       --role=                  Database role for fine-grained access control
-      --visualize-partitions   Visualize the change stream partitions in Graphviz DOT
+      --priority=              Request priority of the change stream queries [low|medium|high] (default: high)
+      --max-partitions=        Maximum number of partitions read concurrently per stream; the others wait (default: none)
+      --partition-start-rate=  Maximum number of partitions started per second per stream, e.g. to read from an old --start
+                               (default: none)
+      --min-sessions=          Minimum number of the sessions kept open in the session pool (default: 100)
+      --max-sessions=          Maximum number of the sessions, at least the partitions read concurrently (default: 400)
+      --grpc-pool-size=        Number of the gRPC connections to Cloud Spanner, e.g. to read many partitions (default: 4)
+      --keepalive-time=        Interval of the keepalive pings of the idle gRPC connections (default: none)
+      --keepalive-timeout=     Time to wait for the keepalive ping to be acknowledged before closing the connection
+                               (default: 20s)
+      --decode-workers=        Number of goroutines decoding the records in parallel with fetching them per stream
+                               (default: none)
+      --heartbeat-interval=    Interval of the heartbeat records of idle partitions, from 1s to 5m (default: 10s)
+      --retry-max-attempts=    Maximum number of attempts per partition query on transient errors (default: 1)
+      --retry-initial-backoff= Wait before the first retry, doubled for each retry (default: 1s)
+      --retry-max-backoff=     Maximum wait between the retries (default: 32s)
+      --retry-budget=          Maximum number of the retries of all the partitions within --retry-budget-window, to fail
+                               fast on widespread failures (default: none)
+      --retry-budget-window=   Window of --retry-budget (default: 1m)
+      --emulator-host=         Host and port of the Cloud Spanner emulator, e.g. localhost:9010
+      --endpoint=              Cloud Spanner API endpoint, e.g. for regional endpoints or private access (default: spanner.googleapis.com:443)
+      --credentials=           Service account key file to use instead of the Application Default Credentials
+      --impersonate-service-account=
+                               Access Cloud Spanner as the service account, or comma separated delegation chain ending with it
+      --quota-project=         Project for quota and billing of the Cloud Spanner API requests
+      --emit-schema            Emit a schema record before the data change records of each table
+      --annotate-lag           Annotate each data change record with the lag from its commit timestamp to the time it's read
+      --redact=                Comma separated columns to redact in the form of table.column
+      --redact-mode=           Redaction mode [placeholder|sha256] (default: placeholder)
+      --sink=                  Name of the sink to write the records to (default: stdout)
+      --sink-param=            Parameter of the sink in the form of key=value (can be repeated)
+      --bigquery-table=        Stream the data change records into the BigQuery table ([project.]dataset.table)
+      --gcs-path=              Write the records to Cloud Storage objects under gs://bucket[/prefix]
+      --gcs-max-size=          Rotate the Cloud Storage object when it exceeds the size, e.g. 100MB (default: none)
+      --gcs-max-age=           Rotate the Cloud Storage object after the duration (default: 1h)
+      --output-atomic          Write the output to a temporary file and rename it to the path on exit
+      --output-fsync           Sync the output to the disk on exit
+      --output-file=           Write the records to the local file
+      --output-file-max-size=  Rotate the output file when it exceeds the size, e.g. 100MB (default: none)
+      --output-file-max-age=   Rotate the output file after the duration (default: none)
+      --output-file-gzip       Compress the rotated output files with gzip
+      --webhook-url=           POST the data change records to the URL as newline delimited JSON
+      --webhook-header=        HTTP header for the webhook in the form of "Name: value" (can be repeated)
+      --webhook-batch-size=    Maximum number of records per webhook request (default: 1)
+      --webhook-flush-interval= Maximum time a record waits to be sent to the webhook (default: 1s)
+      --webhook-max-retries=   Maximum number of retries per webhook request (default: 5)
+      --elasticsearch-url=     Index the rows into Elasticsearch or OpenSearch at the URL
+      --elasticsearch-prefix=  Prefix of the index names, followed by lower-cased table names
+      --sqlite-path=           Append the data change records into the SQLite database
+      --unix-socket=           Write the records to the clients connected to the Unix domain socket
+      --exec=                  Write the records to the standard input of the shell command, restarting it when it exits
+      --amqp-url=              Publish the data change records to the AMQP 0.9.1 broker at the URL, e.g. RabbitMQ
+      --amqp-exchange=         Exchange to publish the records to, as a Go template (default: the default exchange)
+      --amqp-routing-key=      Routing key of the records, as a Go template (default: {{.TableName}}.{{.ModType}})
+      --tui                    Show the records, the counters of each table and the partitions in an interactive terminal UI
+      --stats                  Print the aggregates of the records periodically and on exit, instead of the records
+      --stats-interval=        Interval of the periodic stats, or 0 to print them only on exit (default: 10s)
+      --visualize-partitions   Visualize the change stream partitions in the format of --viz-format
+      --viz-format=            Format of the visualized partitions [dot|mermaid|json|gantt|html|csv] (default: dot)
+      --viz-output=            File to write the visualized partitions, rendered into an image by Graphviz if it ends
+                               with .svg, .png or .pdf
+      --viz-metadata-table=    Visualize the partitions in the PartitionMetadata table of the Dataflow connector, instead
+                               of reading the stream
+
+Serve Options:
+      --grpc-addr=             Address of the gRPC server, or empty to disable it (default: :50051)
+      --http-addr=             Address of the HTTP server for Server-Sent Events (/events) and WebSocket (/ws) (default: none)
+
+Diff Options:
+      --compare-start=         Start timestamp of the window to compare with RFC3339 format
+      --compare-end=           End timestamp of the window to compare with RFC3339 format
+      --diff-max-memory=       Maximum size of the records diff command buffers in memory, spilling the rest to temporary
+                               files, or 0 for no limit (default: 256MB)
+
+Bench Options:
+      --bench-table=           Table to write the transactions into, with Id STRING and Payload STRING columns (required)
+      --bench-rate=            Number of the transactions written per second (default: 10)
+      --bench-duration=        Duration of writing the transactions (default: 1m)
+      --bench-payload-size=    Size of the payload of each row in bytes (default: 100)
+
+Create Stream Options:
+      --for-all                Watch all the tables
+      --watch=                 Table to watch in the form of table or table(column, ...) (can be repeated)
+      --value-capture-type=    Value capture type [OLD_AND_NEW_VALUES|NEW_ROW|NEW_VALUES|NEW_ROW_AND_OLD_VALUES] (default: OLD_AND_NEW_VALUES)
+      --retention-period=      Retention period of the data change records, e.g. 36h or 7d (default: 1d)
 
 Help Options:
   -h, -help                    Show this help message
+
+Environment Variables:
+  CS_TAIL_<OPTION>             Default of the option, e.g. CS_TAIL_FORMAT for --format
+  SPANNER_PROJECT              Default of --project if CS_TAIL_PROJECT is not set
+  SPANNER_INSTANCE             Default of --instance if CS_TAIL_INSTANCE is not set
+  SPANNER_DATABASE             Default of --database if CS_TAIL_DATABASE is not set
+  SPANNER_EMULATOR_HOST        Default of --emulator-host if CS_TAIL_EMULATOR_HOST is not set
 `, command)
 }
 
 func main() {
 	var (
-		projectID, instanceID, databaseID, streamID, format, start, end, role string
-		startTimestamp, endTimestamp                                          time.Time
-		verbose, visualizePartitions                                          bool
+		projectID, instanceID, databaseID, format, start, end, role        string
+		redact, redactMode, bigQueryTable, gcsPath, gcsMaxSize             string
+		outputFile, outputFileMaxSize, webhookURL                          string
+		elasticsearchURL, elasticsearchIndexPrefix, sqlitePath, grpcAddr   string
+		httpAddr, unixSocket, execCommand, sinkName                        string
+		amqpURL, amqpExchange, amqpRoutingKey, configPath, outputPath      string
+		emulatorHost, endpoint, impersonateServiceAccount, quotaProject    string
+		credentialsFile, priority, table                                   string
+		gcsMaxAge, outputFileMaxAge, webhookFlushInterval, since, duration time.Duration
+		statsInterval                                                      time.Duration
+		webhookBatchSize, webhookMaxRetries, limit                         int
+		webhookHeaders, sinkParamFlags, streamIDFlags                      stringsFlag
+		startTimestamp, endTimestamp                                       time.Time
+		verbose, visualizePartitions, emitSchema, outputFileGzip, stats    bool
+		outputAtomic, outputFsync                                          bool
+		valueCaptureType, retentionPeriod                                  string
+		watchFlags                                                         stringsFlag
+		forAll                                                             bool
+		compareStart, compareEnd                                           string
+		tui                                                                bool
+		progressInterval                                                   time.Duration
+		maxPartitions                                                      int
+		heartbeatInterval                                                  time.Duration
+		retryMaxAttempts                                                   int
+		retryInitialBackoff, retryMaxBackoff                               time.Duration
+		checkpointPath                                                     string
+		notifyNewTables                                                    bool
+		metricsAddr                                                        string
+		traceExporter                                                      string
+		quiet                                                              bool
+		logLevel, logFormat                                                string
+		debugAddr                                                          string
+		annotateLag                                                        bool
+		summaryInterval                                                    time.Duration
+		pprofAddr                                                          string
+		healthAddr                                                         string
+		healthStallTimeout                                                 time.Duration
+		queryStats                                                         bool
+		auditLogPath                                                       string
+		deadLetterPath                                                     string
+		deadLetterMaxErrorRate                                             float64
+		cloudMonitoring                                                    bool
+		cloudMonitoringInterval                                            time.Duration
+		vizFormat                                                          string
+		vizMetadataTable                                                   string
+		vizOutput                                                          string
+		decodeWorkers                                                      int
+		diffMaxMemory                                                      string
+		partitionStartRate                                                 float64
+		minSessions, maxSessions                                           uint64
+		benchTable                                                         string
+		benchRate                                                          float64
+		benchDuration                                                      time.Duration
+		benchPayloadSize                                                   int
+		grpcPoolSize                                                       int
+		keepaliveTime, keepaliveTimeout                                    time.Duration
+		clampStart                                                         bool
+		showCoverage                                                       bool
+		verifySequence                                                     bool
+		notifySchemaChanges                                                bool
+		retryBudget                                                        int
+		retryBudgetWindow                                                  time.Duration
+		reconstructOldValues                                               int
+		enrichRows                                                         bool
+		redactor                                                           changestreams.Redactor
 	)
 
 	// Long options.
+	flag.StringVar(&configPath, "config", "", "")
 	flag.StringVar(&projectID, "project", "", "")
 	flag.StringVar(&instanceID, "instance", "", "")
 	flag.StringVar(&databaseID, "database", "", "")
-	flag.StringVar(&streamID, "stream", "", "")
+	flag.Var(&streamIDFlags, "stream", "")
+	flag.StringVar(&table, "table", "", "")
 	flag.StringVar(&format, "format", formatText, "")
 	flag.StringVar(&start, "start", "", "")
 	flag.StringVar(&end, "end", "", "")
+	flag.DurationVar(&since, "since", 0, "")
+	flag.BoolVar(&clampStart, "clamp-start", false, "")
+	flag.BoolVar(&showCoverage, "show-coverage", false, "")
+	flag.DurationVar(&duration, "duration", 0, "")
+	flag.IntVar(&limit, "limit", 0, "")
 	flag.StringVar(&role, "role", "", "")
+	flag.StringVar(&priority, "priority", "", "")
+	flag.IntVar(&maxPartitions, "max-partitions", 0, "")
+	flag.Float64Var(&partitionStartRate, "partition-start-rate", 0, "")
+	flag.Uint64Var(&minSessions, "min-sessions", 0, "")
+	flag.Uint64Var(&maxSessions, "max-sessions", 0, "")
+	flag.IntVar(&grpcPoolSize, "grpc-pool-size", 0, "")
+	flag.DurationVar(&keepaliveTime, "keepalive-time", 0, "")
+	flag.DurationVar(&keepaliveTimeout, "keepalive-timeout", 20*time.Second, "")
+	flag.IntVar(&decodeWorkers, "decode-workers", 0, "")
+	flag.DurationVar(&heartbeatInterval, "heartbeat-interval", 0, "")
+	flag.IntVar(&retryMaxAttempts, "retry-max-attempts", 1, "")
+	flag.DurationVar(&retryInitialBackoff, "retry-initial-backoff", changestreams.DefaultRetryInitialBackoff, "")
+	flag.DurationVar(&retryMaxBackoff, "retry-max-backoff", changestreams.DefaultRetryMaxBackoff, "")
+	flag.IntVar(&retryBudget, "retry-budget", 0, "")
+	flag.DurationVar(&retryBudgetWindow, "retry-budget-window", changestreams.DefaultRetryBudgetWindow, "")
+	flag.StringVar(&emulatorHost, "emulator-host", "", "")
+	flag.StringVar(&endpoint, "endpoint", "", "")
+	flag.StringVar(&credentialsFile, "credentials", "", "")
+	flag.StringVar(&impersonateServiceAccount, "impersonate-service-account", "", "")
+	flag.StringVar(&quotaProject, "quota-project", "", "")
+	flag.StringVar(&redact, "redact", "", "")
+	flag.StringVar(&redactMode, "redact-mode", redactModePlaceholder, "")
+	flag.StringVar(&bigQueryTable, "bigquery-table", "", "")
+	flag.StringVar(&gcsPath, "gcs-path", "", "")
+	flag.StringVar(&gcsMaxSize, "gcs-max-size", "", "")
+	flag.DurationVar(&gcsMaxAge, "gcs-max-age", time.Hour, "")
+	flag.StringVar(&outputPath, "output", "", "")
+	flag.BoolVar(&outputAtomic, "output-atomic", false, "")
+	flag.BoolVar(&outputFsync, "output-fsync", false, "")
+	flag.StringVar(&outputFile, "output-file", "", "")
+	flag.StringVar(&outputFileMaxSize, "output-file-max-size", "", "")
+	flag.DurationVar(&outputFileMaxAge, "output-file-max-age", 0, "")
+	flag.BoolVar(&outputFileGzip, "output-file-gzip", false, "")
+	flag.StringVar(&webhookURL, "webhook-url", "", "")
+	flag.Var(&webhookHeaders, "webhook-header", "")
+	flag.IntVar(&webhookBatchSize, "webhook-batch-size", 1, "")
+	flag.DurationVar(&webhookFlushInterval, "webhook-flush-interval", time.Second, "")
+	flag.IntVar(&webhookMaxRetries, "webhook-max-retries", 5, "")
+	flag.StringVar(&elasticsearchURL, "elasticsearch-url", "", "")
+	flag.StringVar(&elasticsearchIndexPrefix, "elasticsearch-prefix", "", "")
+	flag.StringVar(&sqlitePath, "sqlite-path", "", "")
+	flag.StringVar(&grpcAddr, "grpc-addr", ":50051", "")
+	flag.StringVar(&httpAddr, "http-addr", "", "")
+	flag.StringVar(&unixSocket, "unix-socket", "", "")
+	flag.StringVar(&execCommand, "exec", "", "")
+	flag.StringVar(&amqpURL, "amqp-url", "", "")
+	flag.StringVar(&amqpExchange, "amqp-exchange", "", "")
+	flag.StringVar(&amqpRoutingKey, "amqp-routing-key", defaultAMQPRoutingKey, "")
+	flag.StringVar(&sinkName, "sink", sinkStdout, "")
+	flag.Var(&sinkParamFlags, "sink-param", "")
 	flag.BoolVar(&verbose, "verbose", false, "")
+	flag.BoolVar(&quiet, "quiet", false, "")
+	flag.StringVar(&logLevel, "log-level", logLevelInfo, "")
+	flag.StringVar(&logFormat, "log-format", logFormatText, "")
 	flag.BoolVar(&visualizePartitions, "visualize-partitions", false, "")
+	flag.StringVar(&vizFormat, "viz-format", vizFormatDOT, "")
+	flag.StringVar(&vizOutput, "viz-output", "", "")
+	flag.StringVar(&vizMetadataTable, "viz-metadata-table", "", "")
+	flag.BoolVar(&stats, "stats", false, "")
+	flag.BoolVar(&tui, "tui", false, "")
+	flag.DurationVar(&progressInterval, "progress-interval", defaultProgressInterval, "")
+	flag.DurationVar(&summaryInterval, "summary-interval", 0, "")
+	flag.BoolVar(&verifySequence, "verify-sequence", false, "")
+	flag.StringVar(&checkpointPath, "checkpoint", "", "")
+	flag.StringVar(&auditLogPath, "audit-log", "", "")
+	flag.StringVar(&deadLetterPath, "dead-letter", "", "")
+	flag.Float64Var(&deadLetterMaxErrorRate, "dead-letter-max-error-rate", 0, "")
+	flag.BoolVar(&notifyNewTables, "notify-new-tables", false, "")
+	flag.BoolVar(&notifySchemaChanges, "notify-schema-changes", false, "")
+	flag.BoolVar(&enrichRows, "enrich-rows", false, "")
+	flag.IntVar(&reconstructOldValues, "reconstruct-old-values", 0, "")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "")
+	flag.BoolVar(&cloudMonitoring, "cloud-monitoring", false, "")
+	flag.DurationVar(&cloudMonitoringInterval, "cloud-monitoring-interval", defaultCloudMonitoringInterval, "")
+	flag.BoolVar(&queryStats, "query-stats", false, "")
+	flag.StringVar(&debugAddr, "debug-addr", "", "")
+	flag.StringVar(&pprofAddr, "pprof-addr", "", "")
+	flag.StringVar(&healthAddr, "health-addr", "", "")
+	flag.DurationVar(&healthStallTimeout, "health-stall-timeout", defaultHealthStallTimeout, "")
+	flag.StringVar(&traceExporter, "trace-exporter", "", "")
+	flag.DurationVar(&statsInterval, "stats-interval", defaultStatsInterval, "")
+	flag.BoolVar(&emitSchema, "emit-schema", false, "")
+	flag.BoolVar(&annotateLag, "annotate-lag", false, "")
+	flag.StringVar(&compareStart, "compare-start", "", "")
+	flag.StringVar(&compareEnd, "compare-end", "", "")
+	flag.StringVar(&diffMaxMemory, "diff-max-memory", "256MB", "")
+	flag.StringVar(&benchTable, "bench-table", "", "")
+	flag.Float64Var(&benchRate, "bench-rate", 10, "")
+	flag.DurationVar(&benchDuration, "bench-duration", time.Minute, "")
+	flag.IntVar(&benchPayloadSize, "bench-payload-size", 100, "")
+	flag.BoolVar(&forAll, "for-all", false, "")
+	flag.Var(&watchFlags, "watch", "")
+	flag.StringVar(&valueCaptureType, "value-capture-type", "", "")
+	flag.StringVar(&retentionPeriod, "retention-period", "", "")
 
 	// Short options.
 	flag.StringVar(&projectID, "p", "", "")
 	flag.StringVar(&instanceID, "i", "", "")
 	flag.StringVar(&databaseID, "d", "", "")
-	flag.StringVar(&streamID, "s", "", "")
+	flag.Var(&streamIDFlags, "s", "")
 	flag.StringVar(&format, "f", formatText, "")
 	flag.BoolVar(&verbose, "v", false, "")
+	flag.BoolVar(&quiet, "q", false, "")
+	flag.StringVar(&outputPath, "o", "", "")
 
 	flag.Usage = usage
-	flag.Parse()
+
+	args := os.Args[1:]
+	var command string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+	var completedOption, replayPath, recordPath string
+	switch command {
+	case commandCompletion:
+		if len(args) != 1 {
+			usagef("usage: %s %s [bash|zsh|fish]", os.Args[0], commandCompletion)
+		}
+		if err := writeCompletionScript(os.Stdout, args[0], filepath.Base(os.Args[0]), flag.CommandLine); err != nil {
+			usagef("%v", err)
+		}
+		return
+	case commandReplay:
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			usagef("usage: %s %s FILE [OPTIONS]", os.Args[0], commandReplay)
+		}
+		replayPath, args = args[0], args[1:]
+	case commandRecord:
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			usagef("usage: %s %s FILE [OPTIONS]", os.Args[0], commandRecord)
+		}
+		recordPath, args = args[0], args[1:]
+	case commandComplete:
+		if len(args) == 0 {
+			return
+		}
+		completedOption, args = args[0], completionArgs(flag.CommandLine, args[1:])
+		// Nothing but the values must be printed while completing.
+		flag.Usage = func() {}
+	}
+	flag.CommandLine.Parse(args)
+
+	if err := loadEnv(flag.CommandLine, os.LookupEnv); err != nil {
+		usagef("%v", err)
+	}
+	if configPath == "" {
+		configPath = defaultConfigPath()
+	}
+	if configPath != "" {
+		if err := loadConfigFile(flag.CommandLine, configPath); err != nil {
+			usagef("failed to load config file: %v", err)
+		}
+	}
+	if quiet && (logLevel == logLevelDebug || logLevel == logLevelInfo) {
+		logLevel = logLevelWarn
+	}
+	l, err := newLogger(os.Stderr, logLevel, logFormat)
+	if err != nil {
+		usagef("%v", err)
+	}
+	logger = l
+
+	if command == commandComplete {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		values, err := completeValues(ctx, completedOption, completionConfig{
+			projectID:  projectID,
+			instanceID: instanceID,
+			databaseID: databaseID,
+			role:       role,
+			client: clientConfig{
+				emulatorHost:              emulatorHost,
+				endpoint:                  endpoint,
+				credentialsFile:           credentialsFile,
+				impersonateServiceAccount: impersonateServiceAccount,
+				quotaProject:              quotaProject,
+			},
+		})
+		if err != nil {
+			exitf("failed to complete %s: %v", completedOption, err)
+		}
+		for _, v := range values {
+			fmt.Println(v)
+		}
+		return
+	}
+
+	switch command {
+	case "", commandServe, commandCreateStream, commandDropStream, commandDescribeStream, commandRecord, commandReplay, commandDiff, commandBench:
+	default:
+		usagef("unknown command: %s", command)
+	}
+
+	if emulatorHost != "" {
+		for _, warning := range emulatorWarnings(emulatorOptions{
+			role:                role,
+			queryStats:          queryStats,
+			visualizePartitions: visualizePartitions,
+			command:             command,
+		}) {
+			logger.Warn(warning)
+		}
+		role = ""
+	}
 
 	// Validate required options.
-	if projectID == "" || instanceID == "" || databaseID == "" || streamID == "" {
+	streamIDs := parseStreamIDs(streamIDFlags)
+	if command == commandReplay {
+		if table != "" {
+			usagef("--table option cannot be specified with %s command", commandReplay)
+		}
+	} else if projectID == "" || instanceID == "" || databaseID == "" || (len(streamIDs) == 0 && table == "" && vizMetadataTable == "") {
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(exitCodeUsage)
 	}
 
 	// Validate optional options.
-	if format != formatText && format != formatJSON {
-		exitf("invalid format: %s", format)
+	if format != formatText && format != formatJSON && format != formatLogfmt && format != formatRaw {
+		usagef("invalid format: %s", format)
 	}
 	if start != "" {
 		ts, err := time.Parse(time.RFC3339, start)
 		if err != nil {
-			exitf("invalid start timestamp: %v", err)
+			usagef("invalid start timestamp: %v", err)
 		}
 		startTimestamp = ts
 	}
+	if since != 0 {
+		if start != "" {
+			usagef("--start and --since options cannot be specified together")
+		}
+		if since < 0 {
+			usagef("invalid since duration: %v", since)
+		}
+		startTimestamp = time.Now().Add(-since)
+	}
 	if end != "" {
 		ts, err := time.Parse(time.RFC3339, end)
 		if err != nil {
-			exitf("invalid end timestamp: %v", err)
+			usagef("invalid end timestamp: %v", err)
 		}
 		endTimestamp = ts
 	}
+	if duration != 0 {
+		if end != "" {
+			usagef("--end and --duration options cannot be specified together")
+		}
+		if duration < 0 {
+			usagef("invalid duration: %v", duration)
+		}
+		// Without the start, the window starts at the current timestamp of the database once the client is created.
+		if !startTimestamp.IsZero() {
+			endTimestamp = startTimestamp.Add(duration)
+		}
+	}
+	if len(streamIDs) > 0 && table != "" {
+		usagef("--stream and --table options cannot be specified together")
+	}
+	if emulatorHost != "" && endpoint != "" {
+		usagef("--emulator-host and --endpoint options cannot be specified together")
+	}
+	if emulatorHost != "" && (credentialsFile != "" || impersonateServiceAccount != "") {
+		usagef("--emulator-host option cannot be specified with --credentials or --impersonate-service-account options")
+	}
+	var compareStartTimestamp, compareEndTimestamp time.Time
+	var diffMaxMemoryBytes int64
+	if command == commandDiff {
+		if endTimestamp.IsZero() && duration == 0 {
+			usagef("To diff, specify --end (or --duration) option as well")
+		}
+		if (compareStart == "") != (compareEnd == "") {
+			usagef("--compare-start and --compare-end options must be specified together")
+		}
+		var err error
+		if diffMaxMemoryBytes, err = parseByteSize(diffMaxMemory); err != nil {
+			usagef("invalid diff max memory: %v", err)
+		}
+		if compareStart != "" {
+			if compareStartTimestamp, err = time.Parse(time.RFC3339, compareStart); err != nil {
+				usagef("invalid compare start timestamp: %v", err)
+			}
+			if compareEndTimestamp, err = time.Parse(time.RFC3339, compareEnd); err != nil {
+				usagef("invalid compare end timestamp: %v", err)
+			}
+		}
+	}
+	if command == commandBench {
+		if benchTable == "" {
+			usagef("To bench, specify the table to write the transactions into with --bench-table")
+		}
+		if benchRate <= 0 || benchDuration <= 0 || benchPayloadSize < 0 {
+			usagef("invalid bench rate, duration or payload size: %v, %v, %d", benchRate, benchDuration, benchPayloadSize)
+		}
+	}
+	var requestPriority sppb.RequestOptions_Priority
+	switch priority {
+	case "":
+	case priorityLow:
+		requestPriority = sppb.RequestOptions_PRIORITY_LOW
+	case priorityMedium:
+		requestPriority = sppb.RequestOptions_PRIORITY_MEDIUM
+	case priorityHigh:
+		requestPriority = sppb.RequestOptions_PRIORITY_HIGH
+	default:
+		usagef("invalid priority: %s", priority)
+	}
+	if limit < 0 {
+		usagef("invalid limit: %d", limit)
+	}
+	if maxPartitions < 0 {
+		usagef("invalid max partitions: %d", maxPartitions)
+	}
+	if maxPartitions > 0 && endTimestamp.IsZero() && duration == 0 {
+		// Without the end, the partitions over the limit wait for the others forever.
+		usagef("--max-partitions option can be specified only with --end or --duration option")
+	}
+	if grpcPoolSize < 0 {
+		usagef("invalid gRPC pool size: %d", grpcPoolSize)
+	}
+	if keepaliveTime < 0 || keepaliveTimeout <= 0 {
+		usagef("invalid keepalive time or timeout: %v, %v", keepaliveTime, keepaliveTimeout)
+	}
+	if minSessions > 0 && maxSessions > 0 && minSessions > maxSessions {
+		usagef("--min-sessions must not be greater than --max-sessions: %d > %d", minSessions, maxSessions)
+	}
+	if partitionStartRate < 0 {
+		usagef("invalid partition start rate: %v", partitionStartRate)
+	}
+	if decodeWorkers < 0 {
+		usagef("invalid decode workers: %d", decodeWorkers)
+	}
+	// Cloud Spanner accepts the heartbeat interval from 1 second to 5 minutes.
+	if heartbeatInterval != 0 && (heartbeatInterval < time.Second || heartbeatInterval > 5*time.Minute) {
+		usagef("invalid heartbeat interval: %v, must be from 1s to 5m", heartbeatInterval)
+	}
+	if retryMaxAttempts < 1 {
+		usagef("invalid retry max attempts: %d", retryMaxAttempts)
+	}
+	if retryInitialBackoff <= 0 || retryMaxBackoff < retryInitialBackoff {
+		usagef("invalid retry backoff: %v to %v", retryInitialBackoff, retryMaxBackoff)
+	}
+	if retryBudget < 0 || retryBudgetWindow <= 0 {
+		usagef("invalid retry budget: %d within %v", retryBudget, retryBudgetWindow)
+	}
+	if redact != "" {
+		var mode changestreams.RedactMode
+		switch redactMode {
+		case redactModePlaceholder:
+			mode = changestreams.RedactModePlaceholder
+		case redactModeSHA256:
+			mode = changestreams.RedactModeSHA256
+		default:
+			usagef("invalid redact mode: %s", redactMode)
+		}
+		r, err := changestreams.NewColumnRedactor(strings.Split(redact, ","), mode)
+		if err != nil {
+			usagef("invalid redact option: %v", err)
+		}
+		redactor = r
+	}
+	if tui && (command == commandServe || visualizePartitions) {
+		usagef("--tui option cannot be specified with serve command or --visualize-partitions option")
+	}
+	if checkpointPath != "" && ((command != "" && command != commandRecord) || visualizePartitions) {
+		usagef("--checkpoint option can be specified only to read the streams into the sinks")
+	}
+	if notifyNewTables && ((command != "" && command != commandRecord) || visualizePartitions || tui) {
+		usagef("--notify-new-tables option can be specified only to read the streams into the sinks without --tui option")
+	}
+	if notifySchemaChanges && ((command != "" && command != commandRecord) || visualizePartitions || tui) {
+		usagef("--notify-schema-changes option can be specified only to read the streams into the sinks without --tui option")
+	}
+	if reconstructOldValues < 0 {
+		usagef("--reconstruct-old-values must not be negative")
+	}
+	if reconstructOldValues > 0 && ((command != "" && command != commandRecord) || visualizePartitions) {
+		usagef("--reconstruct-old-values option can be specified only to read the streams into the sinks")
+	}
+	if enrichRows && (command == commandReplay || visualizePartitions) {
+		usagef("--enrich-rows option cannot be specified with replay command or --visualize-partitions option")
+	}
+	if format == formatRaw && (command != "" || visualizePartitions || tui || verbose || emitSchema || annotateLag || redactor != nil || notifySchemaChanges || reconstructOldValues > 0 || enrichRows || deadLetterPath != "") {
+		usagef("raw format can be specified only to read the streams into the sinks without --tui, --verbose, --emit-schema, --annotate-lag, --redact, --notify-schema-changes, --reconstruct-old-values, --enrich-rows or --dead-letter options")
+	}
+	if deadLetterMaxErrorRate < 0 || deadLetterMaxErrorRate > 1 {
+		usagef("--dead-letter-max-error-rate must be between 0 and 1")
+	}
+	if cloudMonitoring && cloudMonitoringInterval < 5*time.Second {
+		usagef("--cloud-monitoring-interval must be at least 5s")
+	}
+	if healthAddr != "" && healthStallTimeout <= 0 {
+		usagef("--health-stall-timeout must be positive")
+	}
+	if traceExporter != "" && traceExporter != traceExporterStdout && traceExporter != traceExporterOTLP {
+		usagef("invalid trace exporter: %s", traceExporter)
+	}
 	if visualizePartitions {
-		if start == "" || end == "" {
-			exitf("To visualize partitions, specify --start and --end options as well")
+		if len(streamIDs) > 1 {
+			usagef("To visualize partitions, specify only one stream")
+		}
+		if !slices.Contains(vizFormats, vizFormat) {
+			usagef("invalid visualization format: %s", vizFormat)
+		}
+	}
+	if vizOutput != "" {
+		if !visualizePartitions {
+			usagef("--viz-output option can be specified only with --visualize-partitions option")
+		}
+		if vizImageFormat(vizOutput) != "" && vizFormat != vizFormatDOT {
+			usagef("To render the partitions into an image, --viz-format must be %s", vizFormatDOT)
 		}
 	}
+	if vizMetadataTable != "" && (!visualizePartitions || command != "") {
+		usagef("--viz-metadata-table option can be specified only with --visualize-partitions option")
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go handleInterrupt(cancel)
 
-	config := changestreams.Config{
-		StartTimestamp: startTimestamp,
-		EndTimestamp:   endTimestamp,
-		SpannerClientConfig: spanner.ClientConfig{
-			SessionPoolConfig: spanner.DefaultSessionPoolConfig,
-			DatabaseRole:      role,
-		},
+	if pprofAddr != "" {
+		stopPprof, err := servePprof(pprofAddr)
+		if err != nil {
+			exitf("failed to serve the profiles: %v", err)
+		}
+		defer stopPprof()
 	}
-	reader, err := changestreams.NewReaderWithConfig(ctx, projectID, instanceID, databaseID, streamID, config)
-	if err != nil {
-		exitf("failed to create a reader: %v", err)
+
+	var reader recordSource
+	// streamReaders is kept to report the unread partitions, as the reader is wrapped by the observers.
+	var readStreams streamReaders
+	var tracerProvider trace.TracerProvider
+	if traceExporter != "" {
+		tp, err := newTracerProvider(ctx, traceExporter, os.Stderr)
+		if err != nil {
+			exitf("failed to create the tracer provider: %v", err)
+		}
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			tp.Shutdown(ctx)
+		}()
+		tracerProvider = tp
+	}
+	var m *metrics
+	if metricsAddr != "" {
+		m = newMetrics()
+	}
+	var audit *auditLog
+	if auditLogPath != "" {
+		a, err := openAuditLog(auditLogPath)
+		if err != nil {
+			exitf("failed to open the audit log: %v", err)
+		}
+		defer a.Close()
+		audit = a
+	}
+	var checkpointState *checkpoint
+	var listTables func(ctx context.Context) ([]string, error)
+	if command == commandReplay {
+		reader = newReplayReader(replayPath, streamIDs, redactor)
+	} else {
+		opts, err := clientOptions(ctx, clientConfig{
+			emulatorHost:              emulatorHost,
+			endpoint:                  endpoint,
+			credentialsFile:           credentialsFile,
+			impersonateServiceAccount: impersonateServiceAccount,
+			quotaProject:              quotaProject,
+			grpcPoolSize:              grpcPoolSize,
+			keepaliveTime:             keepaliveTime,
+			keepaliveTimeout:          keepaliveTimeout,
+		})
+		if err != nil {
+			exitCodef(exitCodePermission, "failed to configure the client: %v", err)
+		}
+		dbPath := fmt.Sprintf("projects/%s/instances/%s/databases/%s", projectID, instanceID, databaseID)
+		if command == commandCreateStream || command == commandDropStream {
+			if len(streamIDs) != 1 || table != "" {
+				usagef("To %s, specify one stream with --stream", command)
+			}
+			if err := runStreamDDL(ctx, command, dbPath, streamIDs[0], createStreamConfig{
+				forAll:           forAll,
+				watch:            watchFlags,
+				valueCaptureType: valueCaptureType,
+				retentionPeriod:  retentionPeriod,
+			}, opts); err != nil {
+				exitCodef(errorExitCode(err, exitCodeError), "failed to %s: %v", command, err)
+			}
+			return
+		}
+		client, err := spanner.NewClientWithConfig(ctx, dbPath, spanner.ClientConfig{
+			SessionPoolConfig: sessionPoolConfig(minSessions, maxSessions),
+			DatabaseRole:      role,
+		}, opts...)
+		if err != nil {
+			exitCodef(errorExitCode(err, exitCodeError), "failed to create a client: %v", err)
+		}
+		defer client.Close()
+
+		if duration != 0 && startTimestamp.IsZero() {
+			// Fix the start timestamp by the database, not by the local clock, so that the window is exactly the duration
+			// of the commits.
+			ts, err := changestreams.CurrentTimestamp(ctx, client)
+			if err != nil {
+				exitCodef(errorExitCode(err, exitCodeError), "failed to get the current timestamp: %v", err)
+			}
+			startTimestamp = ts
+			endTimestamp = ts.Add(duration)
+		}
+
+		if vizMetadataTable != "" {
+			logger.Info("Reading the partition metadata table", "table", vizMetadataTable)
+			visualizer := newVisualizer(heartbeatInterval)
+			if err := visualizer.ReadMetadataTable(ctx, client, vizMetadataTable); err != nil {
+				exitCodef(errorExitCode(err, exitCodeError), "failed to read the partition metadata table: %v", err)
+			}
+			renderPartitions(visualizer, endTimestamp, vizFormat, vizOutput)
+			return
+		}
+
+		if table != "" {
+			streams, err := changestreams.StreamsForTable(ctx, client, table)
+			if err != nil {
+				exitCodef(errorExitCode(err, exitCodeError), "failed to find the change stream: %v", err)
+			}
+			switch len(streams) {
+			case 0:
+				exitCodef(exitCodeStreamNotFound, "no change stream watches table %s", table)
+			case 1:
+				logger.Info("Found the change stream watching the table", "stream", streams[0], "table", table)
+				streamIDs = streams
+			default:
+				usagef("multiple change streams watch table %s, specify one of them with --stream: %s", table, strings.Join(streams, ", "))
+			}
+		}
+
+		if command == commandDescribeStream {
+			for _, streamID := range streamIDs {
+				d, err := changestreams.DescribeStream(ctx, client, streamID)
+				if err != nil {
+					exitCodef(errorExitCode(err, exitCodeError), "failed to describe the change stream: %v", err)
+				}
+				if err := writeStreamDescription(os.Stdout, d, format); err != nil {
+					exitf("failed to write the description: %v", err)
+				}
+			}
+			return
+		}
+
+		config := changestreams.Config{
+			StartTimestamp:          startTimestamp,
+			EndTimestamp:            endTimestamp,
+			Redactor:                redactor,
+			Priority:                requestPriority,
+			MaxConcurrentPartitions: maxPartitions,
+			PartitionStartRate:      partitionStartRate,
+			ClampStartTimestamp:     clampStart,
+			DecodeWorkers:           decodeWorkers,
+			RawJSON:                 format == formatRaw,
+			EnrichRows:              enrichRows,
+			HeartbeatInterval:       heartbeatInterval,
+			Retry: changestreams.RetryPolicy{
+				MaxAttempts:    retryMaxAttempts,
+				InitialBackoff: retryInitialBackoff,
+				MaxBackoff:     retryMaxBackoff,
+				Budget:         retryBudget,
+				BudgetWindow:   retryBudgetWindow,
+			},
+			TracerProvider: tracerProvider,
+			Logger:         logger,
+		}
+		// The mods are decoded only for the subscribers matching the records, and not drawn in the partitions.
+		config.LazyMods = command == commandServe || visualizePartitions
+		if m != nil || audit != nil {
+			config.Retry.OnRetry = func(event *changestreams.RetryEvent) {
+				if m != nil {
+					m.onRetry(event)
+				}
+				if audit != nil {
+					audit.retry(event)
+				}
+			}
+		}
+		if queryStats {
+			config.OnQueryStats = func(stats *changestreams.QueryStats) {
+				logger.Info("Partition query finished", "stream", stats.StreamID, "partition_token", stats.PartitionToken,
+					"rows_returned", stats.RowsReturned, "rows_scanned", stats.RowsScanned, "cpu_time", stats.CPUTime, "elapsed_time", stats.ElapsedTime)
+				if m != nil {
+					m.onQueryStats(stats)
+				}
+			}
+		}
+		if command == commandDiff {
+			if err := runDiff(ctx, os.Stdout, client, streamIDs, config, diffConfig{
+				compareStartTimestamp: compareStartTimestamp,
+				compareEndTimestamp:   compareEndTimestamp,
+				format:                format,
+				maxMemory:             diffMaxMemoryBytes,
+			}); err != nil {
+				exitCodef(errorExitCode(err, exitCodeReadFailure), "failed to diff: %v", err)
+			}
+			return
+		}
+		if command == commandBench {
+			logger.Info("Writing the transactions and reading the stream", "table", benchTable, "rate", benchRate, "duration", benchDuration)
+			if err := runBench(ctx, os.Stdout, client, streamIDs, config, benchConfig{
+				table:       benchTable,
+				rate:        benchRate,
+				duration:    benchDuration,
+				payloadSize: benchPayloadSize,
+				format:      format,
+			}); err != nil {
+				exitCodef(errorExitCode(err, exitCodeReadFailure), "failed to bench: %v", err)
+			}
+			return
+		}
+		listTables = func(ctx context.Context) ([]string, error) {
+			return changestreams.Tables(ctx, client)
+		}
+		var resumeTimestamps map[string]time.Time
+		if checkpointPath != "" {
+			state, err := loadCheckpoint(checkpointPath)
+			if err != nil {
+				exitf("failed to load the checkpoint: %v", err)
+			}
+			checkpointState = state
+			resumeTimestamps = state.resumeTimestamps()
+			for _, streamID := range streamIDs {
+				if ts, ok := resumeTimestamps[streamID]; ok {
+					logger.Info("Resuming the stream", "stream", streamID, "timestamp", ts)
+					if audit != nil {
+						audit.resume(streamID, ts, "checkpoint")
+					}
+				}
+			}
+		}
+		readers, err := newStreamReaders(ctx, client, streamIDs, config, resumeTimestamps)
+		if err != nil {
+			exitCodef(errorExitCode(err, exitCodeError), "failed to create a reader: %v", err)
+		}
+		if showCoverage {
+			for _, streamID := range streamIDs {
+				d, err := changestreams.DescribeStreamCoverage(ctx, client, streamID)
+				if err != nil {
+					exitCodef(errorExitCode(err, exitCodeError), "failed to describe the change stream: %v", err)
+				}
+				logger.Info("Stream coverage", "stream", streamID, "tables", watchedTables(d))
+			}
+		}
+		reader = readers
+		readStreams = readers
 	}
 	defer reader.Close()
 
+	if m != nil {
+		stopMetrics, err := serveMetrics(metricsAddr, m)
+		if err != nil {
+			exitf("failed to serve the metrics: %v", err)
+		}
+		defer stopMetrics()
+		reader = observedSource{recordSource: reader, observe: m.observe}
+	}
+	if debugAddr != "" {
+		vars := newDebugVars()
+		stopDebugVars, err := serveDebugVars(debugAddr, vars)
+		if err != nil {
+			exitf("failed to serve the debug variables: %v", err)
+		}
+		defer stopDebugVars()
+		reader = observedSource{recordSource: reader, observe: vars.observe}
+	}
+	if cloudMonitoring {
+		opts, err := clientOptions(ctx, clientConfig{
+			credentialsFile:           credentialsFile,
+			impersonateServiceAccount: impersonateServiceAccount,
+			quotaProject:              quotaProject,
+		})
+		if err != nil {
+			exitf("failed to configure the Cloud Monitoring client: %v", err)
+		}
+		exporter, err := newCloudMonitoringExporter(ctx, projectID, instanceID+"/"+databaseID, cloudMonitoringInterval, opts...)
+		if err != nil {
+			exitf("failed to create the Cloud Monitoring client: %v", err)
+		}
+		exporter.start(ctx)
+		defer exporter.Close()
+		reader = observedSource{recordSource: reader, observe: exporter.observe}
+	}
+	if healthAddr != "" {
+		h := newHealthChecker(healthStallTimeout)
+		stopHealth, err := serveHealth(healthAddr, h)
+		if err != nil {
+			exitf("failed to serve the health checks: %v", err)
+		}
+		defer stopHealth()
+		reader = observedSource{recordSource: reader, observe: h.observe}
+	}
+
+	if command == commandServe {
+		// The context is canceled only by the interrupt, which is a clean exit.
+		if err := serve(ctx, reader, serveConfig{grpcAddr: grpcAddr, httpAddr: httpAddr}); err != nil && ctx.Err() == nil {
+			exitCodef(errorExitCode(err, exitCodeReadFailure), "failed to serve stream: %v", err)
+		}
+		return
+	}
+
 	if visualizePartitions {
-		fmt.Fprintf(os.Stderr, "Reading the stream and analyzing partitions...\n\n")
-		visualizer := NewPartitionVisualizer(os.Stdout)
-		if err := reader.Read(ctx, visualizer.Read); err != nil {
-			exitf("failed to read stream: %v", err)
+		if endTimestamp.IsZero() {
+			logger.Info("Reading the stream and analyzing partitions, press Ctrl-C to draw them")
+		} else {
+			logger.Info("Reading the stream and analyzing partitions")
 		}
-		visualizer.Draw()
+		visualizer := newVisualizer(heartbeatInterval)
+		if err := reader.Read(ctx, visualizer.Read); err != nil && ctx.Err() == nil {
+			exitCodef(errorExitCode(err, exitCodeReadFailure), "failed to read stream: %v", err)
+		}
+		renderPartitions(visualizer, endTimestamp, vizFormat, vizOutput)
 		return
 	}
 
-	fmt.Fprintf(os.Stderr, "Reading the stream...\n")
+	params, err := parseSinkParams(sinkParamFlags)
+	if err != nil {
+		usagef("%v", err)
+	}
+	// The dedicated options are shortcuts for --sink and --sink-param.
+	switch {
+	case command == commandRecord:
+		sinkName = sinkRecord
+		params.Set("path", recordPath)
+	case outputPath != "":
+		sinkName = sinkOutput
+		params.Set("path", outputPath)
+		params.Set("atomic", strconv.FormatBool(outputAtomic))
+		params.Set("fsync", strconv.FormatBool(outputFsync))
+	case bigQueryTable != "":
+		sinkName = sinkBigQuery
+		params.Set("table", bigQueryTable)
+	case gcsPath != "":
+		sinkName = sinkGCS
+		params.Set("path", gcsPath)
+		params.Set("max_size", gcsMaxSize)
+		params.Set("max_age", gcsMaxAge.String())
+	case outputFile != "":
+		sinkName = sinkFile
+		params.Set("path", outputFile)
+		params.Set("max_size", outputFileMaxSize)
+		params.Set("max_age", outputFileMaxAge.String())
+		params.Set("gzip", strconv.FormatBool(outputFileGzip))
+	case webhookURL != "":
+		sinkName = sinkWebhook
+		params.Set("url", webhookURL)
+		for _, header := range webhookHeaders {
+			params.Add("header", header)
+		}
+		params.Set("batch_size", strconv.Itoa(webhookBatchSize))
+		params.Set("flush_interval", webhookFlushInterval.String())
+		params.Set("max_retries", strconv.Itoa(webhookMaxRetries))
+	case elasticsearchURL != "":
+		sinkName = sinkElasticsearch
+		params.Set("url", elasticsearchURL)
+		params.Set("index_prefix", elasticsearchIndexPrefix)
+	case sqlitePath != "":
+		sinkName = sinkSQLite
+		params.Set("path", sqlitePath)
+	case unixSocket != "":
+		sinkName = sinkUnixSocket
+		params.Set("path", unixSocket)
+	case execCommand != "":
+		sinkName = sinkExec
+		params.Set("command", execCommand)
+	case amqpURL != "":
+		sinkName = sinkAMQP
+		params.Set("url", amqpURL)
+		params.Set("exchange", amqpExchange)
+		params.Set("routing_key", amqpRoutingKey)
+	case stats:
+		sinkName = sinkStats
+		params.Set("interval", statsInterval.String())
+	}
+	// The other sinks need the mods, which aren't decoded in the raw format.
+	if format == formatRaw && sinkName != sinkStdout && sinkName != sinkOutput && sinkName != sinkFile && sinkName != sinkGCS && sinkName != sinkExec {
+		usagef("raw format cannot be written to the %s sink", sinkName)
+	}
+	params.Set(sinkParamProject, projectID)
+	params.Set(sinkParamFormat, format)
+	params.Set(sinkParamVerbose, strconv.FormatBool(verbose))
+	params.Set(sinkParamEmitSchema, strconv.FormatBool(emitSchema))
+	params.Set(sinkParamAnnotateLag, strconv.FormatBool(annotateLag))
+	params.Set(sinkParamStreamTag, strconv.FormatBool(len(streamIDs) > 1))
+
+	var sink changestreams.Sink
+	if tui {
+		sink = NewTUISink(cancel)
+	} else {
+		sink, err = changestreams.NewSink(sinkName, params)
+		if err != nil {
+			exitf("failed to create the %s sink: %v", sinkName, err)
+		}
+	}
+	output := &deferredCloseSink{Sink: sink}
+	sink = output
+	if deadLetterPath != "" {
+		f, err := openDeadLetterFile(deadLetterPath)
+		if err != nil {
+			exitf("failed to open the dead letter file: %v", err)
+		}
+		defer f.Close()
+		sink = changestreams.NewDeadLetterSink(sink, f.write, deadLetterMaxErrorRate)
+	}
+	if checkpointState != nil {
+		sink = newCheckpointSink(sink, checkpointPath, defaultCheckpointInterval, checkpointState)
+	}
+	if notifyNewTables {
+		sink = newNewTableSink(sink, logger, listTables, defaultTableRefreshInterval)
+	}
+	if notifySchemaChanges {
+		sink = newSchemaChangeSink(sink, logger)
+	}
+	if limit > 0 {
+		sink = newLimitSink(sink, limit)
+	}
+	// The progress is shown only to the users watching the terminal.
+	if !quiet && !tui && sinkName != sinkStats && progressInterval > 0 && term.IsTerminal(int(os.Stderr.Fd())) {
+		sink = newProgressSink(sink, os.Stderr, progressInterval)
+	}
+	if summaryInterval > 0 {
+		sink = newSummarySink(sink, logger, summaryInterval)
+	}
+	if verifySequence {
+		sink = newVerifySink(sink, logger)
+	}
+	if reconstructOldValues > 0 {
+		sink = changestreams.NewReconstructSink(sink, changestreams.NewOldValuesReconstructor(changestreams.NewLRURowStore(reconstructOldValues)))
+	}
+
+	if command == commandReplay {
+		logger.Info("Replaying the file", "path", replayPath)
+	} else {
+		logger.Info("Reading the stream", "streams", streamIDs)
+	}
 
-	logger := &Logger{
-		out:     os.Stdout,
-		format:  format,
-		verbose: verbose,
+	err = reader.ReadToSink(ctx, sink)
+	readStreams.logUnreadPartitions(logger)
+	// The context is canceled only by the interrupt or quitting the TUI, which are clean exits.
+	failed := err != nil && ctx.Err() == nil
+	if closeErr := output.finish(failed); closeErr != nil && !failed {
+		exitf("failed to close sink: %v", closeErr)
 	}
-	if err := reader.Read(ctx, logger.Read); err != nil {
-		exitf("failed to read stream: %v", err)
+	if failed {
+		exitCodef(errorExitCode(err, exitCodeReadFailure), "failed to read stream: %v", err)
 	}
 }
 
+// stringsFlag is a flag that can be specified multiple times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
 func exitf(format string, a ...interface{}) {
-	message := fmt.Sprintf(format, a...)
-	if !strings.HasSuffix(message, "\n") {
-		message += "\n"
+	exitCodef(exitCodeError, format, a...)
+}
+
+// usagef exits for the invalid usage.
+func usagef(format string, a ...interface{}) {
+	exitCodef(exitCodeUsage, format, a...)
+}
+
+func exitCodef(code int, format string, a ...interface{}) {
+	logger.Error(strings.TrimSuffix(fmt.Sprintf(format, a...), "\n"))
+	os.Exit(code)
+}
+
+// newVisualizer creates a PartitionVisualizer writing to the standard output. The partitions without any records for
+// two heartbeat intervals are lagged.
+func newVisualizer(heartbeatInterval time.Duration) *PartitionVisualizer {
+	visualizer := NewPartitionVisualizer(os.Stdout)
+	visualizer.lagThreshold = 2 * heartbeatInterval
+	if heartbeatInterval == 0 {
+		visualizer.lagThreshold = 2 * 10 * time.Second
+	}
+	return visualizer
+}
+
+// renderPartitions draws the partitions to the output file, or the standard output if it's empty, and logs their
+// summary. Without --end, the window ends when it's interrupted.
+func renderPartitions(visualizer *PartitionVisualizer, endTimestamp time.Time, format, output string) {
+	visualizer.windowEnd = endTimestamp
+	if visualizer.windowEnd.IsZero() {
+		visualizer.windowEnd = time.Now()
+	}
+	switch {
+	case output == "":
+		if err := visualizer.Render(format); err != nil {
+			exitf("failed to draw the partitions: %v", err)
+		}
+	case vizImageFormat(output) != "":
+		var dot bytes.Buffer
+		visualizer.out = &dot
+		if err := visualizer.Render(format); err != nil {
+			exitf("failed to draw the partitions: %v", err)
+		}
+		if err := renderImage(dot.Bytes(), output); err != nil {
+			exitf("failed to render the partitions: %v", err)
+		}
+		logger.Info("Rendered the partitions", "path", output)
+	default:
+		f, err := os.Create(output)
+		if err != nil {
+			exitf("failed to create the output file: %v", err)
+		}
+		visualizer.out = f
+		if err := visualizer.Render(format); err != nil {
+			exitf("failed to draw the partitions: %v", err)
+		}
+		if err := f.Close(); err != nil {
+			exitf("failed to close the output file: %v", err)
+		}
 	}
-	fmt.Fprint(os.Stderr, message)
-	os.Exit(1)
+	summary := visualizer.Summary()
+	logger.Info("Partition summary", "partitions", summary.Partitions, "splits", summary.Splits, "merges", summary.Merges,
+		"avg_lifetime", summary.AvgLifetime, "max_concurrent_partitions", summary.MaxConcurrent)
 }
 
 func handleInterrupt(cancel context.CancelFunc) {
@@ -0,0 +1,218 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const metricsNamespace = "changestreams"
+
+// partitionKey identifies a partition of a stream.
+type partitionKey struct {
+	streamID string
+	token    string
+}
+
+// tableKey identifies a table of a stream.
+type tableKey struct {
+	streamID string
+	table    string
+}
+
+// metrics are the Prometheus metrics of the records read from the streams.
+type metrics struct {
+	registry    *prometheus.Registry
+	records     *prometheus.CounterVec
+	bytes       *prometheus.CounterVec
+	lastCommit  *prometheus.GaugeVec
+	commitLag   *prometheus.GaugeVec
+	retries     prometheus.Counter
+	queryCPU    *prometheus.CounterVec
+	rowsScanned *prometheus.CounterVec
+	now         func() time.Time
+
+	// partitions is the last timestamp of the active partitions.
+	partitions map[partitionKey]time.Time
+	// lastCommits is the last commit timestamp of the tables.
+	lastCommits map[tableKey]time.Time
+	mu          sync.Mutex
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		records: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "records_total",
+			Help:      "Number of the data change records read.",
+		}, []string{"stream", "table", "mod_type"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "record_bytes_total",
+			Help:      "Size of the mods of the data change records read in JSON.",
+		}, []string{"stream", "table"}),
+		lastCommit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "last_commit_timestamp_seconds",
+			Help:      "Commit timestamp of the last data change record read of the table.",
+		}, []string{"stream", "table"}),
+		commitLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "commit_lag_seconds",
+			Help:      "Lag of the last data change record read behind its commit timestamp.",
+		}, []string{"stream"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "spanner_query_retries_total",
+			Help:      "Number of the retried partition queries.",
+		}),
+		queryCPU: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "query_cpu_seconds_total",
+			Help:      "CPU time of the finished partition queries, collected by --query-stats.",
+		}, []string{"stream"}),
+		rowsScanned: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "query_rows_scanned_total",
+			Help:      "Number of the rows scanned by the finished partition queries, collected by --query-stats.",
+		}, []string{"stream"}),
+		now:         time.Now,
+		partitions:  make(map[partitionKey]time.Time),
+		lastCommits: make(map[tableKey]time.Time),
+	}
+	m.registry.MustRegister(
+		m.records,
+		m.bytes,
+		m.lastCommit,
+		m.commitLag,
+		m.retries,
+		m.queryCPU,
+		m.rowsScanned,
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "active_partitions",
+			Help:      "Number of the partitions being read.",
+		}, m.activePartitions),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "watermark_lag_seconds",
+			Help:      "Lag of the last timestamp of the slowest partition behind the current time.",
+		}, m.watermarkLag),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// observe updates the metrics by the result.
+func (m *metrics) observe(result *changestreams.ReadResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := partitionKey{streamID: result.StreamID, token: result.PartitionToken}
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			m.records.WithLabelValues(result.StreamID, r.TableName, r.ModType).Inc()
+			m.bytes.WithLabelValues(result.StreamID, r.TableName).Add(float64(modsSize(r)))
+			// The records of the partitions are not ordered by the commit timestamps.
+			table := tableKey{streamID: result.StreamID, table: r.TableName}
+			if r.CommitTimestamp.After(m.lastCommits[table]) {
+				m.lastCommits[table] = r.CommitTimestamp
+				m.lastCommit.WithLabelValues(result.StreamID, r.TableName).Set(float64(r.CommitTimestamp.UnixNano()) / 1e9)
+			}
+			m.commitLag.WithLabelValues(result.StreamID).Set(m.now().Sub(r.CommitTimestamp).Seconds())
+			m.touchPartition(key, r.CommitTimestamp)
+		}
+		for _, r := range changeRecord.HeartbeatRecords {
+			m.touchPartition(key, r.Timestamp)
+		}
+		if len(changeRecord.ChildPartitionsRecords) > 0 {
+			// The partition finishes after returning the child partitions.
+			delete(m.partitions, key)
+		}
+	}
+}
+
+func (m *metrics) touchPartition(key partitionKey, ts time.Time) {
+	if key.token == "" {
+		// The initial query is not a partition.
+		return
+	}
+	if ts.After(m.partitions[key]) {
+		m.partitions[key] = ts
+	}
+}
+
+func (m *metrics) activePartitions() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return float64(len(m.partitions))
+}
+
+func (m *metrics) watermarkLag() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var watermark time.Time
+	for _, ts := range m.partitions {
+		if watermark.IsZero() || ts.Before(watermark) {
+			watermark = ts
+		}
+	}
+	if watermark.IsZero() {
+		return 0
+	}
+	return m.now().Sub(watermark).Seconds()
+}
+
+// onRetry counts the retries of the partition queries. It's called by changestreams.RetryPolicy.
+func (m *metrics) onRetry(event *changestreams.RetryEvent) {
+	m.retries.Inc()
+}
+
+// onQueryStats counts the costs of the partition queries. It's called by changestreams.Config.OnQueryStats.
+func (m *metrics) onQueryStats(stats *changestreams.QueryStats) {
+	m.queryCPU.WithLabelValues(stats.StreamID).Add(stats.CPUTime.Seconds())
+	m.rowsScanned.WithLabelValues(stats.StreamID).Add(float64(stats.RowsScanned))
+}
+
+// serveMetrics serves the metrics on /metrics of the address in the background, and returns the function to stop it.
+func serveMetrics(addr string, m *metrics) (func(), error) {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux}
+	go server.Serve(lis)
+	logger.Info("Serving the metrics", "url", fmt.Sprintf("http://%s/metrics", lis.Addr()))
+	return func() {
+		server.Shutdown(context.Background())
+	}, nil
+}
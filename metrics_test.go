@@ -0,0 +1,113 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	m := newMetrics()
+	m.now = func() time.Time { return mustParseTime(t, "2022-12-04T18:01:00Z") }
+	recorder := &recordingSink{}
+	sink := &observedSink{Sink: recorder, observe: m.observe}
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+
+	data := func(token, ts, modType string) *changestreams.ReadResult {
+		r := newTestDataChangeRecord(t, ts, "PlayerId")
+		r.ModType = modType
+		result := newTestReadResult(r)
+		result.StreamID = "s"
+		result.PartitionToken = token
+		return result
+	}
+	heartbeat := func(token, ts string) *changestreams.ReadResult {
+		return &changestreams.ReadResult{
+			StreamID:       "s",
+			PartitionToken: token,
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{HeartbeatRecords: []*changestreams.HeartbeatRecord{{Timestamp: mustParseTime(t, ts)}}},
+			},
+		}
+	}
+	childPartitions := func(token string) *changestreams.ReadResult {
+		return &changestreams.ReadResult{
+			StreamID:       "s",
+			PartitionToken: token,
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{{}}},
+			},
+		}
+	}
+	for _, result := range []*changestreams.ReadResult{
+		data("a", "2022-12-04T18:00:00Z", "INSERT"),
+		data("a", "2022-12-04T18:00:10Z", "UPDATE"),
+		data("b", "2022-12-04T18:00:20Z", "INSERT"),
+		heartbeat("c", "2022-12-04T18:00:30Z"),
+		childPartitions("a"),
+	} {
+		if err := sink.Write(result); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	m.onRetry(&changestreams.RetryEvent{StreamID: "s", PartitionToken: "b", Attempt: 1, Err: errors.New("unavailable")})
+	m.onQueryStats(&changestreams.QueryStats{StreamID: "s", PartitionToken: "a", RowsScanned: 10, CPUTime: 1500 * time.Millisecond})
+
+	if got := testutil.ToFloat64(m.records.WithLabelValues("s", "Players", "INSERT")); got != 2 {
+		t.Errorf("records_total of INSERT = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.records.WithLabelValues("s", "Players", "UPDATE")); got != 1 {
+		t.Errorf("records_total of UPDATE = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.bytes.WithLabelValues("s", "Players")); got != 213 {
+		t.Errorf("record_bytes_total = %v, want 213", got)
+	}
+	if got := testutil.ToFloat64(m.lastCommit.WithLabelValues("s", "Players")); got != float64(mustParseTime(t, "2022-12-04T18:00:20Z").Unix()) {
+		t.Errorf("last_commit_timestamp_seconds = %v", got)
+	}
+	// The last record of s was committed at 18:00:20.
+	if got := testutil.ToFloat64(m.commitLag.WithLabelValues("s")); got != 40 {
+		t.Errorf("commit_lag_seconds = %v, want 40", got)
+	}
+	if got := testutil.ToFloat64(m.retries); got != 1 {
+		t.Errorf("spanner_query_retries_total = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(m.queryCPU.WithLabelValues("s")); got != 1.5 {
+		t.Errorf("query_cpu_seconds_total = %v, want 1.5", got)
+	}
+	if got := testutil.ToFloat64(m.rowsScanned.WithLabelValues("s")); got != 10 {
+		t.Errorf("query_rows_scanned_total = %v, want 10", got)
+	}
+	if got := m.activePartitions(); got != 2 {
+		t.Errorf("active_partitions = %v, want 2", got)
+	}
+	// b is the slowest partition after a finished.
+	if got := m.watermarkLag(); got != 40 {
+		t.Errorf("watermark_lag_seconds = %v, want 40", got)
+	}
+	if got := len(recorder.timestamps); got != 3 {
+		t.Errorf("written records = %d, want 3", got)
+	}
+}
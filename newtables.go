@@ -0,0 +1,150 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// defaultTableRefreshInterval is the interval of refreshing the tables of the database.
+const defaultTableRefreshInterval = time.Minute
+
+// newTableSink prints notices about the tables created after the tail started, which the streams FOR ALL tables start
+// watching without restarting. The tables are refreshed periodically to notice the created tables, and the first
+// records of each of them are noticed as well.
+//
+// The records are self-describing, so the records of the new tables are decoded and labeled, e.g. by the schema
+// records, without the refreshed tables.
+type newTableSink struct {
+	changestreams.Sink
+	logger     *slog.Logger
+	listTables func(ctx context.Context) ([]string, error)
+	interval   time.Duration
+
+	// initialTables are the tables existing when the sink was opened.
+	initialTables map[string]bool
+	// tables are the tables known to exist.
+	tables map[string]bool
+	// appeared are the new tables whose records have been written.
+	appeared map[string]bool
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+func newNewTableSink(sink changestreams.Sink, logger *slog.Logger, listTables func(ctx context.Context) ([]string, error), interval time.Duration) *newTableSink {
+	return &newTableSink{
+		Sink:          sink,
+		logger:        logger,
+		listTables:    listTables,
+		interval:      interval,
+		initialTables: make(map[string]bool),
+		tables:        make(map[string]bool),
+		appeared:      make(map[string]bool),
+	}
+}
+
+func (s *newTableSink) Open(ctx context.Context) error {
+	tables, err := s.listTables(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list the tables: %w", err)
+	}
+	for _, table := range tables {
+		s.initialTables[table] = true
+		s.tables[table] = true
+	}
+	if err := s.Sink.Open(ctx); err != nil {
+		return err
+	}
+
+	// The context of Open must not be retained.
+	refreshCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	if s.interval > 0 {
+		s.wg.Add(1)
+		go s.refreshPeriodically(refreshCtx)
+	}
+	return nil
+}
+
+func (s *newTableSink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			if s.initialTables[r.TableName] || s.appeared[r.TableName] {
+				continue
+			}
+			s.appeared[r.TableName] = true
+			s.tables[r.TableName] = true
+			s.logger.Info("Records of a new table appear", "table", r.TableName, "stream", result.StreamID)
+		}
+	}
+	s.mu.Unlock()
+
+	return s.Sink.Write(result)
+}
+
+// Close stops refreshing the tables.
+func (s *newTableSink) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	return s.Sink.Close()
+}
+
+func (s *newTableSink) refreshPeriodically(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// refresh lists the tables, and prints the notices of the created ones.
+func (s *newTableSink) refresh(ctx context.Context) {
+	tables, err := s.listTables(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			// The refresh is retried at the next interval.
+			s.logger.Warn("Failed to refresh the tables", "error", err)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, table := range tables {
+		if !s.tables[table] {
+			s.tables[table] = true
+			s.logger.Info("A table was created", "table", table)
+		}
+	}
+}
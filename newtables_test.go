@@ -0,0 +1,76 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewTableSink(t *testing.T) {
+	var out bytes.Buffer
+	tables := []string{"Players"}
+	recorder := &recordingSink{}
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	sink := newNewTableSink(recorder, logger, func(ctx context.Context) ([]string, error) {
+		return tables, nil
+	}, 0)
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+
+	newRecord := func(ts, table string) *changestreams.ReadResult {
+		r := newTestDataChangeRecord(t, ts, "PlayerId")
+		r.TableName = table
+		result := newTestReadResult(r)
+		result.StreamID = "s"
+		return result
+	}
+	write := func(ts, table string) {
+		if err := sink.Write(newRecord(ts, table)); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+
+	write("2022-12-04T18:00:00Z", "Players")
+	tables = []string{"Players", "Items"}
+	sink.refresh(context.Background())
+	write("2022-12-04T18:01:00Z", "Items")
+	write("2022-12-04T18:02:00Z", "Items")
+	// Created and dropped between the refreshes.
+	write("2022-12-04T18:03:00Z", "Scores")
+	sink.refresh(context.Background())
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if diff := cmp.Diff(recorder.timestamps, []string{"18:00", "18:01", "18:02", "18:03"}); diff != "" {
+		t.Errorf("written records have diff = %v", diff)
+	}
+	expected := `level=INFO msg="A table was created" table=Items
+level=INFO msg="Records of a new table appear" table=Items stream=s
+level=INFO msg="Records of a new table appear" table=Scores stream=s
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("notices have diff = %v", diff)
+	}
+}
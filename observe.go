@@ -0,0 +1,51 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// observedSource passes the results read from the source to observe, e.g. to update the metrics, before the consumer.
+type observedSource struct {
+	recordSource
+	observe func(result *changestreams.ReadResult)
+}
+
+func (s observedSource) Read(ctx context.Context, f func(result *changestreams.ReadResult) error) error {
+	return s.recordSource.Read(ctx, func(result *changestreams.ReadResult) error {
+		s.observe(result)
+		return f(result)
+	})
+}
+
+func (s observedSource) ReadToSink(ctx context.Context, sink changestreams.Sink) error {
+	return s.recordSource.ReadToSink(ctx, &observedSink{Sink: sink, observe: s.observe})
+}
+
+// observedSink passes the results to observe before writing them to the sink.
+type observedSink struct {
+	changestreams.Sink
+	observe func(result *changestreams.ReadResult)
+}
+
+func (s *observedSink) Write(result *changestreams.ReadResult) error {
+	s.observe(result)
+	return s.Sink.Write(result)
+}
@@ -0,0 +1,85 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// OutputFile writes to a local file.
+//
+// If atomic is set, the records are written to a temporary file in the same directory, which is renamed to the path
+// on Close, so that other processes never see a partial file. If fsync is set, the file is synced to the disk on Close.
+type OutputFile struct {
+	*os.File
+	path   string
+	atomic bool
+	fsync  bool
+}
+
+// NewOutputFile creates or truncates the file.
+func NewOutputFile(path string, atomic, fsync bool) (*OutputFile, error) {
+	var f *os.File
+	var err error
+	if atomic {
+		f, err = os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	} else {
+		f, err = os.Create(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &OutputFile{
+		File:   f,
+		path:   path,
+		atomic: atomic,
+		fsync:  fsync,
+	}, nil
+}
+
+// Abort closes the file, and removes it instead of renaming it to the path if atomic is set, e.g. when reading fails.
+func (f *OutputFile) Abort() error {
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if f.atomic {
+		return os.Remove(f.File.Name())
+	}
+	return nil
+}
+
+// Close closes the file, and renames it to the path if atomic is set.
+func (f *OutputFile) Close() error {
+	if f.fsync {
+		if err := f.File.Sync(); err != nil {
+			f.File.Close()
+			return err
+		}
+	}
+	if err := f.File.Close(); err != nil {
+		return err
+	}
+	if f.atomic {
+		// CreateTemp creates the file with 0600, while os.Create uses 0666 before umask.
+		if err := os.Chmod(f.File.Name(), 0644); err != nil {
+			return err
+		}
+		return os.Rename(f.File.Name(), f.path)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOutputFile(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		atomic bool
+	}{
+		{desc: "plain", atomic: false},
+		{desc: "atomic", atomic: true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "changes.jsonl")
+			if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+
+			f, err := NewOutputFile(path, test.atomic, true)
+			if err != nil {
+				t.Fatalf("NewOutputFile error: %v", err)
+			}
+			if _, err := f.Write([]byte("new\n")); err != nil {
+				t.Fatalf("Write error: %v", err)
+			}
+
+			before, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if test.atomic && string(before) != "old\n" {
+				t.Errorf("file must not be replaced before Close, got %q", before)
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatalf("Close error: %v", err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if diff := cmp.Diff(string(got), "new\n"); diff != "" {
+				t.Errorf("file has diff = %v", diff)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("failed to read dir: %v", err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("temporary file must be removed, got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestOutputSinkAbort(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "changes.jsonl")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	sink, err := newOutputSink(changestreams.SinkParams{"path": {path}, "atomic": {"true"}})
+	if err != nil {
+		t.Fatalf("newOutputSink error: %v", err)
+	}
+	// The sink is closed by finish after reading fails.
+	output := &deferredCloseSink{Sink: sink}
+	if err := output.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	if err := output.Write(&changestreams.ReadResult{}); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := output.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if err := output.finish(true); err != nil {
+		t.Fatalf("finish error: %v", err)
+	}
+
+	// The partial output must not replace the destination.
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if diff := cmp.Diff(string(got), "old\n"); diff != "" {
+		t.Errorf("file has diff = %v", diff)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("temporary file must be removed, got %d entries", len(entries))
+	}
+}
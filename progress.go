@@ -0,0 +1,177 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// defaultProgressInterval is the default interval of the progress indicator.
+const defaultProgressInterval = 10 * time.Second
+
+// progressSink shows a progress line on the terminal while no data change records arrive, so that "no changes" can be
+// told from "stuck". The line shows the last heartbeat, the active partitions and the lag of the slowest partition.
+// It's overwritten in place, and cleared before the next records are written.
+type progressSink struct {
+	changestreams.Sink
+	out      io.Writer
+	interval time.Duration
+	now      func() time.Time
+
+	// lastRecordAt is when the last data change record was written, or when the sink was opened.
+	lastRecordAt  time.Time
+	lastHeartbeat time.Time
+	// partitions is the last timestamp of the active partitions.
+	partitions map[string]time.Time
+	// shown is true while the progress line is shown.
+	shown bool
+	done  chan struct{}
+	wg    sync.WaitGroup
+	mu    sync.Mutex
+}
+
+func newProgressSink(sink changestreams.Sink, out io.Writer, interval time.Duration) *progressSink {
+	return &progressSink{
+		Sink:       sink,
+		out:        out,
+		interval:   interval,
+		now:        time.Now,
+		partitions: make(map[string]time.Time),
+		done:       make(chan struct{}),
+	}
+}
+
+func (s *progressSink) Open(ctx context.Context) error {
+	if err := s.Sink.Open(ctx); err != nil {
+		return err
+	}
+	s.lastRecordAt = s.now()
+	s.wg.Add(1)
+	go s.reportPeriodically()
+	return nil
+}
+
+func (s *progressSink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	hasData := false
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			hasData = true
+			s.touchPartition(result.PartitionToken, r.CommitTimestamp)
+		}
+		for _, r := range changeRecord.HeartbeatRecords {
+			if r.Timestamp.After(s.lastHeartbeat) {
+				s.lastHeartbeat = r.Timestamp
+			}
+			s.touchPartition(result.PartitionToken, r.Timestamp)
+		}
+		if len(changeRecord.ChildPartitionsRecords) > 0 {
+			// The partition finishes after returning the child partitions.
+			delete(s.partitions, result.PartitionToken)
+		}
+	}
+	if hasData {
+		s.lastRecordAt = s.now()
+		s.clear()
+	}
+	s.mu.Unlock()
+
+	return s.Sink.Write(result)
+}
+
+func (s *progressSink) touchPartition(token string, ts time.Time) {
+	if token == "" {
+		// The initial query is not a partition.
+		return
+	}
+	if ts.After(s.partitions[token]) {
+		s.partitions[token] = ts
+	}
+}
+
+// Close clears the progress line.
+func (s *progressSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	s.clear()
+	s.mu.Unlock()
+	return s.Sink.Close()
+}
+
+func (s *progressSink) reportPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.report()
+		}
+	}
+}
+
+// report shows the progress line if no data change records were written during the interval.
+func (s *progressSink) report() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	idle := now.Sub(s.lastRecordAt)
+	if idle < s.interval {
+		return
+	}
+
+	lastHeartbeat := "none"
+	if !s.lastHeartbeat.IsZero() {
+		lastHeartbeat = s.lastHeartbeat.Format(time.RFC3339)
+	}
+	// The lag of the slowest partition.
+	var watermark time.Time
+	for _, ts := range s.partitions {
+		if watermark.IsZero() || ts.Before(watermark) {
+			watermark = ts
+		}
+	}
+	lag := "unknown"
+	if !watermark.IsZero() {
+		lag = now.Sub(watermark).Round(time.Millisecond).String()
+	}
+
+	line := fmt.Sprintf("No changes for %v | Last heartbeat: %s | Partitions: %d | Lag: %s",
+		idle.Round(time.Second), lastHeartbeat, len(s.partitions), lag)
+	fmt.Fprintf(s.out, "\r\033[K%s", line)
+	s.shown = true
+}
+
+// clear clears the progress line.
+func (s *progressSink) clear() {
+	if s.shown {
+		fmt.Fprint(s.out, "\r\033[K")
+		s.shown = false
+	}
+}
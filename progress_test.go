@@ -0,0 +1,82 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProgressSink(t *testing.T) {
+	var out bytes.Buffer
+	now := mustParseTime(t, "2022-12-04T18:00:00Z")
+	recorder := &recordingSink{}
+	sink := newProgressSink(recorder, &out, time.Hour)
+	sink.now = func() time.Time { return now }
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+
+	heartbeat := func(token, ts string) *changestreams.ReadResult {
+		return &changestreams.ReadResult{
+			PartitionToken: token,
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{HeartbeatRecords: []*changestreams.HeartbeatRecord{{Timestamp: mustParseTime(t, ts)}}},
+			},
+		}
+	}
+	for _, result := range []*changestreams.ReadResult{
+		heartbeat("a", "2022-12-04T19:59:58Z"),
+		heartbeat("b", "2022-12-04T19:59:59Z"),
+		heartbeat("a", "2022-12-04T20:00:00Z"),
+	} {
+		if err := sink.Write(result); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+
+	// Not shown until the interval passes without records.
+	now = now.Add(30 * time.Minute)
+	sink.report()
+	now = now.Add(90 * time.Minute)
+	sink.report()
+	expected := "\r\033[KNo changes for 2h0m0s | Last heartbeat: 2022-12-04T20:00:00Z | Partitions: 2 | Lag: 1s"
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+
+	// Cleared by the records.
+	out.Reset()
+	if err := sink.Write(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T20:00:01Z", "PlayerId"))); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	sink.report()
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if diff := cmp.Diff(out.String(), "\r\033[K"); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+	if diff := cmp.Diff(recorder.timestamps, []string{"20:00"}); diff != "" {
+		t.Errorf("written records have diff = %v", diff)
+	}
+}
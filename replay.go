@@ -0,0 +1,117 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+const commandReplay = "replay"
+
+// recordSource is the source of the records, i.e. the change streams or a capture file.
+type recordSource interface {
+	Read(ctx context.Context, f func(result *changestreams.ReadResult) error) error
+	ReadToSink(ctx context.Context, sink changestreams.Sink) error
+	Close()
+}
+
+// replayReader reads the ReadResults captured in a file as JSON lines, e.g. by the record command or the verbose output,
+// to pass them through the same pipeline as the change streams. The file can be compressed with gzip.
+type replayReader struct {
+	path string
+	// If streamIDs is not empty, only the results of the streams are read.
+	streamIDs []string
+	redactor  changestreams.Redactor
+}
+
+func newReplayReader(path string, streamIDs []string, redactor changestreams.Redactor) *replayReader {
+	return &replayReader{path: path, streamIDs: streamIDs, redactor: redactor}
+}
+
+// Read reads the results in the file in order. If f returns changestreams.ErrStop, Read returns nil.
+func (r *replayReader) Read(ctx context.Context, f func(result *changestreams.ReadResult) error) error {
+	file, err := openCapture(r.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	dec := json.NewDecoder(file)
+	for line := 1; ; line++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var result changestreams.ReadResult
+		if err := dec.Decode(&result); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to decode result %d: %w", line, err)
+		}
+		if !r.matchStream(result.StreamID) {
+			continue
+		}
+		if r.redactor != nil {
+			for _, changeRecord := range result.ChangeRecords {
+				for _, dataChangeRecord := range changeRecord.DataChangeRecords {
+					r.redactor.Redact(dataChangeRecord)
+				}
+			}
+		}
+		if err := f(&result); errors.Is(err, changestreams.ErrStop) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func (r *replayReader) matchStream(streamID string) bool {
+	if len(r.streamIDs) == 0 {
+		return true
+	}
+	for _, id := range r.streamIDs {
+		if id == streamID {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadToSink is the same as changestreams.Reader.ReadToSink, for the capture file.
+func (r *replayReader) ReadToSink(ctx context.Context, sink changestreams.Sink) error {
+	if err := sink.Open(ctx); err != nil {
+		return fmt.Errorf("failed to open sink: %w", err)
+	}
+
+	err := r.Read(ctx, sink.Write)
+	if flushErr := sink.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to flush sink: %w", flushErr)
+	}
+	if closeErr := sink.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close sink: %w", closeErr)
+	}
+	return err
+}
+
+// Close does nothing, as the file is closed by Read.
+func (r *replayReader) Close() {}
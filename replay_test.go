@@ -0,0 +1,93 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReplayReader(t *testing.T) {
+	newResults := func() []*changestreams.ReadResult {
+		results := []*changestreams.ReadResult{
+			newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId", "Name")),
+			newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId", "Name")),
+			newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:02Z", "PlayerId", "Name")),
+		}
+		results[0].StreamID = "a"
+		results[1].StreamID = "b"
+		results[2].StreamID = "a"
+		return results
+	}
+
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	logger := &Logger{out: file, verbose: true}
+	for _, result := range newResults() {
+		if err := logger.Read(result); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("failed to close file: %v", err)
+	}
+
+	redactor, err := changestreams.NewColumnRedactor([]string{"Players.Name"}, changestreams.RedactModePlaceholder)
+	if err != nil {
+		t.Fatalf("NewColumnRedactor error: %v", err)
+	}
+	var expected []*changestreams.ReadResult
+	for _, result := range newResults() {
+		if result.StreamID != "a" {
+			continue
+		}
+		redactor.Redact(result.ChangeRecords[0].DataChangeRecords[0])
+		expected = append(expected, result)
+	}
+
+	var got []*changestreams.ReadResult
+	reader := newReplayReader(path, []string{"a"}, redactor)
+	if err := reader.Read(context.Background(), func(result *changestreams.ReadResult) error {
+		got = append(got, result)
+		return nil
+	}); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("results have diff = %v", diff)
+	}
+
+	got = nil
+	reader = newReplayReader(path, nil, nil)
+	if err := reader.Read(context.Background(), func(result *changestreams.ReadResult) error {
+		got = append(got, result)
+		return changestreams.ErrStop
+	}); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("Read must stop after the first result, got %d results", len(got))
+	}
+}
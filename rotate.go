@@ -0,0 +1,164 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatingWriter is an io.WriteCloser that writes to a sequence of destinations.
+// A new destination is opened when the current one exceeds maxSize bytes or has been open for maxAge.
+// Zero maxSize or maxAge disables the respective limit.
+//
+// Rotation only happens between Write calls, so callers that write whole lines per call never get split lines.
+type rotatingWriter struct {
+	open     func(t time.Time) (io.WriteCloser, error)
+	maxSize  int64
+	maxAge   time.Duration
+	current  io.WriteCloser
+	size     int64
+	openedAt time.Time
+	closed   bool
+	err      error
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+// newRotatingWriter creates a new rotatingWriter. open is called with the current time whenever a new destination is needed.
+func newRotatingWriter(open func(t time.Time) (io.WriteCloser, error), maxSize int64, maxAge time.Duration) *rotatingWriter {
+	w := &rotatingWriter{
+		open:    open,
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		done:    make(chan struct{}),
+	}
+	if maxAge > 0 {
+		// Rotate idle destinations as well, so that they are finalized even if no more records arrive.
+		go w.rotateByAge()
+	}
+	return w
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if w.err != nil {
+		// Report the error from the background rotation.
+		err := w.err
+		w.err = nil
+		return 0, err
+	}
+	if w.current != nil && w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize && w.size > 0 {
+		if err := w.closeCurrent(); err != nil {
+			return 0, err
+		}
+	}
+	if w.current == nil {
+		now := time.Now()
+		current, err := w.open(now)
+		if err != nil {
+			return 0, err
+		}
+		w.current = current
+		w.size = 0
+		w.openedAt = now
+	}
+
+	n, err := w.current.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current destination. Further writes fail.
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	close(w.done)
+	if err := w.closeCurrent(); err != nil {
+		return err
+	}
+	return w.err
+}
+
+func (w *rotatingWriter) rotateByAge() {
+	ticker := time.NewTicker(w.maxAge / 10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.current != nil && time.Since(w.openedAt) >= w.maxAge {
+				if err := w.closeCurrent(); err != nil {
+					w.err = err
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+func (w *rotatingWriter) closeCurrent() error {
+	if w.current == nil {
+		return nil
+	}
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
+
+// parseByteSize parses a size such as "1048576", "512KB", "100MB" or "1GB". Units are powers of 1024.
+func parseByteSize(s string) (int64, error) {
+	multiplier := int64(1)
+	upper := strings.ToUpper(strings.TrimSpace(s))
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	} {
+		if strings.HasSuffix(upper, unit.suffix) {
+			multiplier = unit.multiplier
+			upper = strings.TrimSuffix(upper, unit.suffix)
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size: %q", s)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,128 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestRotatingWriterMaxSize(t *testing.T) {
+	var destinations []*bufferCloser
+	w := newRotatingWriter(func(time.Time) (io.WriteCloser, error) {
+		b := &bufferCloser{}
+		destinations = append(destinations, b)
+		return b, nil
+	}, 10, 0)
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "dddddddddddd\n", "e\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	var got []string
+	for _, d := range destinations {
+		if !d.closed {
+			t.Errorf("destination %q is not closed", d.String())
+		}
+		got = append(got, d.String())
+	}
+	expected := []string{"aaaa\nbbbb\n", "cccc\n", "dddddddddddd\n", "e\n"}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("rotatingWriter has diff = %v", diff)
+	}
+}
+
+func TestRotatingWriterMaxAge(t *testing.T) {
+	opened := make(chan *bufferCloser, 10)
+	w := newRotatingWriter(func(time.Time) (io.WriteCloser, error) {
+		b := &bufferCloser{}
+		opened <- b
+		return b, nil
+	}, 0, 50*time.Millisecond)
+	defer w.Close()
+
+	if _, err := w.Write([]byte("a\n")); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	first := <-opened
+
+	time.Sleep(100 * time.Millisecond)
+	w.mu.Lock()
+	closed := first.closed
+	w.mu.Unlock()
+	if !closed {
+		t.Errorf("destination must be closed after max age")
+	}
+
+	if _, err := w.Write([]byte("b\n")); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if second := <-opened; second.String() != "b\n" {
+		t.Errorf("second destination = %q, want %q", second.String(), "b\n")
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected int64
+		wantErr  bool
+	}{
+		{input: "1024", expected: 1024},
+		{input: "10B", expected: 10},
+		{input: "512KB", expected: 512 << 10},
+		{input: "100mb", expected: 100 << 20},
+		{input: "1GB", expected: 1 << 30},
+		{input: "MB", wantErr: true},
+		{input: "-1", wantErr: true},
+		{input: "1TB", wantErr: true},
+	} {
+		got, err := parseByteSize(test.input)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseByteSize(%q) should return an error", test.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseByteSize(%q) error: %v", test.input, err)
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("parseByteSize(%q) = %d, want %d", test.input, got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// SchemaRecord is a synthetic record describing the columns of a table.
+// It is emitted before the first data change record of the table, and again whenever the column types change.
+type SchemaRecord struct {
+	TableName   string                      `json:"table_name"`
+	ColumnTypes []*changestreams.ColumnType `json:"column_types"`
+}
+
+// schemaTracker remembers the last seen column types per table. It is not goroutine safe.
+type schemaTracker struct {
+	columnTypes map[string][]byte
+}
+
+func newSchemaTracker() *schemaTracker {
+	return &schemaTracker{
+		columnTypes: make(map[string][]byte),
+	}
+}
+
+// update records the column types of the data change record, and returns a SchemaRecord
+// if they differ from the ones last seen for the table. Otherwise it returns nil.
+func (t *schemaTracker) update(record *changestreams.DataChangeRecord) (*SchemaRecord, error) {
+	b, err := json.Marshal(record.ColumnTypes)
+	if err != nil {
+		return nil, err
+	}
+	if last, ok := t.columnTypes[record.TableName]; ok && bytes.Equal(last, b) {
+		return nil, nil
+	}
+	t.columnTypes[record.TableName] = b
+	return &SchemaRecord{
+		TableName:   record.TableName,
+		ColumnTypes: record.ColumnTypes,
+	}, nil
+}
@@ -0,0 +1,125 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// schemaChangeSink prints notices when the schema of a table changes while tailing, e.g. by ALTER TABLE, so that the
+// consumers decoding the values by the types notice the migration.
+//
+// The column types of a data change record can be a subset of the columns, e.g. only the changed columns of an UPDATE,
+// so a column not seen before is added only if its ordinal position is after the columns seen before. The dropped
+// columns are not noticed, as they are indistinguishable from the columns not in the records.
+type schemaChangeSink struct {
+	changestreams.Sink
+	logger *slog.Logger
+
+	// tables are the columns seen by table.
+	tables map[string]*tableColumns
+	mu     sync.Mutex
+}
+
+type tableColumns struct {
+	columns     map[string]*changestreams.ColumnType
+	maxPosition int64
+}
+
+func newSchemaChangeSink(sink changestreams.Sink, logger *slog.Logger) *schemaChangeSink {
+	return &schemaChangeSink{
+		Sink:   sink,
+		logger: logger,
+		tables: make(map[string]*tableColumns),
+	}
+}
+
+func (s *schemaChangeSink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			if err := s.update(result.StreamID, r); err != nil {
+				s.mu.Unlock()
+				return err
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	return s.Sink.Write(result)
+}
+
+// update records the column types of the record, and prints a notice if the schema of the table changed.
+func (s *schemaChangeSink) update(streamID string, r *changestreams.DataChangeRecord) error {
+	table, ok := s.tables[r.TableName]
+	if !ok {
+		table = &tableColumns{columns: make(map[string]*changestreams.ColumnType)}
+		s.tables[r.TableName] = table
+	}
+
+	var added, changed []string
+	maxPosition := table.maxPosition
+	for _, columnType := range r.ColumnTypes {
+		last, seen := table.columns[columnType.Name]
+		if seen {
+			lastType, err := columnTypeString(last)
+			if err != nil {
+				return err
+			}
+			newType, err := columnTypeString(columnType)
+			if err != nil {
+				return err
+			}
+			if lastType != newType {
+				changed = append(changed, fmt.Sprintf("%s: %s -> %s", columnType.Name, lastType, newType))
+			}
+		} else if ok && columnType.OrdinalPosition > table.maxPosition {
+			added = append(added, columnType.Name)
+		}
+		table.columns[columnType.Name] = columnType
+		if columnType.OrdinalPosition > maxPosition {
+			maxPosition = columnType.OrdinalPosition
+		}
+	}
+	table.maxPosition = maxPosition
+
+	if len(added) == 0 && len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	s.logger.Warn("Schema of a table changed", "table", r.TableName, "stream", streamID, "commit_timestamp", r.CommitTimestamp,
+		"added_columns", added, "changed_columns", changed)
+	return nil
+}
+
+// columnTypeString returns the type of the column in JSON, with the primary key marked.
+func columnTypeString(columnType *changestreams.ColumnType) (string, error) {
+	b, err := columnType.Type.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	if columnType.IsPrimaryKey {
+		return string(b) + " PRIMARY KEY", nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,68 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"cloud.google.com/go/spanner"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSchemaChangeSink(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	recorder := &recordingSink{}
+	sink := newSchemaChangeSink(recorder, logger)
+
+	write := func(ts string, columns ...string) {
+		r := newTestDataChangeRecord(t, ts, columns...)
+		result := newTestReadResult(r)
+		result.StreamID = "s"
+		if err := sink.Write(result); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+
+	// The subsets of the columns seen before are not changes.
+	write("2022-12-04T18:00:00Z", "PlayerId", "Name")
+	write("2022-12-04T18:00:01Z", "PlayerId")
+	write("2022-12-04T18:00:02Z", "PlayerId", "Name")
+	// Score is added after Name.
+	write("2022-12-04T18:00:03Z", "PlayerId", "Name", "Score")
+
+	// The type of Name changes.
+	r := newTestDataChangeRecord(t, "2022-12-04T18:00:04Z", "PlayerId", "Name")
+	r.ColumnTypes[1].Type = spanner.NullJSON{Value: map[string]interface{}{"code": "BYTES"}, Valid: true}
+	result := newTestReadResult(r)
+	result.StreamID = "s"
+	if err := sink.Write(result); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	expected := `level=WARN msg="Schema of a table changed" table=Players stream=s commit_timestamp=2022-12-04T18:00:03.000Z added_columns=[Score] changed_columns=[]
+level=WARN msg="Schema of a table changed" table=Players stream=s commit_timestamp=2022-12-04T18:00:04.000Z added_columns=[] changed_columns="[Name: {\"code\":\"STRING\"} -> {\"code\":\"BYTES\"}]"
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+	if got := len(recorder.timestamps); got != 5 {
+		t.Errorf("written records = %d, want 5", got)
+	}
+}
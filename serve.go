@@ -0,0 +1,103 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/tailpb"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
+)
+
+const commandServe = "serve"
+
+// serveConfig is the configuration for the serve command.
+// Empty addresses disable the respective servers.
+type serveConfig struct {
+	grpcAddr string
+	httpAddr string
+}
+
+// serve reads the stream once and serves the data change records to the clients until the reader finishes.
+func serve(ctx context.Context, reader recordSource, config serveConfig) error {
+	if config.grpcAddr == "" && config.httpAddr == "" {
+		return fmt.Errorf("either gRPC or HTTP address must be specified")
+	}
+
+	broadcaster := NewBroadcaster()
+	group, ctx := errgroup.WithContext(ctx)
+	var stops []func()
+
+	// Both listeners are opened before serving either, not to leave one serving when the other fails.
+	var grpcLis, httpLis net.Listener
+	if config.grpcAddr != "" {
+		lis, err := net.Listen("tcp", config.grpcAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		grpcLis = lis
+	}
+	if config.httpAddr != "" {
+		lis, err := net.Listen("tcp", config.httpAddr)
+		if err != nil {
+			if grpcLis != nil {
+				grpcLis.Close()
+			}
+			return fmt.Errorf("failed to listen: %w", err)
+		}
+		httpLis = lis
+	}
+
+	if grpcLis != nil {
+		server := grpc.NewServer()
+		tailpb.RegisterTailServer(server, &tailServer{broadcaster: broadcaster})
+		group.Go(func() error {
+			return server.Serve(grpcLis)
+		})
+		stops = append(stops, server.GracefulStop)
+		logger.Info("Serving the stream over gRPC", "addr", grpcLis.Addr().String())
+	}
+
+	if httpLis != nil {
+		server := &http.Server{Handler: newHTTPHandler(broadcaster)}
+		group.Go(func() error {
+			if err := server.Serve(httpLis); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		})
+		stops = append(stops, func() {
+			server.Shutdown(context.Background())
+		})
+		logger.Info("Serving the stream over HTTP", "addr", httpLis.Addr().String())
+	}
+
+	group.Go(func() error {
+		err := reader.Read(ctx, broadcaster.Read)
+		// Finish the client streams, so that the servers can stop gracefully.
+		broadcaster.Close()
+		for _, stop := range stops {
+			stop()
+		}
+		return err
+	})
+	return group.Wait()
+}
@@ -0,0 +1,47 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestServeListenError(t *testing.T) {
+	used, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer used.Close()
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	grpcAddr := free.Addr().String()
+	free.Close()
+
+	if err := serve(context.Background(), nil, serveConfig{grpcAddr: grpcAddr, httpAddr: used.Addr().String()}); err == nil {
+		t.Fatalf("serve must fail to listen on the used address")
+	}
+	// The gRPC listener must be closed when the HTTP listener fails.
+	lis, err := net.Listen("tcp", grpcAddr)
+	if err != nil {
+		t.Fatalf("gRPC address must be released, but got %v", err)
+	}
+	lis.Close()
+}
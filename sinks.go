@@ -0,0 +1,504 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// Names of the built-in sinks.
+const (
+	sinkStdout        = "stdout"
+	sinkBigQuery      = "bigquery"
+	sinkGCS           = "gcs"
+	sinkFile          = "file"
+	sinkWebhook       = "webhook"
+	sinkElasticsearch = "elasticsearch"
+	sinkSQLite        = "sqlite"
+	sinkUnixSocket    = "unix-socket"
+	sinkExec          = "exec"
+	sinkAMQP          = "amqp"
+	sinkStats         = "stats"
+	sinkOutput        = "output"
+	sinkRecord        = "record"
+)
+
+// Parameters passed by the CLI to every sink, in addition to the --sink-param options.
+const (
+	sinkParamProject     = "project"
+	sinkParamFormat      = "format"
+	sinkParamVerbose     = "verbose"
+	sinkParamEmitSchema  = "emit_schema"
+	sinkParamStreamTag   = "stream_tag"
+	sinkParamAnnotateLag = "annotate_lag"
+)
+
+func init() {
+	changestreams.RegisterSink(sinkStdout, newStdoutSink)
+	changestreams.RegisterSink(sinkBigQuery, newBigQuerySink)
+	changestreams.RegisterSink(sinkGCS, newGCSSink)
+	changestreams.RegisterSink(sinkFile, newFileSink)
+	changestreams.RegisterSink(sinkWebhook, newWebhookSink)
+	changestreams.RegisterSink(sinkElasticsearch, newElasticsearchSink)
+	changestreams.RegisterSink(sinkSQLite, newSQLiteSink)
+	changestreams.RegisterSink(sinkUnixSocket, newUnixSocketSink)
+	changestreams.RegisterSink(sinkExec, newExecSink)
+	changestreams.RegisterSink(sinkAMQP, newAMQPSink)
+	changestreams.RegisterSink(sinkStats, newStatsSink)
+	changestreams.RegisterSink(sinkOutput, newOutputSink)
+	changestreams.RegisterSink(sinkRecord, newRecordSink)
+}
+
+// output is an opened output of a built-in sink. Nil functions are no-ops.
+type output struct {
+	write func(result *changestreams.ReadResult) error
+	flush func() error
+	close func() error
+	// If abort is set, it's called instead of close when reading fails.
+	abort func() error
+}
+
+// outputSink adapts an output opened on Open to changestreams.Sink.
+type outputSink struct {
+	open func(ctx context.Context) (*output, error)
+	out  *output
+}
+
+func (s *outputSink) Open(ctx context.Context) error {
+	out, err := s.open(ctx)
+	if err != nil {
+		return err
+	}
+	s.out = out
+	return nil
+}
+
+func (s *outputSink) Write(result *changestreams.ReadResult) error {
+	return s.out.write(result)
+}
+
+func (s *outputSink) Flush() error {
+	if s.out.flush == nil {
+		return nil
+	}
+	return s.out.flush()
+}
+
+func (s *outputSink) Close() error {
+	if s.out.close == nil {
+		return nil
+	}
+	return s.out.close()
+}
+
+// Abort discards the output, or closes it if it can't be discarded.
+func (s *outputSink) Abort() error {
+	if s.out.abort == nil {
+		return s.Close()
+	}
+	return s.out.abort()
+}
+
+// deferredCloseSink defers closing the sink until reading finishes, so that the output is discarded by finish if
+// reading fails, e.g. not to rename a partial atomic output file over the destination.
+type deferredCloseSink struct {
+	changestreams.Sink
+}
+
+func (s *deferredCloseSink) Close() error {
+	return nil
+}
+
+// finish closes the sink, or aborts it if reading failed and it can be aborted.
+func (s *deferredCloseSink) finish(failed bool) error {
+	if a, ok := s.Sink.(interface{ Abort() error }); ok && failed {
+		return a.Abort()
+	}
+	return s.Sink.Close()
+}
+
+// loggerOutput returns an output formatting the records into w.
+func loggerOutput(params changestreams.SinkParams, w io.WriteCloser) (*output, error) {
+	newLogger, err := newLoggerFunc(params)
+	if err != nil {
+		return nil, err
+	}
+	return &output{write: newLogger(w).Read, close: w.Close}, nil
+}
+
+// newLoggerFunc returns a function creating a Logger with the format parameters.
+func newLoggerFunc(params changestreams.SinkParams) (func(out io.Writer) *Logger, error) {
+	format := params.Get(sinkParamFormat)
+	if format == "" {
+		format = formatText
+	}
+	verbose, err := boolParam(params, sinkParamVerbose)
+	if err != nil {
+		return nil, err
+	}
+	emitSchema, err := boolParam(params, sinkParamEmitSchema)
+	if err != nil {
+		return nil, err
+	}
+	streamTag, err := boolParam(params, sinkParamStreamTag)
+	if err != nil {
+		return nil, err
+	}
+	annotateLag, err := boolParam(params, sinkParamAnnotateLag)
+	if err != nil {
+		return nil, err
+	}
+	return func(out io.Writer) *Logger {
+		logger := &Logger{
+			out:         out,
+			format:      format,
+			verbose:     verbose,
+			streamTag:   streamTag,
+			annotateLag: annotateLag,
+		}
+		if emitSchema {
+			logger.schemas = newSchemaTracker()
+		}
+		return logger
+	}, nil
+}
+
+func newStdoutSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	newLogger, err := newLoggerFunc(params)
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		return &output{write: newLogger(os.Stdout).Read}, nil
+	}}, nil
+}
+
+func newOutputSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	path, err := requiredParam(params, "path")
+	if err != nil {
+		return nil, err
+	}
+	atomic, err := boolParam(params, "atomic")
+	if err != nil {
+		return nil, err
+	}
+	fsync, err := boolParam(params, "fsync")
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		f, err := NewOutputFile(path, atomic, fsync)
+		if err != nil {
+			return nil, err
+		}
+		out, err := loggerOutput(params, f)
+		if err != nil {
+			return nil, err
+		}
+		out.abort = f.Abort
+		return out, nil
+	}}, nil
+}
+
+func newRecordSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	path, err := requiredParam(params, "path")
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		w, err := NewCaptureWriter(path)
+		if err != nil {
+			return nil, err
+		}
+		return &output{write: w.Read, flush: w.Flush, close: w.Close}, nil
+	}}, nil
+}
+
+func newBigQuerySink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	table, err := requiredParam(params, "table")
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		// The sink must not be cancelled by the interrupt, otherwise the last rows are not inserted.
+		sink, err := NewBigQuerySink(context.Background(), params.Get(sinkParamProject), table)
+		if err != nil {
+			return nil, err
+		}
+		return &output{write: sink.Read, close: sink.Close}, nil
+	}}, nil
+}
+
+func newGCSSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	path, err := requiredParam(params, "path")
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := byteSizeParam(params, "max_size")
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := durationParam(params, "max_age", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	verbose, err := boolParam(params, sinkParamVerbose)
+	if err != nil {
+		return nil, err
+	}
+	ext := formatExtension(params.Get(sinkParamFormat), verbose)
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		// The writer must not be cancelled by the interrupt, otherwise the last object is not finalized.
+		w, err := NewGCSWriter(context.Background(), path, ext, maxSize, maxAge)
+		if err != nil {
+			return nil, err
+		}
+		return loggerOutput(params, w)
+	}}, nil
+}
+
+func newFileSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	path, err := requiredParam(params, "path")
+	if err != nil {
+		return nil, err
+	}
+	maxSize, err := byteSizeParam(params, "max_size")
+	if err != nil {
+		return nil, err
+	}
+	maxAge, err := durationParam(params, "max_age", 0)
+	if err != nil {
+		return nil, err
+	}
+	compress, err := boolParam(params, "gzip")
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		w, err := NewFileWriter(path, maxSize, maxAge, compress)
+		if err != nil {
+			return nil, err
+		}
+		return loggerOutput(params, w)
+	}}, nil
+}
+
+func newWebhookSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	url, err := requiredParam(params, "url")
+	if err != nil {
+		return nil, err
+	}
+	headers, err := parseHeaders(params["header"])
+	if err != nil {
+		return nil, err
+	}
+	batchSize, err := intParam(params, "batch_size", 1)
+	if err != nil {
+		return nil, err
+	}
+	flushInterval, err := durationParam(params, "flush_interval", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxRetries, err := intParam(params, "max_retries", 5)
+	if err != nil {
+		return nil, err
+	}
+	config := WebhookConfig{
+		URL:           url,
+		Headers:       headers,
+		BatchSize:     batchSize,
+		FlushInterval: flushInterval,
+		MaxRetries:    maxRetries,
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		// The sink must not be cancelled by the interrupt, otherwise the last batch is not sent.
+		sink := NewWebhookSink(context.Background(), config)
+		return &output{write: sink.Read, flush: sink.Flush, close: sink.Close}, nil
+	}}, nil
+}
+
+func newElasticsearchSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	url, err := requiredParam(params, "url")
+	if err != nil {
+		return nil, err
+	}
+	indexPrefix := params.Get("index_prefix")
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		sink := NewElasticsearchSink(context.Background(), url, indexPrefix)
+		return &output{write: sink.Read}, nil
+	}}, nil
+}
+
+func newSQLiteSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	path, err := requiredParam(params, "path")
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		// The sink must not be cancelled by the interrupt, otherwise the last transaction is rolled back.
+		sink, err := NewSQLiteSink(context.Background(), path)
+		if err != nil {
+			return nil, err
+		}
+		return &output{write: sink.Read, close: sink.Close}, nil
+	}}, nil
+}
+
+func newUnixSocketSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	path, err := requiredParam(params, "path")
+	if err != nil {
+		return nil, err
+	}
+	newLogger, err := newLoggerFunc(params)
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		sink, err := NewUnixSocketSink(path, newLogger)
+		if err != nil {
+			return nil, err
+		}
+		return &output{write: sink.Read, close: sink.Close}, nil
+	}}, nil
+}
+
+func newExecSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	command, err := requiredParam(params, "command")
+	if err != nil {
+		return nil, err
+	}
+	initialBackoff, err := durationParam(params, "initial_backoff", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	maxBackoff, err := durationParam(params, "max_backoff", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		w, err := NewExecWriter(command, initialBackoff, maxBackoff)
+		if err != nil {
+			return nil, err
+		}
+		return loggerOutput(params, w)
+	}}, nil
+}
+
+func newAMQPSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	url, err := requiredParam(params, "url")
+	if err != nil {
+		return nil, err
+	}
+	exchange := params.Get("exchange")
+	routingKey := params.Get("routing_key")
+	if routingKey == "" {
+		routingKey = defaultAMQPRoutingKey
+	}
+	return &outputSink{open: func(ctx context.Context) (*output, error) {
+		sink, err := NewAMQPSink(url, exchange, routingKey)
+		if err != nil {
+			return nil, err
+		}
+		return &output{write: sink.Read, close: sink.Close}, nil
+	}}, nil
+}
+
+func newStatsSink(params changestreams.SinkParams) (changestreams.Sink, error) {
+	interval, err := durationParam(params, "interval", defaultStatsInterval)
+	if err != nil {
+		return nil, err
+	}
+	return NewStatsSink(os.Stdout, interval), nil
+}
+
+// parseSinkParams parses the parameters in the form of key=value.
+func parseSinkParams(params []string) (changestreams.SinkParams, error) {
+	parsed := changestreams.SinkParams{}
+	for _, p := range params {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid sink parameter: %q", p)
+		}
+		parsed.Add(kv[0], kv[1])
+	}
+	return parsed, nil
+}
+
+func requiredParam(params changestreams.SinkParams, key string) (string, error) {
+	v := params.Get(key)
+	if v == "" {
+		return "", fmt.Errorf("sink parameter %q is required", key)
+	}
+	return v, nil
+}
+
+func boolParam(params changestreams.SinkParams, key string) (bool, error) {
+	v := params.Get(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid sink parameter %q: %w", key, err)
+	}
+	return b, nil
+}
+
+func intParam(params changestreams.SinkParams, key string, defaultValue int) (int, error) {
+	v := params.Get(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sink parameter %q: %w", key, err)
+	}
+	return i, nil
+}
+
+func durationParam(params changestreams.SinkParams, key string, defaultValue time.Duration) (time.Duration, error) {
+	v := params.Get(key)
+	if v == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sink parameter %q: %w", key, err)
+	}
+	return d, nil
+}
+
+func byteSizeParam(params changestreams.SinkParams, key string) (int64, error) {
+	v := params.Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	size, err := parseByteSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid sink parameter %q: %w", key, err)
+	}
+	return size, nil
+}
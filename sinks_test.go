@@ -0,0 +1,91 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSinkParams(t *testing.T) {
+	got, err := parseSinkParams([]string{"path=/tmp/a", "header=X-A: 1", "header=X-B: 2=3"})
+	if err != nil {
+		t.Fatalf("parseSinkParams error: %v", err)
+	}
+	expected := changestreams.SinkParams{
+		"path":   {"/tmp/a"},
+		"header": {"X-A: 1", "X-B: 2=3"},
+	}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("parseSinkParams has diff = %v", diff)
+	}
+
+	for _, p := range []string{"path", "=a"} {
+		if _, err := parseSinkParams([]string{p}); err == nil {
+			t.Errorf("parseSinkParams(%q) must fail", p)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	params := changestreams.SinkParams{}
+	params.Set("path", filepath.Join(dir, "changes.jsonl"))
+	params.Set(sinkParamFormat, formatJSON)
+
+	sink, err := changestreams.NewSink(sinkFile, params)
+	if err != nil {
+		t.Fatalf("NewSink error: %v", err)
+	}
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	if err := sink.Write(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "changes-*.jsonl"))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("expected one rotated file, got %v (err = %v)", matches, err)
+	}
+	b, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if len(b) == 0 {
+		t.Errorf("rotated file must not be empty")
+	}
+}
+
+func TestNewSinkMissingParam(t *testing.T) {
+	for _, name := range []string{sinkBigQuery, sinkGCS, sinkFile, sinkWebhook, sinkElasticsearch, sinkSQLite, sinkUnixSocket, sinkExec, sinkAMQP, sinkOutput, sinkRecord} {
+		if _, err := changestreams.NewSink(name, nil); err == nil {
+			t.Errorf("NewSink(%q) must fail without the required parameter", name)
+		}
+	}
+}
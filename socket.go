@@ -0,0 +1,103 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// UnixSocketSink listens on a Unix domain socket, and writes the data change records to every connected client
+// in the same format as the standard output. Records read while no client is connected are discarded.
+type UnixSocketSink struct {
+	*Broadcaster
+	listener  net.Listener
+	newLogger func(out io.Writer) *Logger
+	wg        sync.WaitGroup
+}
+
+// NewUnixSocketSink creates a new UnixSocketSink. A stale socket file at the path is removed.
+// newLogger is called for each client to format the records.
+func NewUnixSocketSink(path string, newLogger func(out io.Writer) *Logger) (*UnixSocketSink, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &UnixSocketSink{
+		Broadcaster: NewBroadcaster(),
+		listener:    listener,
+		newLogger:   newLogger,
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Close disconnects the clients and removes the socket file.
+func (s *UnixSocketSink) Close() error {
+	err := s.listener.Close()
+	s.Broadcaster.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *UnixSocketSink) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			// The listener is closed.
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *UnixSocketSink) serve(conn net.Conn) {
+	defer conn.Close()
+
+	sub := s.Subscribe(newRecordFilter(nil, nil))
+	defer s.Unsubscribe(sub)
+
+	logger := s.newLogger(conn)
+	for r := range sub.records {
+		result := &changestreams.ReadResult{
+			StreamID:       r.streamID,
+			PartitionToken: r.partitionToken,
+			ChangeRecords: []*changestreams.ChangeRecord{
+				{DataChangeRecords: []*changestreams.DataChangeRecord{r.record}},
+			},
+		}
+		if err := logger.Read(result); err != nil {
+			// The client is disconnected.
+			return
+		}
+	}
+}
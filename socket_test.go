@@ -0,0 +1,108 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnixSocketSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tail.sock")
+	// Stale socket file left by a previous run.
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	sink, err := NewUnixSocketSink(path, func(out io.Writer) *Logger {
+		return &Logger{out: out, format: formatText}
+	})
+	if err != nil {
+		t.Fatalf("NewUnixSocketSink error: %v", err)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	waitForSubscribers(t, sink.Broadcaster, 1)
+
+	if err := sink.Read(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected := `2022-12-04 18:00:00 +0000 UTC | INSERT | Players | [{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}]
+`
+	if diff := cmp.Diff(string(got), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("socket file must be removed on close")
+	}
+}
+
+func TestUnixSocketSinkStreams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tail.sock")
+	sink, err := NewUnixSocketSink(path, func(out io.Writer) *Logger {
+		return &Logger{out: out, format: formatText, streamTag: true}
+	})
+	if err != nil {
+		t.Fatalf("NewUnixSocketSink error: %v", err)
+	}
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	waitForSubscribers(t, sink.Broadcaster, 1)
+
+	for _, streamID := range []string{"stream1", "stream2"} {
+		result := newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))
+		result.StreamID = streamID
+		if err := sink.Read(result); err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	expected := `2022-12-04 18:00:00 +0000 UTC | stream1 | INSERT | Players | [{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}]
+2022-12-04 18:00:00 +0000 UTC | stream2 | INSERT | Players | [{"keys":{"PlayerId":"1"},"new_values":{"Name":"foo"},"old_values":{}}]
+`
+	if diff := cmp.Diff(string(got), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+}
@@ -0,0 +1,189 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// recordSpool buffers the data change records to iterate them in the commit order. If maxMemory is positive, the
+// records are spilled to a temporary file sorted by the commit timestamp whenever their estimated size exceeds it, and
+// the files are merged on iteration, so that a burst of large transactions doesn't run out of memory.
+type recordSpool struct {
+	// dir is the directory of the temporary files, or the default directory for temporary files if empty.
+	dir       string
+	maxMemory int64
+	records   []*changestreams.DataChangeRecord
+	size      int64
+	runs      []*os.File
+}
+
+func (s *recordSpool) add(r *changestreams.DataChangeRecord) error {
+	s.records = append(s.records, r)
+	s.size += recordSize(r)
+	if s.maxMemory > 0 && s.size > s.maxMemory {
+		return s.spill()
+	}
+	return nil
+}
+
+// spill writes the records in memory to a new temporary file in the commit order.
+func (s *recordSpool) spill() error {
+	f, err := os.CreateTemp(s.dir, "spanner-change-streams-tail-*.jsonl")
+	if err != nil {
+		return err
+	}
+	// Removed now not to leave the file behind on crash, as it's accessed only through the open file.
+	os.Remove(f.Name())
+	s.runs = append(s.runs, f)
+
+	sortRecords(s.records)
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, r := range s.records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	s.records = nil
+	s.size = 0
+	return nil
+}
+
+// each calls f with the records in the commit order. The records committed at the same time are in the order added.
+func (s *recordSpool) each(f func(r *changestreams.DataChangeRecord) error) error {
+	sortRecords(s.records)
+
+	// The heads of the spilled files in the order spilled, followed by the records in memory, which are the latest.
+	type run struct {
+		head *changestreams.DataChangeRecord
+		next func() (*changestreams.DataChangeRecord, error)
+	}
+	var runs []*run
+	for _, file := range s.runs {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		dec := json.NewDecoder(bufio.NewReader(file))
+		runs = append(runs, &run{next: func() (*changestreams.DataChangeRecord, error) {
+			var r changestreams.DataChangeRecord
+			if err := dec.Decode(&r); err != nil {
+				if errors.Is(err, io.EOF) {
+					return nil, nil
+				}
+				return nil, err
+			}
+			return &r, nil
+		}})
+	}
+	records := s.records
+	runs = append(runs, &run{next: func() (*changestreams.DataChangeRecord, error) {
+		if len(records) == 0 {
+			return nil, nil
+		}
+		r := records[0]
+		records = records[1:]
+		return r, nil
+	}})
+	for _, r := range runs {
+		var err error
+		if r.head, err = r.next(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		var min *run
+		for _, r := range runs {
+			if r.head != nil && (min == nil || r.head.CommitTimestamp.Before(min.head.CommitTimestamp)) {
+				min = r
+			}
+		}
+		if min == nil {
+			return nil
+		}
+		if err := f(min.head); err != nil {
+			return err
+		}
+		var err error
+		if min.head, err = min.next(); err != nil {
+			return err
+		}
+	}
+}
+
+// close removes the temporary files.
+func (s *recordSpool) close() error {
+	var errs []error
+	for _, f := range s.runs {
+		errs = append(errs, f.Close())
+	}
+	s.runs = nil
+	s.records = nil
+	return errors.Join(errs...)
+}
+
+func sortRecords(records []*changestreams.DataChangeRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CommitTimestamp.Before(records[j].CommitTimestamp)
+	})
+}
+
+// recordSize estimates the memory used by the data change record.
+func recordSize(r *changestreams.DataChangeRecord) int64 {
+	size := int64(256 + len(r.RecordSequence) + len(r.ServerTransactionID) + len(r.TableName) + len(r.ModType) +
+		len(r.ValueCaptureType) + len(r.TransactionTag))
+	for _, c := range r.ColumnTypes {
+		size += 64 + int64(len(c.Name)) + jsonValueSize(c.Type.Value)
+	}
+	for _, mod := range r.Mods {
+		size += 64 + jsonValueSize(mod.Keys.Value) + jsonValueSize(mod.NewValues.Value) + jsonValueSize(mod.OldValues.Value)
+	}
+	return size
+}
+
+// jsonValueSize estimates the memory used by the value decoded from JSON.
+func jsonValueSize(v interface{}) int64 {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		size := int64(48)
+		for k, e := range v {
+			size += 16 + int64(len(k)) + jsonValueSize(e)
+		}
+		return size
+	case []interface{}:
+		size := int64(24)
+		for _, e := range v {
+			size += jsonValueSize(e)
+		}
+		return size
+	case string:
+		return 32 + int64(len(v))
+	default:
+		return 16
+	}
+}
@@ -0,0 +1,77 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordSpool(t *testing.T) {
+	dir := t.TempDir()
+	// Spills every two records.
+	spool := &recordSpool{dir: dir, maxMemory: 2*recordSize(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z")) - 1}
+	defer spool.close()
+
+	var records []*changestreams.DataChangeRecord
+	for i, ts := range []string{
+		"2022-12-04T18:00:03Z",
+		"2022-12-04T18:00:01Z",
+		"2022-12-04T18:00:02Z",
+		"2022-12-04T18:00:00Z",
+		"2022-12-04T18:00:01Z",
+	} {
+		r := newTestDataChangeRecord(t, ts)
+		r.RecordSequence = string(rune('a' + i))
+		records = append(records, r)
+		if err := spool.add(r); err != nil {
+			t.Fatalf("add error: %v", err)
+		}
+	}
+	if len(spool.runs) != 2 {
+		t.Errorf("records must be spilled to 2 files, but got %d", len(spool.runs))
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("spilled files must be removed from the directory, but got %d", len(entries))
+	}
+
+	// The records at the same time are in the order added.
+	var got []string
+	err = spool.each(func(r *changestreams.DataChangeRecord) error {
+		got = append(got, r.CommitTimestamp.Format("15:04:05")+" "+r.RecordSequence)
+		if r.RecordSequence == "b" {
+			if diff := cmp.Diff(r, records[1]); diff != "" {
+				t.Errorf("spilled record has diff = %v", diff)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("each error: %v", err)
+	}
+	expected := []string{"18:00:00 d", "18:00:01 b", "18:00:01 e", "18:00:02 c", "18:00:03 a"}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("records have diff = %v", diff)
+	}
+}
@@ -0,0 +1,161 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+
+	// Pure Go SQLite driver, so that the tool can still be built without cgo.
+	_ "modernc.org/sqlite"
+)
+
+// sqliteTimestampFormat has a fixed width, so that timestamps are ordered correctly as text.
+const sqliteTimestampFormat = "2006-01-02T15:04:05.000000000Z"
+
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS changes (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  commit_timestamp TEXT NOT NULL,
+  partition_token TEXT NOT NULL,
+  record_sequence TEXT NOT NULL,
+  server_transaction_id TEXT NOT NULL,
+  table_name TEXT NOT NULL,
+  mod_type TEXT NOT NULL,
+  value_capture_type TEXT NOT NULL,
+  transaction_tag TEXT NOT NULL,
+  is_system_transaction INTEGER NOT NULL,
+  mod_index INTEGER NOT NULL,
+  keys TEXT,
+  new_values TEXT,
+  old_values TEXT
+)`,
+	`CREATE INDEX IF NOT EXISTS changes_commit_timestamp ON changes (commit_timestamp)`,
+	`CREATE INDEX IF NOT EXISTS changes_table_name ON changes (table_name, commit_timestamp)`,
+	`CREATE INDEX IF NOT EXISTS changes_mod_type ON changes (mod_type, commit_timestamp)`,
+}
+
+// SQLiteSink appends the mods into the "changes" table of a local SQLite database.
+// Keys, new values and old values are stored as JSON text, which can be queried with the SQLite JSON functions.
+type SQLiteSink struct {
+	ctx context.Context
+	db  *sql.DB
+	mu  sync.Mutex
+}
+
+// NewSQLiteSink opens the database, creating it and the table if they don't exist.
+func NewSQLiteSink(ctx context.Context, path string) (*SQLiteSink, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite doesn't support concurrent writes.
+	db.SetMaxOpenConns(1)
+
+	// WAL allows the database to be queried while the tool is writing to it.
+	if _, err := db.ExecContext(ctx, "PRAGMA journal_mode=WAL"); err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, stmt := range sqliteSchema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &SQLiteSink{
+		ctx: ctx,
+		db:  db,
+	}, nil
+}
+
+func (s *SQLiteSink) Read(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(s.ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(s.ctx, `INSERT INTO changes (
+  commit_timestamp, partition_token, record_sequence, server_transaction_id, table_name, mod_type,
+  value_capture_type, transaction_tag, is_system_transaction, mod_index, keys, new_values, old_values
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			for i, mod := range r.Mods {
+				keys, err := sqliteJSON(mod.Keys)
+				if err != nil {
+					return err
+				}
+				newValues, err := sqliteJSON(mod.NewValues)
+				if err != nil {
+					return err
+				}
+				oldValues, err := sqliteJSON(mod.OldValues)
+				if err != nil {
+					return err
+				}
+				if _, err := stmt.ExecContext(s.ctx,
+					r.CommitTimestamp.UTC().Format(sqliteTimestampFormat),
+					result.PartitionToken,
+					r.RecordSequence,
+					r.ServerTransactionID,
+					r.TableName,
+					r.ModType,
+					r.ValueCaptureType,
+					r.TransactionTag,
+					r.IsSystemTransaction,
+					i,
+					keys,
+					newValues,
+					oldValues,
+				); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// Close closes the database.
+func (s *SQLiteSink) Close() error {
+	return s.db.Close()
+}
+
+func sqliteJSON(v spanner.NullJSON) (sql.NullString, error) {
+	if !v.Valid {
+		return sql.NullString{}, nil
+	}
+	b, err := v.MarshalJSON()
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
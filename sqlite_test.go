@@ -0,0 +1,69 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSQLiteSink(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "changes.db")
+
+	sink, err := NewSQLiteSink(ctx, path)
+	if err != nil {
+		t.Fatalf("NewSQLiteSink error: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Read(newTestReadResult(
+		newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId"),
+		newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"),
+	)); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+
+	rows, err := sink.db.QueryContext(ctx, "SELECT commit_timestamp, table_name, mod_type, json_extract(new_values, '$.Name') FROM changes ORDER BY commit_timestamp")
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	defer rows.Close()
+
+	var got [][]string
+	for rows.Next() {
+		var commitTimestamp, tableName, modType, name string
+		if err := rows.Scan(&commitTimestamp, &tableName, &modType, &name); err != nil {
+			t.Fatalf("failed to scan: %v", err)
+		}
+		got = append(got, []string{commitTimestamp, tableName, modType, name})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+
+	expected := [][]string{
+		{"2022-12-04T18:00:00.000000000Z", "Players", "INSERT", "foo"},
+		{"2022-12-04T18:00:01.000000000Z", "Players", "INSERT", "foo"},
+	}
+	if diff := cmp.Diff(got, expected); diff != "" {
+		t.Errorf("rows have diff = %v", diff)
+	}
+}
@@ -0,0 +1,212 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// defaultStatsInterval is the default interval of the periodic stats.
+const defaultStatsInterval = 10 * time.Second
+
+// tableStats is the statistics of the data change records of a table.
+type tableStats struct {
+	Records int64 `json:"records"`
+	// Bytes is the size of the mods in JSON.
+	Bytes               int64     `json:"bytes"`
+	LastCommitTimestamp time.Time `json:"last_commit_timestamp"`
+}
+
+func (s *tableStats) add(r *changestreams.DataChangeRecord) {
+	s.Records++
+	s.Bytes += modsSize(r)
+	if r.CommitTimestamp.After(s.LastCommitTimestamp) {
+		s.LastCommitTimestamp = r.CommitTimestamp
+	}
+}
+
+// modsSize returns the size of the mods of the record in JSON, as the volume of the changes.
+func modsSize(r *changestreams.DataChangeRecord) int64 {
+	b, err := json.Marshal(r.Mods)
+	if err != nil {
+		return 0
+	}
+	return int64(len(b))
+}
+
+// StatsSink prints the aggregates of the data change records periodically and on close, instead of the records.
+//
+// The lag is the time between the commit timestamp of a record and when it is written to the sink.
+type StatsSink struct {
+	out      io.Writer
+	interval time.Duration
+	now      func() time.Time
+
+	startedAt       time.Time
+	reportedAt      time.Time
+	records         int64
+	reportedRecords int64
+	mods            int64
+	// counts is the number of records by table name and mod type.
+	counts     map[string]map[string]int64
+	tables     map[string]*tableStats
+	partitions map[string]struct{}
+	// topology tracks the splits and the merges of the partitions.
+	topology *PartitionVisualizer
+	lag      time.Duration
+	maxLag   time.Duration
+	done     chan struct{}
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+}
+
+// NewStatsSink creates a new StatsSink. If interval is zero, the stats are printed only on close.
+func NewStatsSink(out io.Writer, interval time.Duration) *StatsSink {
+	return &StatsSink{
+		out:        out,
+		interval:   interval,
+		now:        time.Now,
+		counts:     make(map[string]map[string]int64),
+		tables:     make(map[string]*tableStats),
+		partitions: make(map[string]struct{}),
+		topology:   NewPartitionVisualizer(io.Discard),
+		done:       make(chan struct{}),
+	}
+}
+
+func (s *StatsSink) Open(ctx context.Context) error {
+	s.startedAt = s.now()
+	s.reportedAt = s.startedAt
+	if s.interval > 0 {
+		s.wg.Add(1)
+		go s.reportPeriodically()
+	}
+	return nil
+}
+
+func (s *StatsSink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.partitions[result.PartitionToken] = struct{}{}
+	if err := s.topology.Read(result); err != nil {
+		return err
+	}
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			s.records++
+			s.mods += int64(len(r.Mods))
+			if s.counts[r.TableName] == nil {
+				s.counts[r.TableName] = make(map[string]int64)
+			}
+			s.counts[r.TableName][r.ModType]++
+			if s.tables[r.TableName] == nil {
+				s.tables[r.TableName] = &tableStats{}
+			}
+			s.tables[r.TableName].add(r)
+
+			s.lag = now.Sub(r.CommitTimestamp)
+			if s.lag > s.maxLag {
+				s.maxLag = s.lag
+			}
+		}
+	}
+	return nil
+}
+
+func (s *StatsSink) Flush() error {
+	return nil
+}
+
+// Close prints the final stats, whose rate is the average since the start.
+func (s *StatsSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reportedAt, s.reportedRecords = s.startedAt, 0
+	return s.report()
+}
+
+func (s *StatsSink) reportPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			// Errors are ignored as there is nowhere to report them but the output itself.
+			_ = s.report()
+			s.mu.Unlock()
+		}
+	}
+}
+
+// report prints the stats, with the rate since the last report.
+func (s *StatsSink) report() error {
+	now := s.now()
+	var rate float64
+	if elapsed := now.Sub(s.reportedAt).Seconds(); elapsed > 0 {
+		rate = float64(s.records-s.reportedRecords) / elapsed
+	}
+	s.reportedAt, s.reportedRecords = now, s.records
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Elapsed: %v | Records: %d (%.1f/s) | Mods: %d | Partitions: %d | Lag: %v (max: %v)\n",
+		now.Sub(s.startedAt).Round(time.Second), s.records, rate, s.mods, len(s.partitions),
+		s.lag.Round(time.Millisecond), s.maxLag.Round(time.Millisecond))
+	if summary := s.topology.Summary(); summary.Partitions > 0 {
+		fmt.Fprintf(&b, "  Splits: %d | Merges: %d | Avg partition lifetime: %v | Max concurrent partitions: %d\n",
+			summary.Splits, summary.Merges, summary.AvgLifetime.Round(time.Second), summary.MaxConcurrent)
+	}
+	tables := make([]string, 0, len(s.counts))
+	for table := range s.counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Fprintf(&b, "  %s", table)
+		modTypes := make([]string, 0, len(s.counts[table]))
+		for modType := range s.counts[table] {
+			modTypes = append(modTypes, modType)
+		}
+		sort.Strings(modTypes)
+		for _, modType := range modTypes {
+			fmt.Fprintf(&b, " | %s: %d", modType, s.counts[table][modType])
+		}
+		fmt.Fprintf(&b, " | Bytes: %d | Last commit: %s", s.tables[table].Bytes,
+			s.tables[table].LastCommitTimestamp.Format(time.RFC3339Nano))
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(s.out, b.String())
+	return err
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStatsSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewStatsSink(&out, 0)
+	now := mustParseTime(t, "2022-12-04T18:00:00Z")
+	sink.now = func() time.Time { return now }
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	update := newTestDataChangeRecord(t, "2022-12-04T18:00:08Z", "PlayerId")
+	update.ModType = "UPDATE"
+	result := newTestReadResult(
+		newTestDataChangeRecord(t, "2022-12-04T18:00:05Z", "PlayerId"),
+		newTestDataChangeRecord(t, "2022-12-04T18:00:06Z", "PlayerId"),
+		update,
+	)
+	if err := sink.Write(result); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	other := newTestDataChangeRecord(t, "2022-12-04T18:00:09Z", "PlayerId")
+	other.TableName = "Albums"
+	result = newTestReadResult(other)
+	result.PartitionToken = "b"
+	if err := sink.Write(result); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+
+	now = now.Add(10 * time.Second)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	expected := `Elapsed: 20s | Records: 4 (0.2/s) | Mods: 4 | Partitions: 2 | Lag: 1s (max: 5s)
+  Albums | INSERT: 1 | Bytes: 71 | Last commit: 2022-12-04T18:00:09Z
+  Players | INSERT: 2 | UPDATE: 1 | Bytes: 213 | Last commit: 2022-12-04T18:00:08Z
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+}
+
+func TestStatsSinkPartitionSummary(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewStatsSink(&out, 0)
+	now := mustParseTime(t, "2022-12-04T20:00:05Z")
+	sink.now = func() time.Time { return now }
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	for _, result := range newTestPartitionResults(t) {
+		if err := sink.Write(result); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	expected := `Elapsed: 0s | Records: 2 (0.0/s) | Mods: 2 | Partitions: 5 | Lag: 3s (max: 4s)
+  Splits: 1 | Merges: 1 | Avg partition lifetime: 1h0m0s | Max concurrent partitions: 2
+  Players | INSERT: 2 | Bytes: 142 | Last commit: 2022-12-04T20:00:02Z
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+}
@@ -0,0 +1,129 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/spanner"
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"golang.org/x/sync/errgroup"
+)
+
+// streamReaders reads multiple change streams concurrently.
+type streamReaders []*changestreams.Reader
+
+// newStreamReaders creates the readers of the streams sharing the client.
+// The streams in startTimestamps start from the timestamps instead of config.StartTimestamp, e.g. to resume them.
+func newStreamReaders(ctx context.Context, client *spanner.Client, streamIDs []string, config changestreams.Config, startTimestamps map[string]time.Time) (streamReaders, error) {
+	var readers streamReaders
+	for _, streamID := range streamIDs {
+		streamConfig := config
+		if ts, ok := startTimestamps[streamID]; ok {
+			streamConfig.StartTimestamp = ts
+		}
+		reader, err := changestreams.NewReaderWithClient(ctx, client, streamID, streamConfig)
+		if err != nil {
+			readers.Close()
+			return nil, fmt.Errorf("stream %s: %w", streamID, err)
+		}
+		readers = append(readers, reader)
+	}
+	return readers, nil
+}
+
+// Read reads the streams concurrently. If f returns changestreams.ErrStop, all the streams stop and Read returns nil.
+func (rs streamReaders) Read(ctx context.Context, f func(result *changestreams.ReadResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var stopped int32
+	group, ctx := errgroup.WithContext(ctx)
+	for _, r := range rs {
+		r := r
+		group.Go(func() error {
+			return r.Read(ctx, func(result *changestreams.ReadResult) error {
+				err := f(result)
+				if errors.Is(err, changestreams.ErrStop) {
+					// The other streams are stopped by the cancellation.
+					atomic.StoreInt32(&stopped, 1)
+					cancel()
+				}
+				return err
+			})
+		})
+	}
+
+	err := group.Wait()
+	if atomic.LoadInt32(&stopped) == 1 {
+		return nil
+	}
+	return err
+}
+
+// ReadToSink is the same as changestreams.Reader.ReadToSink, for the streams.
+func (rs streamReaders) ReadToSink(ctx context.Context, sink changestreams.Sink) error {
+	if err := sink.Open(ctx); err != nil {
+		return fmt.Errorf("failed to open sink: %w", err)
+	}
+
+	err := rs.Read(ctx, sink.Write)
+	if flushErr := sink.Flush(); err == nil && flushErr != nil {
+		err = fmt.Errorf("failed to flush sink: %w", flushErr)
+	}
+	if closeErr := sink.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close sink: %w", closeErr)
+	}
+	return err
+}
+
+// logUnreadPartitions warns of the child partitions announced but not read by the readers, as the records of the
+// streams may be incomplete.
+func (rs streamReaders) logUnreadPartitions(logger *slog.Logger) {
+	for _, r := range rs {
+		for _, p := range r.UnreadPartitions() {
+			logger.Warn("Partition was announced but not read, the records may be incomplete", "stream", p.StreamID,
+				"partition_token", p.Token, "start_timestamp", p.StartTimestamp, "unfinished_parents", p.UnfinishedParents)
+		}
+	}
+}
+
+// Close closes the readers.
+func (rs streamReaders) Close() {
+	for _, r := range rs {
+		r.Close()
+	}
+}
+
+// parseStreamIDs parses the stream IDs given by the repeatable and comma separated option.
+func parseStreamIDs(values []string) []string {
+	var streamIDs []string
+	for _, v := range values {
+		for _, streamID := range strings.Split(v, ",") {
+			if streamID = strings.TrimSpace(streamID); streamID != "" {
+				streamIDs = append(streamIDs, streamID)
+			}
+		}
+	}
+	return streamIDs
+}
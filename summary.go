@@ -0,0 +1,142 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// summarySink logs a summary of the throughput periodically, so that operators can watch it without an external
+// metrics stack. The summary has the rate of the data change records over the last interval, the totals by mod type
+// and the active partitions.
+type summarySink struct {
+	changestreams.Sink
+	logger   *slog.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	reportedAt      time.Time
+	records         int64
+	reportedRecords int64
+	// modTypes is the number of the records by mod type.
+	modTypes map[string]int64
+	// partitions are the active partitions.
+	partitions map[string]bool
+	done       chan struct{}
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+}
+
+func newSummarySink(sink changestreams.Sink, logger *slog.Logger, interval time.Duration) *summarySink {
+	return &summarySink{
+		Sink:       sink,
+		logger:     logger,
+		interval:   interval,
+		now:        time.Now,
+		modTypes:   make(map[string]int64),
+		partitions: make(map[string]bool),
+		done:       make(chan struct{}),
+	}
+}
+
+func (s *summarySink) Open(ctx context.Context) error {
+	if err := s.Sink.Open(ctx); err != nil {
+		return err
+	}
+	s.reportedAt = s.now()
+	s.wg.Add(1)
+	go s.reportPeriodically()
+	return nil
+}
+
+func (s *summarySink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			s.records++
+			s.modTypes[r.ModType]++
+		}
+		if result.PartitionToken != "" {
+			// The initial query is not a partition.
+			s.partitions[result.PartitionToken] = true
+		}
+		if len(changeRecord.ChildPartitionsRecords) > 0 {
+			// The partition finishes after returning the child partitions.
+			delete(s.partitions, result.PartitionToken)
+		}
+	}
+	s.mu.Unlock()
+
+	return s.Sink.Write(result)
+}
+
+func (s *summarySink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.Sink.Close()
+}
+
+func (s *summarySink) reportPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.report()
+		}
+	}
+}
+
+// report logs the summary, with the rate since the last report.
+func (s *summarySink) report() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var rate float64
+	if elapsed := now.Sub(s.reportedAt).Seconds(); elapsed > 0 {
+		rate = float64(s.records-s.reportedRecords) / elapsed
+	}
+	s.reportedAt, s.reportedRecords = now, s.records
+
+	modTypes := make([]string, 0, len(s.modTypes))
+	for modType := range s.modTypes {
+		modTypes = append(modTypes, modType)
+	}
+	sort.Strings(modTypes)
+	counts := make([]interface{}, 0, len(modTypes))
+	for _, modType := range modTypes {
+		counts = append(counts, slog.Int64(modType, s.modTypes[modType]))
+	}
+
+	s.logger.Info("Throughput",
+		"records_per_sec", math.Round(rate*10)/10,
+		"records", s.records,
+		slog.Group("mod_types", counts...),
+		"partitions", len(s.partitions))
+}
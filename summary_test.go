@@ -0,0 +1,81 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarySink(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	now := mustParseTime(t, "2022-12-04T18:00:00Z")
+	recorder := &recordingSink{}
+	sink := newSummarySink(recorder, logger, time.Hour)
+	sink.now = func() time.Time { return now }
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+
+	data := func(token, modType string) *changestreams.ReadResult {
+		r := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+		r.ModType = modType
+		result := newTestReadResult(r)
+		result.PartitionToken = token
+		return result
+	}
+	write := func(results ...*changestreams.ReadResult) {
+		for _, result := range results {
+			if err := sink.Write(result); err != nil {
+				t.Fatalf("Write error: %v", err)
+			}
+		}
+	}
+
+	write(data("a", "INSERT"), data("a", "UPDATE"), data("b", "INSERT"), data("c", "DELETE"))
+	now = now.Add(2 * time.Second)
+	sink.report()
+
+	write(data("b", "INSERT"), &changestreams.ReadResult{
+		PartitionToken: "a",
+		ChangeRecords: []*changestreams.ChangeRecord{
+			{ChildPartitionsRecords: []*changestreams.ChildPartitionsRecord{{}}},
+		},
+	})
+	now = now.Add(10 * time.Second)
+	sink.report()
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	expected := `level=INFO msg=Throughput records_per_sec=2 records=4 mod_types.DELETE=1 mod_types.INSERT=2 mod_types.UPDATE=1 partitions=3
+level=INFO msg=Throughput records_per_sec=0.1 records=5 mod_types.DELETE=1 mod_types.INSERT=3 mod_types.UPDATE=1 partitions=2
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+	if got := len(recorder.timestamps); got != 5 {
+		t.Errorf("written records = %d, want 5", got)
+	}
+}
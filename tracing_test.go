@@ -0,0 +1,47 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	var out bytes.Buffer
+	tp, err := newTracerProvider(context.Background(), traceExporterStdout, &out)
+	if err != nil {
+		t.Fatalf("newTracerProvider error: %v", err)
+	}
+	_, span := tp.Tracer("test").Start(context.Background(), "changestreams.PartitionQuery")
+	span.End()
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown error: %v", err)
+	}
+
+	for _, s := range []string{`"Name":"changestreams.PartitionQuery"`, serviceName} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("exported spans don't contain %q: %s", s, out.String())
+		}
+	}
+
+	if _, err := newTracerProvider(context.Background(), "zipkin", &out); err == nil {
+		t.Errorf("newTracerProvider must fail for an invalid exporter")
+	}
+}
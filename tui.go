@@ -0,0 +1,420 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+)
+
+const (
+	// tuiMaxRecords is the maximum number of records kept for scrolling.
+	tuiMaxRecords = 10000
+	// tuiRefreshInterval is the interval to redraw the screen for the new records.
+	tuiRefreshInterval = 200 * time.Millisecond
+	// tuiPanelWidth is the width of the side panel of the tables and partitions.
+	tuiPanelWidth = 40
+)
+
+type tuiRecord struct {
+	seq  int64
+	line string
+}
+
+// TUISink shows the records in an interactive terminal UI with the live-updating record list, the counters
+// of each table and the partitions. The list can be paused, scrolled and filtered.
+type TUISink struct {
+	newScreen func() (tcell.Screen, error)
+	// quit is called when the user quits, to stop reading the streams.
+	quit   func()
+	screen tcell.Screen
+
+	records []tuiRecord
+	seq     int64
+	// counts is the number of records by table name and mod type.
+	counts map[string]map[string]int64
+	// partitions is the last timestamp of the active partitions.
+	partitions map[string]time.Time
+
+	// pausedSeq is the last record shown while paused, or zero if not paused.
+	pausedSeq int64
+	// scroll is the number of the lines scrolled up from the bottom.
+	scroll int
+	filter string
+	// prompt is the filter being edited, or nil if not editing.
+	prompt  *string
+	quitted bool
+	dirty   bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+}
+
+// NewTUISink creates a new TUISink. quit is called when the user quits.
+func NewTUISink(quit func()) *TUISink {
+	return newTUISink(tcell.NewScreen, quit)
+}
+
+func newTUISink(newScreen func() (tcell.Screen, error), quit func()) *TUISink {
+	return &TUISink{
+		newScreen:  newScreen,
+		quit:       quit,
+		counts:     make(map[string]map[string]int64),
+		partitions: make(map[string]time.Time),
+		done:       make(chan struct{}),
+	}
+}
+
+func (s *TUISink) Open(ctx context.Context) error {
+	screen, err := s.newScreen()
+	if err != nil {
+		return err
+	}
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	s.screen = screen
+
+	s.mu.Lock()
+	s.draw()
+	s.mu.Unlock()
+
+	s.wg.Add(2)
+	go s.handleEvents()
+	go s.refreshPeriodically()
+	return nil
+}
+
+func (s *TUISink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.quitted {
+		return changestreams.ErrStop
+	}
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			modsJSON, err := json.Marshal(r.Mods)
+			if err != nil {
+				return err
+			}
+			s.seq++
+			s.records = append(s.records, tuiRecord{
+				seq:  s.seq,
+				line: fmt.Sprintf("%s | %s | %s | %s", r.CommitTimestamp.Format("2006-01-02 15:04:05.000"), r.ModType, r.TableName, modsJSON),
+			})
+			if s.counts[r.TableName] == nil {
+				s.counts[r.TableName] = make(map[string]int64)
+			}
+			s.counts[r.TableName][r.ModType]++
+			s.touchPartition(result.PartitionToken, r.CommitTimestamp)
+		}
+		for _, r := range changeRecord.HeartbeatRecords {
+			s.touchPartition(result.PartitionToken, r.Timestamp)
+		}
+		if len(changeRecord.ChildPartitionsRecords) > 0 {
+			// The partition finishes after returning the child partitions.
+			delete(s.partitions, result.PartitionToken)
+		}
+	}
+	if len(s.records) > tuiMaxRecords {
+		s.records = s.records[len(s.records)-tuiMaxRecords:]
+	}
+	s.dirty = true
+	return nil
+}
+
+func (s *TUISink) touchPartition(token string, ts time.Time) {
+	if token == "" {
+		// The initial query is not a partition.
+		return
+	}
+	if ts.After(s.partitions[token]) {
+		s.partitions[token] = ts
+	}
+}
+
+func (s *TUISink) Flush() error {
+	return nil
+}
+
+// Close restores the terminal.
+func (s *TUISink) Close() error {
+	close(s.done)
+	s.screen.Fini()
+	s.wg.Wait()
+	return nil
+}
+
+func (s *TUISink) refreshPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(tuiRefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.dirty {
+				s.draw()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *TUISink) handleEvents() {
+	defer s.wg.Done()
+	for {
+		// PollEvent returns nil after the screen is finalized.
+		ev := s.screen.PollEvent()
+		if ev == nil {
+			return
+		}
+		s.mu.Lock()
+		switch ev := ev.(type) {
+		case *tcell.EventKey:
+			s.handleKey(ev)
+		case *tcell.EventResize:
+			s.screen.Sync()
+		}
+		s.draw()
+		s.mu.Unlock()
+	}
+}
+
+func (s *TUISink) handleKey(ev *tcell.EventKey) {
+	if s.prompt != nil {
+		switch ev.Key() {
+		case tcell.KeyEnter:
+			s.filter = *s.prompt
+			s.prompt = nil
+			s.scroll = 0
+		case tcell.KeyEscape:
+			s.prompt = nil
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			if r := []rune(*s.prompt); len(r) > 0 {
+				*s.prompt = string(r[:len(r)-1])
+			}
+		case tcell.KeyRune:
+			*s.prompt += string(ev.Rune())
+		}
+		return
+	}
+
+	_, height := s.screen.Size()
+	page := height - 2
+	switch ev.Key() {
+	case tcell.KeyCtrlC:
+		s.doQuit()
+	case tcell.KeyEscape:
+		s.filter = ""
+		s.scroll = 0
+	case tcell.KeyUp:
+		s.scroll++
+	case tcell.KeyDown:
+		s.scroll--
+	case tcell.KeyPgUp:
+		s.scroll += page
+	case tcell.KeyPgDn:
+		s.scroll -= page
+	case tcell.KeyHome:
+		s.scroll = len(s.records)
+	case tcell.KeyEnd:
+		s.scroll = 0
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			s.doQuit()
+		case 'p', ' ':
+			if s.pausedSeq == 0 {
+				s.pausedSeq = s.seq
+			} else {
+				s.pausedSeq = 0
+			}
+		case 'k':
+			s.scroll++
+		case 'j':
+			s.scroll--
+		case 'g':
+			s.scroll = len(s.records)
+		case 'G':
+			s.scroll = 0
+		case '/':
+			prompt := s.filter
+			s.prompt = &prompt
+		}
+	}
+	if s.scroll < 0 {
+		s.scroll = 0
+	}
+}
+
+func (s *TUISink) doQuit() {
+	if !s.quitted {
+		s.quitted = true
+		s.quit()
+	}
+}
+
+// visibleRecords returns the records matching the filter, excluding the ones read after pausing.
+func (s *TUISink) visibleRecords() []string {
+	filter := strings.ToLower(s.filter)
+	var lines []string
+	for _, r := range s.records {
+		if s.pausedSeq != 0 && r.seq > s.pausedSeq {
+			break
+		}
+		if filter == "" || strings.Contains(strings.ToLower(r.line), filter) {
+			lines = append(lines, r.line)
+		}
+	}
+	return lines
+}
+
+func (s *TUISink) draw() {
+	s.dirty = false
+	s.screen.Clear()
+	width, height := s.screen.Size()
+	if height < 3 {
+		s.screen.Show()
+		return
+	}
+
+	// Header.
+	header := fmt.Sprintf(" Records: %d | Tables: %d | Partitions: %d", s.seq, len(s.counts), len(s.partitions))
+	if s.pausedSeq != 0 {
+		header += " | PAUSED"
+	}
+	if s.filter != "" {
+		header += " | Filter: " + s.filter
+	}
+	reverse := tcell.StyleDefault.Reverse(true)
+	s.fillLine(0, width, reverse)
+	s.drawText(0, 0, width, reverse, header)
+
+	// Records.
+	listWidth := width
+	if width >= tuiPanelWidth*2 {
+		listWidth = width - tuiPanelWidth
+	}
+	lines := s.visibleRecords()
+	rows := height - 2
+	if maxScroll := len(lines) - rows; s.scroll > maxScroll {
+		s.scroll = maxScroll
+		if s.scroll < 0 {
+			s.scroll = 0
+		}
+	}
+	end := len(lines) - s.scroll
+	start := end - rows
+	if start < 0 {
+		start = 0
+	}
+	for i, line := range lines[start:end] {
+		s.drawText(0, 1+i, listWidth-1, tcell.StyleDefault, line)
+	}
+
+	// Side panel.
+	if listWidth < width {
+		s.drawPanel(listWidth, width-listWidth, rows)
+	}
+
+	// Footer.
+	footer := " q: quit  p: pause  ↑/↓ PgUp/PgDn: scroll  /: filter  Esc: clear filter"
+	if s.prompt != nil {
+		footer = "/" + *s.prompt
+	}
+	s.fillLine(height-1, width, reverse)
+	s.drawText(0, height-1, width, reverse, footer)
+	s.screen.Show()
+}
+
+func (s *TUISink) drawPanel(x, width, rows int) {
+	var lines []string
+	bold := tcell.StyleDefault.Bold(true)
+	styles := make(map[int]tcell.Style)
+
+	styles[len(lines)] = bold
+	lines = append(lines, "Tables")
+	var tables []string
+	for table := range s.counts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		c := s.counts[table]
+		lines = append(lines, fmt.Sprintf("%s  I:%d U:%d D:%d", table, c["INSERT"], c["UPDATE"], c["DELETE"]))
+	}
+
+	lines = append(lines, "")
+	styles[len(lines)] = bold
+	lines = append(lines, fmt.Sprintf("Partitions (%d)", len(s.partitions)))
+	var tokens []string
+	for token := range s.partitions {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	for _, token := range tokens {
+		if len(token) > 16 {
+			token = token[:16] + "…"
+		}
+		lines = append(lines, fmt.Sprintf("%-17s %s", token, s.partitions[token].Format("15:04:05")))
+	}
+
+	for i, line := range lines {
+		if i >= rows {
+			break
+		}
+		s.screen.SetContent(x, 1+i, '│', nil, tcell.StyleDefault)
+		s.drawText(x+2, 1+i, width-2, styles[i], line)
+	}
+	for i := len(lines); i < rows; i++ {
+		s.screen.SetContent(x, 1+i, '│', nil, tcell.StyleDefault)
+	}
+}
+
+func (s *TUISink) fillLine(y, width int, style tcell.Style) {
+	for x := 0; x < width; x++ {
+		s.screen.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+// drawText draws the text at the position, truncating it to the width.
+func (s *TUISink) drawText(x, y, width int, style tcell.Style, text string) {
+	end := x + width
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if x+w > end {
+			return
+		}
+		s.screen.SetContent(x, y, r, nil, style)
+		x += w
+	}
+}
@@ -0,0 +1,125 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/gdamore/tcell/v2"
+)
+
+// screenLines returns the text of the simulation screen.
+func screenLines(screen tcell.SimulationScreen) []string {
+	cells, width, height := screen.GetContents()
+	lines := make([]string, height)
+	for y := 0; y < height; y++ {
+		var b strings.Builder
+		for x := 0; x < width; x++ {
+			b.Write(cells[y*width+x].Bytes)
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+	return lines
+}
+
+func TestTUISink(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	var quitted bool
+	sink := newTUISink(func() (tcell.Screen, error) {
+		return screen, nil
+	}, func() {
+		quitted = true
+	})
+	if err := sink.Open(context.Background()); err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	defer sink.Close()
+	screen.SetSize(120, 10)
+
+	insert := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+	update := newTestDataChangeRecord(t, "2022-12-04T18:00:01Z", "PlayerId")
+	update.TableName = "Teams"
+	update.ModType = "UPDATE"
+	for _, r := range []*changestreams.DataChangeRecord{insert, update} {
+		if err := sink.Write(newTestReadResult(r)); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+
+	draw := func() []string {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		sink.draw()
+		return screenLines(screen)
+	}
+	key := func(k tcell.Key, r rune) {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		sink.handleKey(tcell.NewEventKey(k, r, tcell.ModNone))
+	}
+
+	lines := draw()
+	expected := []string{
+		" Records: 2 | Tables: 2 | Partitions: 1",
+		`2022-12-04 18:00:00.000 | INSERT | Players | [{"keys":{"PlayerId":"1"}`,
+		`2022-12-04 18:00:01.000 | UPDATE | Teams | [{"keys":{"PlayerId":"1"}`,
+	}
+	for i, e := range expected {
+		if !strings.HasPrefix(lines[i], e) {
+			t.Errorf("line %d = %q, want prefix %q", i, lines[i], e)
+		}
+	}
+	if panel := strings.Join(lines, "\n"); !strings.Contains(panel, "Players  I:1 U:0 D:0") || !strings.Contains(panel, "Teams  I:0 U:1 D:0") {
+		t.Errorf("panel must show the counters, got:\n%s", panel)
+	}
+
+	// Filter by the prompt.
+	key(tcell.KeyRune, '/')
+	for _, r := range "teams" {
+		key(tcell.KeyRune, r)
+	}
+	if lines := draw(); lines[9] != "/teams" {
+		t.Errorf("footer = %q, want the prompt", lines[9])
+	}
+	key(tcell.KeyEnter, 0)
+	lines = draw()
+	if !strings.HasSuffix(lines[0], "Filter: teams") || !strings.Contains(lines[1], "| UPDATE | Teams |") || strings.Contains(lines[2], "INSERT") {
+		t.Errorf("records must be filtered, got:\n%s", strings.Join(lines, "\n"))
+	}
+	key(tcell.KeyEscape, 0)
+
+	// Records read while paused are not shown.
+	key(tcell.KeyRune, 'p')
+	if err := sink.Write(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:02Z", "PlayerId"))); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	lines = draw()
+	if !strings.Contains(lines[0], "Records: 3") || !strings.Contains(lines[0], "PAUSED") || strings.Contains(lines[3], "18:00:02") {
+		t.Errorf("records must be paused, got:\n%s", strings.Join(lines, "\n"))
+	}
+
+	key(tcell.KeyRune, 'q')
+	if !quitted {
+		t.Errorf("quit must be called")
+	}
+	if err := sink.Write(newTestReadResult(insert)); err != changestreams.ErrStop {
+		t.Errorf("Write after quit must return ErrStop, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// verifySink verifies the record sequences of the data change records, so that users can validate their consumption
+// doesn't lose or reorder any records. The record sequences must increase within a partition of a transaction, and
+// once every partition of the transaction sent its last record, they must be from 0 to the number of the records in
+// the transaction minus 1 without gaps. The violations are logged as warnings, and the totals on close.
+type verifySink struct {
+	changestreams.Sink
+	logger *slog.Logger
+
+	transactions map[transactionKey]*transactionSequences
+	verified     int64
+	gaps         int64
+	outOfOrder   int64
+	mu           sync.Mutex
+}
+
+// transactionKey identifies a transaction in a stream, as a transaction can be read from multiple streams.
+type transactionKey struct {
+	streamID            string
+	serverTransactionID string
+}
+
+// transactionSequences are the record sequences of a transaction read so far.
+type transactionSequences struct {
+	records    int64
+	partitions int64
+	// finished is the number of the partitions which sent the last record of the transaction.
+	finished  int64
+	sequences map[int64]bool
+	// last is the last record sequence by partition.
+	last map[string]int64
+}
+
+func newVerifySink(sink changestreams.Sink, logger *slog.Logger) *verifySink {
+	return &verifySink{
+		Sink:         sink,
+		logger:       logger,
+		transactions: make(map[transactionKey]*transactionSequences),
+	}
+}
+
+func (s *verifySink) Write(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			s.verify(result.StreamID, result.PartitionToken, r)
+		}
+	}
+	s.mu.Unlock()
+
+	return s.Sink.Write(result)
+}
+
+func (s *verifySink) verify(streamID, partitionToken string, r *changestreams.DataChangeRecord) {
+	sequence, err := strconv.ParseInt(r.RecordSequence, 10, 64)
+	if err != nil {
+		s.logger.Warn("Record sequence is invalid", "stream", streamID, "partition_token", partitionToken,
+			"server_transaction_id", r.ServerTransactionID, "record_sequence", r.RecordSequence)
+		return
+	}
+
+	key := transactionKey{streamID: streamID, serverTransactionID: r.ServerTransactionID}
+	tx, ok := s.transactions[key]
+	if !ok {
+		tx = &transactionSequences{
+			records:    r.NumberOfRecordsInTransaction,
+			partitions: r.NumberOfPartitionsInTransaction,
+			sequences:  make(map[int64]bool),
+			last:       make(map[string]int64),
+		}
+		s.transactions[key] = tx
+	}
+
+	if last, ok := tx.last[partitionToken]; ok && sequence <= last {
+		s.outOfOrder++
+		s.logger.Warn("Record sequence is out of order", "stream", streamID, "partition_token", partitionToken,
+			"server_transaction_id", r.ServerTransactionID, "record_sequence", sequence, "previous_record_sequence", last)
+	} else {
+		tx.last[partitionToken] = sequence
+	}
+	tx.sequences[sequence] = true
+
+	if !r.IsLastRecordInTransactionInPartition {
+		return
+	}
+	tx.finished++
+	if tx.finished < tx.partitions {
+		return
+	}
+	delete(s.transactions, key)
+	if missing := tx.missing(); len(missing) > 0 {
+		s.gaps++
+		s.logger.Warn("Record sequences have gaps", "stream", streamID, "server_transaction_id", r.ServerTransactionID,
+			"missing_record_sequences", missing, "number_of_records_in_transaction", tx.records)
+		return
+	}
+	s.verified++
+}
+
+// missing returns the record sequences not read in the transaction.
+func (tx *transactionSequences) missing() []int64 {
+	var missing []int64
+	for sequence := int64(0); sequence < tx.records; sequence++ {
+		if !tx.sequences[sequence] {
+			missing = append(missing, sequence)
+		}
+	}
+	return missing
+}
+
+// Close logs the totals of the verification, with the transactions whose partitions didn't all send the last record.
+func (s *verifySink) Close() error {
+	s.mu.Lock()
+	keys := make([]transactionKey, 0, len(s.transactions))
+	for key := range s.transactions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].streamID != keys[j].streamID {
+			return keys[i].streamID < keys[j].streamID
+		}
+		return keys[i].serverTransactionID < keys[j].serverTransactionID
+	})
+	for _, key := range keys {
+		tx := s.transactions[key]
+		s.logger.Warn("Transaction is incomplete", "stream", key.streamID, "server_transaction_id", key.serverTransactionID,
+			"finished_partitions", tx.finished, "number_of_partitions_in_transaction", tx.partitions, "missing_record_sequences", tx.missing())
+	}
+	s.logger.Info("Record sequence verification", "verified_transactions", s.verified, "transactions_with_gaps", s.gaps,
+		"out_of_order_records", s.outOfOrder, "incomplete_transactions", len(s.transactions))
+	s.mu.Unlock()
+
+	return s.Sink.Close()
+}
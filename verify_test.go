@@ -0,0 +1,96 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestVerifySink(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	recorder := &recordingSink{}
+	sink := newVerifySink(recorder, logger)
+
+	write := func(token, tx, sequence string, records, partitions int64, last bool) {
+		r := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+		r.ServerTransactionID = tx
+		r.RecordSequence = sequence
+		r.NumberOfRecordsInTransaction = records
+		r.NumberOfPartitionsInTransaction = partitions
+		r.IsLastRecordInTransactionInPartition = last
+		result := newTestReadResult(r)
+		result.StreamID = "s"
+		result.PartitionToken = token
+		if err := sink.Write(result); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+	}
+
+	// The records of tx1 are split into the partitions a and b.
+	write("a", "tx1", "00000000", 3, 2, false)
+	write("b", "tx1", "00000001", 3, 2, true)
+	write("a", "tx1", "00000002", 3, 2, true)
+	// The record 1 of tx2 is lost.
+	write("a", "tx2", "00000000", 3, 1, false)
+	write("a", "tx2", "00000002", 3, 1, true)
+	// The records of tx3 are out of order, and the last one never arrives.
+	write("b", "tx3", "00000001", 3, 1, false)
+	write("b", "tx3", "00000000", 3, 1, false)
+
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	expected := `level=WARN msg="Record sequences have gaps" stream=s server_transaction_id=tx2 missing_record_sequences=[1] number_of_records_in_transaction=3
+level=WARN msg="Record sequence is out of order" stream=s partition_token=b server_transaction_id=tx3 record_sequence=0 previous_record_sequence=1
+level=WARN msg="Transaction is incomplete" stream=s server_transaction_id=tx3 finished_partitions=0 number_of_partitions_in_transaction=1 missing_record_sequences=[2]
+level=INFO msg="Record sequence verification" verified_transactions=1 transactions_with_gaps=1 out_of_order_records=1 incomplete_transactions=1
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+	if got := len(recorder.timestamps); got != 7 {
+		t.Errorf("written records = %d, want 7", got)
+	}
+}
+
+func TestVerifySinkInvalidSequence(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{ReplaceAttr: removeTime}))
+	sink := newVerifySink(&recordingSink{}, logger)
+
+	r := newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId")
+	r.ServerTransactionID = "tx"
+	r.RecordSequence = "x"
+	if err := sink.Write(&changestreams.ReadResult{
+		StreamID:       "s",
+		PartitionToken: "a",
+		ChangeRecords:  []*changestreams.ChangeRecord{{DataChangeRecords: []*changestreams.DataChangeRecord{r}}},
+	}); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	expected := `level=WARN msg="Record sequence is invalid" stream=s partition_token=a server_transaction_id=tx record_sequence=x
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+}
@@ -0,0 +1,210 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// Layout of the nodes in the HTML report.
+const (
+	htmlNodeWidth  = 240
+	htmlNodeHeight = 56
+	htmlNodeGapX   = 24
+	htmlNodeGapY   = 48
+	htmlMargin     = 16
+	htmlLabelLen   = 24
+)
+
+type htmlNode struct {
+	X, Y    int
+	Label   string
+	Records string
+	Tooltip string
+	// Fill is the color to highlight the partition in the watermark overlay.
+	Fill string
+}
+
+type htmlEdge struct {
+	X1, Y1, X2, Y2 int
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Change stream partitions</title>
+<style>
+  html, body { margin: 0; height: 100%; font-family: sans-serif; }
+  #help { position: fixed; top: 8px; left: 8px; color: #666; font-size: 12px; }
+  svg { width: 100%; height: 100%; cursor: grab; }
+  rect { fill: #eef4fb; stroke: #4a7ab5; }
+  rect:hover { fill: #d5e5f7; }
+  line { stroke: #888; marker-end: url(#arrow); }
+  text { font-size: 12px; pointer-events: none; }
+</style>
+</head>
+<body>
+<div id="help">Drag to pan, scroll to zoom, hover over a partition for the details.</div>
+<svg id="graph" viewBox="0 0 {{.Width}} {{.Height}}" xmlns="http://www.w3.org/2000/svg">
+<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="6" markerHeight="6" orient="auto"><path d="M0,0 L10,5 L0,10 z" fill="#888"/></marker></defs>
+{{- range .Edges}}
+<line x1="{{.X1}}" y1="{{.Y1}}" x2="{{.X2}}" y2="{{.Y2}}"/>
+{{- end}}
+{{- range .Nodes}}
+<g><title>{{.Tooltip}}</title><rect x="{{.X}}" y="{{.Y}}" width="{{$.NodeWidth}}" height="{{$.NodeHeight}}" rx="4"{{if .Fill}} style="fill: {{.Fill}}"{{end}}/><text x="{{.X}}" y="{{.Y}}" dx="8" dy="22">{{.Label}}</text><text x="{{.X}}" y="{{.Y}}" dx="8" dy="42">{{.Records}}</text></g>
+{{- end}}
+</svg>
+<script>
+(function() {
+  var svg = document.getElementById("graph");
+  var box = svg.viewBox.baseVal;
+  var drag = null;
+  svg.addEventListener("mousedown", function(e) { drag = {x: e.clientX, y: e.clientY}; });
+  window.addEventListener("mouseup", function() { drag = null; });
+  window.addEventListener("mousemove", function(e) {
+    if (!drag) return;
+    var scale = box.width / svg.clientWidth;
+    box.x -= (e.clientX - drag.x) * scale;
+    box.y -= (e.clientY - drag.y) * scale;
+    drag = {x: e.clientX, y: e.clientY};
+  });
+  svg.addEventListener("wheel", function(e) {
+    e.preventDefault();
+    var factor = e.deltaY > 0 ? 1.1 : 1 / 1.1;
+    var rect = svg.getBoundingClientRect();
+    var x = box.x + (e.clientX - rect.left) / rect.width * box.width;
+    var y = box.y + (e.clientY - rect.top) / rect.height * box.height;
+    box.x = x - (x - box.x) * factor;
+    box.y = y - (y - box.y) * factor;
+    box.width *= factor;
+    box.height *= factor;
+  });
+})();
+</script>
+</body>
+</html>
+`))
+
+// DrawHTML writes the partitions as a self-contained HTML file with an interactive graph, which can be viewed in a
+// browser without Graphviz. The partitions are laid out in the rows by the depth from the root.
+func (v *PartitionVisualizer) DrawHTML() error {
+	partitions := sortPartitions(v.partitions)
+	depths := make(map[*Partition]int, len(partitions))
+	var depth func(p *Partition) int
+	depth = func(p *Partition) int {
+		if d, ok := depths[p]; ok {
+			return d
+		}
+		d := 0
+		for _, parent := range p.Parents {
+			if pd := depth(parent) + 1; pd > d {
+				d = pd
+			}
+		}
+		depths[p] = d
+		return d
+	}
+
+	var rows [][]*Partition
+	for _, partition := range partitions {
+		d := depth(partition)
+		for len(rows) <= d {
+			rows = append(rows, nil)
+		}
+		rows[d] = append(rows[d], partition)
+	}
+
+	type position struct{ x, y int }
+	positions := make(map[*Partition]position, len(partitions))
+	var nodes []htmlNode
+	width, height := 0, 0
+	for i, row := range rows {
+		for j, partition := range row {
+			pos := position{
+				x: htmlMargin + j*(htmlNodeWidth+htmlNodeGapX),
+				y: htmlMargin + i*(htmlNodeHeight+htmlNodeGapY),
+			}
+			positions[partition] = pos
+			node := htmlNode{
+				X:       pos.x,
+				Y:       pos.y,
+				Label:   truncate(partition.Token, htmlLabelLen),
+				Records: fmt.Sprintf("records: %d, heartbeats: %d", partition.DataChangeRecords, partition.HeartbeatRecords),
+				Tooltip: htmlTooltip(partition),
+			}
+			if lag, status, ok := v.watermark(partition); ok {
+				node.Tooltip += fmt.Sprintf("\nlag: %v\nstatus: %s", lag, status)
+				node.Fill = vizColors[status]
+			}
+			nodes = append(nodes, node)
+			width = max(width, pos.x+htmlNodeWidth+htmlMargin)
+			height = max(height, pos.y+htmlNodeHeight+htmlMargin)
+		}
+	}
+
+	var edges []htmlEdge
+	for _, partition := range partitions {
+		child := positions[partition]
+		for _, parent := range partition.Parents {
+			from := positions[parent]
+			edges = append(edges, htmlEdge{
+				X1: from.x + htmlNodeWidth/2,
+				Y1: from.y + htmlNodeHeight,
+				X2: child.x + htmlNodeWidth/2,
+				Y2: child.y,
+			})
+		}
+	}
+
+	return htmlReportTemplate.Execute(v.out, struct {
+		Width, Height         int
+		NodeWidth, NodeHeight int
+		Nodes                 []htmlNode
+		Edges                 []htmlEdge
+	}{width, height, htmlNodeWidth, htmlNodeHeight, nodes, edges})
+}
+
+// htmlTooltip returns the metadata of the partition shown on hover.
+func htmlTooltip(partition *Partition) string {
+	tooltip := "token: " + partition.Token
+	if !partition.StartTimestamp.IsZero() {
+		tooltip += "\nstart_timestamp: " + partition.StartTimestamp.Format(time.RFC3339)
+	}
+	if !partition.EndTimestamp.IsZero() {
+		tooltip += "\nend_timestamp: " + partition.EndTimestamp.Format(time.RFC3339)
+	}
+	if partition.RecordSequence != "" {
+		tooltip += "\nrecord_sequence: " + partition.RecordSequence
+	}
+	tooltip += fmt.Sprintf("\ndata_change_records: %d\nheartbeat_records: %d", partition.DataChangeRecords, partition.HeartbeatRecords)
+	if !partition.LastTimestamp.IsZero() {
+		tooltip += "\nlast_timestamp: " + partition.LastTimestamp.Format(time.RFC3339)
+	}
+	return tooltip
+}
+
+// truncate shortens s to n characters with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
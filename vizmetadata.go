@@ -0,0 +1,90 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/spanner"
+)
+
+// dataflowInitialPartitionToken is the token of the fake parent of the initial partitions in the metadata table of the
+// Dataflow connector, which corresponds to the root.
+const dataflowInitialPartitionToken = "Parent0"
+
+// dataflowPartitionFinished is the state of the finished partitions in the metadata table.
+const dataflowPartitionFinished = "FINISHED"
+
+// partitionMetadata is a row of the PartitionMetadata table of the Dataflow connector.
+type partitionMetadata struct {
+	PartitionToken string    `spanner:"PartitionToken"`
+	ParentTokens   []string  `spanner:"ParentTokens"`
+	StartTimestamp time.Time `spanner:"StartTimestamp"`
+	State          string    `spanner:"State"`
+	Watermark      time.Time `spanner:"Watermark"`
+}
+
+var partitionMetadataColumns = []string{"PartitionToken", "ParentTokens", "StartTimestamp", "State", "Watermark"}
+
+// ReadMetadataTable reads the partitions from the PartitionMetadata table of the Dataflow connector, to visualize what
+// the pipeline actually processed instead of reading the stream. The table is read with the Read API, so the same
+// column names work in both GoogleSQL and PostgreSQL databases.
+func (v *PartitionVisualizer) ReadMetadataTable(ctx context.Context, client *spanner.Client, table string) error {
+	var rows []*partitionMetadata
+	if err := client.Single().Read(ctx, table, spanner.AllKeys(), partitionMetadataColumns).Do(func(r *spanner.Row) error {
+		var row partitionMetadata
+		if err := r.ToStruct(&row); err != nil {
+			return err
+		}
+		rows = append(rows, &row)
+		return nil
+	}); err != nil {
+		return err
+	}
+	v.addMetadata(rows)
+	return nil
+}
+
+// addMetadata adds the partitions of the metadata rows. The watermark of a partition is its last timestamp, and the
+// end timestamp as well if it's finished. The numbers of the records are not known.
+func (v *PartitionVisualizer) addMetadata(rows []*partitionMetadata) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, row := range rows {
+		if row.PartitionToken == dataflowInitialPartitionToken {
+			continue
+		}
+		// The parents may be added after their children, as the rows are not ordered.
+		partition := v.partition(row.PartitionToken)
+		partition.StartTimestamp = row.StartTimestamp
+		partition.LastTimestamp = row.Watermark
+		if row.State == dataflowPartitionFinished {
+			partition.EndTimestamp = row.Watermark
+		}
+		for _, parentToken := range row.ParentTokens {
+			if parentToken == dataflowInitialPartitionToken {
+				parentToken = rootPartitionToken
+			}
+			partition.Parents = append(partition.Parents, v.partition(parentToken))
+		}
+		if len(partition.Parents) == 0 {
+			partition.Parents = append(partition.Parents, v.partitions[rootPartitionToken])
+		}
+	}
+}
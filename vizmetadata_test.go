@@ -0,0 +1,64 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPartitionVisualizerAddMetadata(t *testing.T) {
+	var out bytes.Buffer
+	visualizer := NewPartitionVisualizer(&out)
+	// The children come before their parents.
+	visualizer.addMetadata([]*partitionMetadata{
+		{
+			PartitionToken: "b",
+			ParentTokens:   []string{"a"},
+			StartTimestamp: mustParseTime(t, "2022-12-04T19:00:00Z"),
+			State:          "RUNNING",
+			Watermark:      mustParseTime(t, "2022-12-04T19:30:00Z"),
+		},
+		{
+			PartitionToken: "a",
+			ParentTokens:   []string{dataflowInitialPartitionToken},
+			StartTimestamp: mustParseTime(t, "2022-12-04T18:00:00Z"),
+			State:          dataflowPartitionFinished,
+			Watermark:      mustParseTime(t, "2022-12-04T19:00:00Z"),
+		},
+		{
+			PartitionToken: dataflowInitialPartitionToken,
+			StartTimestamp: mustParseTime(t, "2022-12-04T18:00:00Z"),
+			State:          dataflowPartitionFinished,
+			Watermark:      mustParseTime(t, "2022-12-04T18:00:00Z"),
+		},
+	})
+	if err := visualizer.Render(vizFormatCSV); err != nil {
+		t.Fatalf("Render error: %v", err)
+	}
+
+	expected := `token,parents,start_timestamp,record_sequence,end_timestamp,data_change_records,heartbeat_records,last_timestamp
+a,root,2022-12-04T18:00:00Z,,2022-12-04T19:00:00Z,0,0,2022-12-04T19:00:00Z
+b,a,2022-12-04T19:00:00Z,,,0,0,2022-12-04T19:30:00Z
+root,,,,,0,0,
+`
+	if diff := cmp.Diff(out.String(), expected); diff != "" {
+		t.Errorf("visualizer has diff = %v", diff)
+	}
+}
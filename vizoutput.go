@@ -0,0 +1,53 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// vizImageFormats are the formats of the images rendered from the DOT by Graphviz, by the file extension.
+var vizImageFormats = map[string]string{
+	".svg": "svg",
+	".png": "png",
+	".pdf": "pdf",
+}
+
+// vizImageFormat returns the Graphviz output format of the path, or an empty string if it's not an image.
+func vizImageFormat(path string) string {
+	return vizImageFormats[strings.ToLower(filepath.Ext(path))]
+}
+
+// renderImage renders the DOT into the image file with the dot command of Graphviz.
+func renderImage(dot []byte, path string) error {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		return fmt.Errorf("the dot command of Graphviz is required to render %s, install Graphviz or write the DOT instead: %w", path, err)
+	}
+	cmd := exec.Command(dotPath, "-T"+vizImageFormat(path), "-o", path)
+	cmd.Stdin = bytes.NewReader(dot)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("dot failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
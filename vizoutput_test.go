@@ -0,0 +1,57 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderImage(t *testing.T) {
+	// The fake dot command writes the arguments and the input to the output file.
+	dir := t.TempDir()
+	script := "#!/bin/sh\n{ echo \"$@\"; cat; } > \"$3\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "dot"), []byte(script), 0755); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(t.TempDir(), "partitions.SVG")
+	if err := renderImage([]byte("digraph {}\n"), path); err != nil {
+		t.Fatalf("renderImage error: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if diff := cmp.Diff(string(got), "-Tsvg -o "+path+"\ndigraph {}\n"); diff != "" {
+		t.Errorf("output has diff = %v", diff)
+	}
+}
+
+func TestRenderImageWithoutDot(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+
+	err := renderImage([]byte("digraph {}\n"), filepath.Join(t.TempDir(), "partitions.png"))
+	if err == nil || !strings.Contains(err.Error(), "Graphviz is required") {
+		t.Errorf("renderImage must fail without dot, but got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+// WebhookConfig is the configuration for WebhookSink.
+type WebhookConfig struct {
+	URL string
+	// Headers are added to every request, e.g. "Authorization".
+	Headers http.Header
+	// BatchSize is the maximum number of records per request.
+	BatchSize int
+	// FlushInterval is the maximum time a record waits in the batch before being sent.
+	FlushInterval time.Duration
+	// MaxRetries is the maximum number of retries per request.
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// WebhookSink POSTs the data change records to an HTTP endpoint as newline delimited JSON.
+//
+// Requests are retried with exponential backoff on network errors, 429 and 5xx responses.
+type WebhookSink struct {
+	ctx    context.Context
+	config WebhookConfig
+	client *http.Client
+	batch  [][]byte
+	err    error
+	done   chan struct{}
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+}
+
+// NewWebhookSink creates a new WebhookSink.
+func NewWebhookSink(ctx context.Context, config WebhookConfig) *WebhookSink {
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = time.Second
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+
+	s := &WebhookSink{
+		ctx:    ctx,
+		config: config,
+		client: &http.Client{Timeout: 30 * time.Second},
+		done:   make(chan struct{}),
+	}
+	if config.BatchSize > 1 && config.FlushInterval > 0 {
+		s.wg.Add(1)
+		go s.flushPeriodically()
+	}
+	return s
+}
+
+func (s *WebhookSink) Read(result *changestreams.ReadResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	for _, changeRecord := range result.ChangeRecords {
+		for _, r := range changeRecord.DataChangeRecords {
+			b, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			s.batch = append(s.batch, b)
+			if len(s.batch) >= s.config.BatchSize {
+				if err := s.flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Flush sends the batched records.
+func (s *WebhookSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.flush()
+}
+
+// Close sends the remaining records.
+func (s *WebhookSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return s.flush()
+}
+
+func (s *WebhookSink) flushPeriodically() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.err == nil {
+				// The error is returned from the next Read.
+				s.err = s.flush()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *WebhookSink) flush() error {
+	if len(s.batch) == 0 {
+		return nil
+	}
+	body := append(bytes.Join(s.batch, []byte("\n")), '\n')
+	s.batch = nil
+	return s.post(body)
+}
+
+func (s *WebhookSink) post(body []byte) error {
+	backoff := s.config.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := s.send(body)
+		if err == nil {
+			return nil
+		}
+		if retryAfter < 0 || attempt >= s.config.MaxRetries {
+			return err
+		}
+
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-s.ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff *= 2
+		if backoff > s.config.MaxBackoff {
+			backoff = s.config.MaxBackoff
+		}
+	}
+}
+
+// send sends a request. On failure, it returns a non-negative retryAfter if the request is retryable.
+func (s *WebhookSink) send(body []byte) (retryAfter time.Duration, err error) {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, err
+	}
+	for name, values := range s.config.Headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		if s.ctx.Err() != nil {
+			return -1, err
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+	err = fmt.Errorf("webhook returned status %s", resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second, err
+		}
+		return 0, err
+	}
+	return -1, err
+}
+
+// parseHeaders parses headers in the form of "Name: value".
+func parseHeaders(headers []string) (http.Header, error) {
+	h := make(http.Header)
+	for _, header := range headers {
+		parts := strings.SplitN(header, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid header %q, must be in the form of \"Name: value\"", header)
+		}
+		h.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return h, nil
+}
@@ -0,0 +1,103 @@
+//
+// Copyright 2022 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cloudspannerecosystem/spanner-change-streams-tail/changestreams"
+)
+
+func TestWebhookSink(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		bodies   []string
+		requests int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		requests++
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization header = %q", got)
+		}
+		// Fail the first request to test retries.
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+	}))
+	defer server.Close()
+
+	headers, err := parseHeaders([]string{"Authorization: Bearer token"})
+	if err != nil {
+		t.Fatalf("parseHeaders error: %v", err)
+	}
+	sink := NewWebhookSink(context.Background(), WebhookConfig{
+		URL:            server.URL,
+		Headers:        headers,
+		BatchSize:      2,
+		MaxRetries:     1,
+		InitialBackoff: time.Millisecond,
+	})
+
+	var records []*changestreams.DataChangeRecord
+	for _, ts := range []string{"2022-12-04T18:00:00Z", "2022-12-04T18:00:01Z", "2022-12-04T18:00:02Z"} {
+		records = append(records, newTestDataChangeRecord(t, ts, "PlayerId"))
+	}
+	if err := sink.Read(newTestReadResult(records...)); err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("got %d bodies, want 2", len(bodies))
+	}
+	for i, expected := range []int{2, 1} {
+		if got := strings.Count(bodies[i], "\n"); got != expected {
+			t.Errorf("body %d has %d records, want %d", i, got, expected)
+		}
+	}
+}
+
+func TestWebhookSinkNonRetryableError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(context.Background(), WebhookConfig{
+		URL:        server.URL,
+		MaxRetries: 5,
+		MaxBackoff: time.Hour,
+	})
+	if err := sink.Read(newTestReadResult(newTestDataChangeRecord(t, "2022-12-04T18:00:00Z", "PlayerId"))); err == nil {
+		t.Errorf("Read should return an error")
+	}
+}